@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math"
+
+	"github.com/dronesphere/internal/model/dto"
+)
+
+// 飞行模型参数
+const (
+	metersPerDegree = 111320.0 // 每度纬度对应的距离，单位：米
+	climbRate       = 5.0      // 最大升降速度，单位：m/s
+	arriveDistance  = 0.5      // 与目标点的水平距离小于该值视为到达，单位：米
+	arriveHeight    = 0.1      // 与目标点的高度差小于该值视为到达，单位：米
+	batteryDrain    = 0.05     // 空中每秒消耗的电量百分比
+	batteryFloor    = 10.0     // 模拟电量的下限，避免长时间运行后电量耗尽
+	gimbalPitchMin  = -90.0
+	gimbalPitchMax  = 35.0
+)
+
+// point 航点，Height 为相对起飞点的高度
+type point struct {
+	Lat    float64
+	Lng    float64
+	Height float64
+}
+
+// phase 飞行阶段
+type phase int
+
+const (
+	phaseGround  phase = iota // 停在起飞点
+	phaseTakeoff              // 垂直爬升到首个航点的高度
+	phaseTrack                // 循环飞行配置的轨迹
+	phaseWayline              // 执行航线
+	phaseHover                // 取消返航后悬停
+	phaseReturn               // 返航
+	phaseLanding              // 降落
+)
+
+// drone 虚拟无人机的飞行状态，不是并发安全的，由所属网关加锁访问
+type drone struct {
+	home  point
+	pos   point
+	phase phase
+	after phase // 起飞完成后进入的阶段
+	hold  bool  // 原地悬停，用于航线暂停
+
+	path  []point
+	index int // 正在飞往的航点
+	loop  bool
+	speed float64
+
+	takeoffHeight float64
+	returnHeight  float64
+
+	head    float64 // 机头朝向，正北为 0，顺时针为正，单位：度
+	hSpeed  float64
+	vSpeed  float64
+	battery float64
+
+	gimbalPitch float64
+	gimbalYaw   float64
+	zoomFactor  float64
+}
+
+func newDrone(home point) *drone {
+	return &drone{
+		home:       home,
+		pos:        home,
+		battery:    100,
+		zoomFactor: 2,
+	}
+}
+
+// modeCode 当前阶段对应的飞行模式
+func (d *drone) modeCode() int {
+	switch d.phase {
+	case phaseTakeoff:
+		return dto.ModeCodeAutoTakeoff
+	case phaseTrack, phaseHover:
+		return dto.ModeCodeManualFlight
+	case phaseWayline:
+		return dto.ModeCodeRouteFlight
+	case phaseReturn:
+		return dto.ModeCodeAutoReturn
+	case phaseLanding:
+		return dto.ModeCodeAutoLanding
+	}
+	return dto.ModeCodeStandby
+}
+
+func (d *drone) airborne() bool {
+	return d.phase != phaseGround
+}
+
+// fly 沿 path 飞行，停在地面时先起飞到首个航点的高度
+func (d *drone) fly(p phase, path []point, speed float64, loop bool) {
+	d.path, d.index, d.speed, d.loop, d.hold = path, 0, speed, loop, false
+	if d.phase == phaseGround {
+		d.phase, d.after, d.takeoffHeight = phaseTakeoff, p, path[0].Height
+		return
+	}
+	d.phase = p
+}
+
+// returnHome 以不低于 height 的高度飞回起飞点并降落，停在地面时返回 false
+func (d *drone) returnHome(height float64) bool {
+	if !d.airborne() || d.phase == phaseLanding {
+		return false
+	}
+	d.phase, d.hold = phaseReturn, false
+	d.returnHeight = math.Max(d.pos.Height, height)
+	return true
+}
+
+// hover 原地悬停，等待新的指令
+func (d *drone) hover() {
+	d.phase, d.hold = phaseHover, false
+}
+
+// step 推进 dt 秒的飞行状态
+func (d *drone) step(dt float64) {
+	d.hSpeed, d.vSpeed = 0, 0
+	if d.airborne() {
+		d.battery = math.Max(batteryFloor, d.battery-batteryDrain*dt)
+	}
+	if d.hold {
+		return
+	}
+	switch d.phase {
+	case phaseTakeoff:
+		if d.moveTo(point{Lat: d.pos.Lat, Lng: d.pos.Lng, Height: d.takeoffHeight}, 0, dt) {
+			d.phase = d.after
+		}
+	case phaseTrack, phaseWayline:
+		if d.index < len(d.path) && d.moveTo(d.path[d.index], d.speed, dt) {
+			d.index++
+			if d.index == len(d.path) && d.loop {
+				d.index = 0
+			}
+		}
+	case phaseReturn:
+		if d.moveTo(point{Lat: d.home.Lat, Lng: d.home.Lng, Height: d.returnHeight}, d.speed, dt) {
+			d.phase = phaseLanding
+		}
+	case phaseLanding:
+		if d.moveTo(d.home, 0, dt) {
+			d.phase = phaseGround
+		}
+	}
+}
+
+// moveTo 以不超过 speed 的水平速度与 climbRate 的升降速度飞向 target，返回是否到达
+func (d *drone) moveTo(target point, speed, dt float64) bool {
+	dx, dy := offsetMeters(d.pos, target)
+	dist := math.Hypot(dx, dy)
+	dz := target.Height - d.pos.Height
+
+	h := math.Min(dist, speed*dt)
+	v := math.Max(-climbRate*dt, math.Min(climbRate*dt, dz))
+	if dist > arriveDistance {
+		d.head = math.Atan2(dx, dy) * 180 / math.Pi
+		ratio := h / dist
+		d.pos.Lat += (target.Lat - d.pos.Lat) * ratio
+		d.pos.Lng += (target.Lng - d.pos.Lng) * ratio
+	} else {
+		d.pos.Lat, d.pos.Lng = target.Lat, target.Lng
+		h = 0
+	}
+	d.pos.Height += v
+	d.hSpeed, d.vSpeed = h/dt, v/dt
+	return dist-h <= arriveDistance && math.Abs(dz-v) <= arriveHeight
+}
+
+// homeDistance 与起飞点的水平距离，单位：米
+func (d *drone) homeDistance() float64 {
+	return math.Hypot(offsetMeters(d.pos, d.home))
+}
+
+// offsetMeters 两点间向东、向北的距离，单位：米，范围较小时按平面近似
+func offsetMeters(from, to point) (float64, float64) {
+	dx := (to.Lng - from.Lng) * metersPerDegree * math.Cos(from.Lat*math.Pi/180)
+	dy := (to.Lat - from.Lat) * metersPerDegree
+	return dx, dy
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+const (
+	tickInterval    = time.Second      // OSD 上报与飞行模型的推进间隔
+	topoInterval    = 30 * time.Second // 重发拓扑的间隔，平台重启或审批绑定后无需重启模拟器
+	publishTimeout  = 5 * time.Second
+	downloadTimeout = 30 * time.Second
+	thingVersion    = "1.1.2"
+)
+
+// 设备拒绝执行时返回的错误码
+const (
+	resultOK       = 0
+	resultRejected = 314000 // 当前状态无法执行该指令
+)
+
+// 航线任务状态（wayline_mission_state）
+const (
+	missionStateToFirstPoint = 5 // 进入航线，飞往第一个航点
+	missionStateExecuting    = 6 // 航线执行中
+	missionStateInterrupted  = 7 // 航线中断
+	missionStateExit         = 9 // 退出航线
+)
+
+// config 所有虚拟设备共用的配置
+type config struct {
+	gatewayTopo  dto.ProductTopo
+	droneTopo    dto.ProductTopo
+	payloadIndex string  // 主相机的 payload_index
+	altitude     float64 // 起飞点的椭球高，单位：米
+	height       float64 // 轨迹飞行高度，单位：米
+	speed        float64 // 默认飞行速度，单位：m/s
+	rthHeight    float64 // 默认返航高度，单位：米
+}
+
+// message 上行消息，osd、events 与 services_reply 共用
+type message struct {
+	dto.MessageCommon
+	NeedReply int    `json:"need_reply,omitempty"`
+	Gateway   string `json:"gateway,omitempty"`
+	Data      any    `json:"data"`
+}
+
+// request 下行的 services 与 property/set 请求
+type request struct {
+	dto.MessageCommon
+	Data json.RawMessage `json:"data"`
+}
+
+// topoDevice 拓扑中的设备信息
+type topoDevice struct {
+	SN string `json:"sn,omitempty"`
+	dto.ProductTopo
+	Index string `json:"index,omitempty"`
+}
+
+type topoData struct {
+	dto.ProductTopo
+	SubDevices []topoDevice `json:"sub_devices"`
+}
+
+// mission 已下发的航线任务
+type mission struct {
+	flightID    string
+	bid         string
+	wayline     *wayline
+	rthAltitude float64
+	status      string
+	ended       bool // 已进入终态，最后一次进度上报后清除
+}
+
+// gateway 虚拟网关及其挂载的无人机
+type gateway struct {
+	sn      string
+	droneSN string
+	cfg     *config
+	client  mqtt.Client
+	l       *slog.Logger
+
+	mu       sync.Mutex
+	drone    *drone
+	track    []point
+	prepared map[string]*mission // flight_id -> 已准备的任务
+	mission  *mission            // 正在执行的任务
+	drc      bool                // 是否处于 DRC 模式
+}
+
+func newGateway(sn, droneSN string, home point, track []point, cfg *config, client mqtt.Client, l *slog.Logger) *gateway {
+	return &gateway{
+		sn:       sn,
+		droneSN:  droneSN,
+		cfg:      cfg,
+		client:   client,
+		l:        l.With(slog.String("gatewaySN", sn), slog.String("droneSN", droneSN)),
+		drone:    newDrone(home),
+		track:    track,
+		prepared: make(map[string]*mission),
+	}
+}
+
+// subscribe 订阅下发给网关与无人机的主题，连接建立或重连后调用
+func (g *gateway) subscribe() {
+	topics := map[string]mqtt.MessageHandler{
+		fmt.Sprintf("thing/product/%s/services", g.sn):          g.handleServices,
+		fmt.Sprintf("thing/product/%s/property/set", g.sn):      g.handlePropertySet,
+		fmt.Sprintf("thing/product/%s/property/set", g.droneSN): g.handlePropertySet,
+		fmt.Sprintf("thing/product/%s/drc/down", g.sn):          g.handleDRC,
+	}
+	for topic, handler := range topics {
+		token := g.client.Subscribe(topic, 1, handler)
+		if token.WaitTimeout(publishTimeout) && token.Error() != nil {
+			g.l.Error("订阅主题失败", slog.String("topic", topic), slog.Any("err", token.Error()))
+		}
+	}
+}
+
+// run 按 tickInterval 推进飞行状态并上报，ctx 结束时上报无人机下线
+func (g *gateway) run(ctx context.Context) {
+	g.publishTopo(true)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	topo := time.NewTicker(topoInterval)
+	defer topo.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			g.publishTopo(false)
+			return
+		case <-topo.C:
+			g.publishTopo(true)
+		case <-ticker.C:
+			g.tick()
+		}
+	}
+}
+
+// tick 推进一次飞行状态，上报 OSD 与航线进度
+func (g *gateway) tick() {
+	g.mu.Lock()
+	d := g.drone
+	if d.phase == phaseGround && g.mission == nil && len(g.track) > 0 {
+		d.fly(phaseTrack, g.track, g.cfg.speed, true)
+	}
+	d.step(tickInterval.Seconds())
+
+	var progress *message
+	if m := g.mission; m != nil {
+		if !m.ended && d.phase == phaseWayline && d.index >= len(d.path) {
+			m.status, m.ended = dto.FlighttaskStatusOK, true
+			d.returnHome(m.rthAltitude)
+			g.l.Info("航线执行完成", slog.String("flightID", m.flightID))
+		}
+		progress = g.progressEvent(m)
+		if m.ended {
+			g.mission = nil
+		}
+	}
+	droneOSD := g.droneOSD()
+	var drcOSD any
+	if g.drc {
+		drcOSD = g.drcOSD()
+	}
+	g.mu.Unlock()
+
+	g.publish(fmt.Sprintf("thing/product/%s/osd", g.droneSN), 0, g.newMessage("", droneOSD))
+	g.publish(fmt.Sprintf("thing/product/%s/osd", g.sn), 0, g.newMessage("", g.gatewayOSD()))
+	if drcOSD != nil {
+		g.publish(fmt.Sprintf("thing/product/%s/drc/up", g.sn), 0, dto.DRCMessage{
+			Method:    dto.MethodDRCOSDInfoPush,
+			Timestamp: time.Now().UnixMilli(),
+			Data:      drcOSD,
+		})
+	}
+	if progress != nil {
+		g.publish(fmt.Sprintf("thing/product/%s/events", g.sn), 1, progress)
+	}
+}
+
+// publishTopo 上报网关拓扑，online 为 false 时不带子设备，表示无人机下线
+func (g *gateway) publishTopo(online bool) {
+	data := topoData{ProductTopo: g.cfg.gatewayTopo, SubDevices: []topoDevice{}}
+	if online {
+		data.SubDevices = append(data.SubDevices, topoDevice{SN: g.droneSN, ProductTopo: g.cfg.droneTopo, Index: "A"})
+	}
+	g.publish(fmt.Sprintf("sys/product/%s/status", g.sn), 1, g.newMessage("update_topo", data))
+}
+
+// handleServices 处理平台的 services 调用并应答
+func (g *gateway) handleServices(_ mqtt.Client, m mqtt.Message) {
+	var req request
+	if err := json.Unmarshal(m.Payload(), &req); err != nil {
+		g.l.Error("解析服务调用失败", slog.Any("err", err))
+		return
+	}
+	result := g.call(req)
+	g.l.Info("应答服务调用", slog.String("method", req.Method), slog.Int("result", result))
+	g.publish(fmt.Sprintf("thing/product/%s/services_reply", g.sn), 1, message{
+		MessageCommon: dto.MessageCommon{TID: req.TID, BID: req.BID, Method: req.Method, Timestamp: time.Now().UnixMilli()},
+		Data:          dto.ServicesReplyData{Result: result},
+	})
+}
+
+// call 执行一次 services 调用，未模拟的方法直接返回成功
+func (g *gateway) call(req request) int {
+	if req.Method == dto.MethodFlighttaskPrepare {
+		return g.prepare(req)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d, m := g.drone, g.mission
+	active := m != nil && !m.ended
+	switch req.Method {
+	case dto.MethodFlighttaskExecute:
+		var data dto.FlighttaskExecuteData
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return resultRejected
+		}
+		next, ok := g.prepared[data.FlightID]
+		if !ok || active {
+			return resultRejected
+		}
+		delete(g.prepared, data.FlightID)
+		next.bid, next.status = req.BID, dto.FlighttaskStatusInProgress
+		speed := next.wayline.Speed
+		if speed <= 0 {
+			speed = g.cfg.speed
+		}
+		d.fly(phaseWayline, next.wayline.Points, speed, false)
+		g.mission = next
+		g.l.Info("开始执行航线", slog.String("flightID", next.flightID), slog.Int("waypoints", len(next.wayline.Points)))
+	case dto.MethodFlighttaskPause:
+		if !active || m.status != dto.FlighttaskStatusInProgress {
+			return resultRejected
+		}
+		m.status, d.hold = dto.FlighttaskStatusPaused, true
+	case dto.MethodFlighttaskRecovery:
+		if !active || m.status != dto.FlighttaskStatusPaused {
+			return resultRejected
+		}
+		m.status, d.hold = dto.FlighttaskStatusInProgress, false
+	case dto.MethodFlighttaskStop:
+		if !active {
+			return resultRejected
+		}
+		m.status, m.ended = dto.FlighttaskStatusCanceled, true
+		d.returnHome(m.rthAltitude)
+	case dto.MethodReturnHome:
+		if !d.returnHome(g.cfg.rthHeight) {
+			return resultRejected
+		}
+		if active {
+			m.status, m.ended = dto.FlighttaskStatusCanceled, true
+		}
+	case dto.MethodReturnHomeCancel:
+		if d.phase != phaseReturn {
+			return resultRejected
+		}
+		d.hover()
+	case dto.MethodDRCModeEnter:
+		g.drc = true
+	case dto.MethodDRCModeExit:
+		g.drc = false
+	case dto.MethodGimbalReset:
+		var data dto.GimbalResetData
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return resultRejected
+		}
+		switch data.ResetMode {
+		case dto.GimbalResetModeRecenter:
+			d.gimbalPitch, d.gimbalYaw = 0, 0
+		case dto.GimbalResetModeDown:
+			d.gimbalPitch, d.gimbalYaw = gimbalPitchMin, 0
+		case dto.GimbalResetModeYawRecenter:
+			d.gimbalYaw = 0
+		case dto.GimbalResetModePitchDown:
+			d.gimbalPitch = gimbalPitchMin
+		default:
+			return resultRejected
+		}
+	case dto.MethodCameraScreenDrag:
+		var data dto.CameraScreenDragData
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return resultRejected
+		}
+		// 按拖动 1 秒计算转过的角度
+		d.gimbalPitch = math.Max(gimbalPitchMin, math.Min(gimbalPitchMax, d.gimbalPitch+data.PitchSpeed*180/math.Pi))
+		d.gimbalYaw += data.YawSpeed * 180 / math.Pi
+	case dto.MethodCameraFocalLenSet:
+		var data dto.CameraFocalLengthSetData
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			return resultRejected
+		}
+		d.zoomFactor = data.ZoomFactor
+	}
+	return resultOK
+}
+
+// prepare 下载并解析航线文件，成功后等待 flighttask_execute
+func (g *gateway) prepare(req request) int {
+	var data dto.FlighttaskPrepareData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return resultRejected
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+	defer cancel()
+	w, err := downloadWayline(ctx, data.File.URL)
+	if err != nil {
+		g.l.Error("准备航线任务失败", slog.String("flightID", data.FlightID), slog.Any("err", err))
+		return resultRejected
+	}
+
+	rth := float64(data.RTHAltitude)
+	if rth <= 0 {
+		rth = g.cfg.rthHeight
+	}
+	g.mu.Lock()
+	g.prepared[data.FlightID] = &mission{
+		flightID:    data.FlightID,
+		wayline:     w,
+		rthAltitude: rth,
+		status:      dto.FlighttaskStatusSent,
+	}
+	g.mu.Unlock()
+	g.l.Info("航线任务已准备", slog.String("flightID", data.FlightID), slog.Int("waypoints", len(w.Points)))
+	return resultOK
+}
+
+// handlePropertySet 应答 property/set，所有属性均设置成功
+func (g *gateway) handlePropertySet(_ mqtt.Client, m mqtt.Message) {
+	var req request
+	if err := json.Unmarshal(m.Payload(), &req); err != nil {
+		g.l.Error("解析属性设置失败", slog.Any("err", err))
+		return
+	}
+	var props map[string]json.RawMessage
+	_ = json.Unmarshal(req.Data, &props)
+	results := make(map[string]any, len(props))
+	for key := range props {
+		results[key] = map[string]int{"result": resultOK}
+	}
+	req.Timestamp = time.Now().UnixMilli()
+	g.publish(m.Topic()+"_reply", 1, message{MessageCommon: req.MessageCommon, Data: results})
+}
+
+// handleDRC 应答 DRC 心跳，杆量等指令只记录不执行
+func (g *gateway) handleDRC(_ mqtt.Client, m mqtt.Message) {
+	var msg dto.DRCMessage
+	if err := json.Unmarshal(m.Payload(), &msg); err != nil {
+		return
+	}
+	if msg.Method != dto.MethodDRCHeartBeat {
+		g.l.Debug("收到 DRC 指令", slog.String("method", msg.Method))
+		return
+	}
+	g.publish(fmt.Sprintf("thing/product/%s/drc/up", g.sn), 0, dto.DRCMessage{
+		Method: dto.MethodDRCHeartBeat,
+		Seq:    msg.Seq,
+		Data:   map[string]int64{"timestamp": time.Now().UnixMilli()},
+	})
+}
+
+// progressEvent 构造 flighttask_progress 事件，调用方需持有锁
+func (g *gateway) progressEvent(m *mission) *message {
+	d := g.drone
+	total := len(m.wayline.Points)
+	reached := 0
+	state := missionStateToFirstPoint
+	switch {
+	case m.ended:
+		reached, state = d.index, missionStateExit
+		if m.status == dto.FlighttaskStatusOK {
+			reached = total
+		}
+	case m.status == dto.FlighttaskStatusPaused:
+		reached, state = d.index, missionStateInterrupted
+	case d.phase == phaseWayline:
+		reached, state = d.index, missionStateExecuting
+	}
+
+	data := dto.FlighttaskProgressData{Output: dto.FlighttaskProgressOutput{
+		Ext: dto.FlighttaskProgressExt{
+			CurrentWaypointIndex: reached,
+			WaylineMissionState:  state,
+			FlightID:             m.flightID,
+			WaylineID:            m.wayline.ID,
+		},
+		Progress: dto.FlighttaskProgress{Percent: reached * 100 / total},
+		Status:   m.status,
+	}}
+	msg := g.newMessage(dto.MethodFlighttaskProgress, data)
+	msg.BID, msg.NeedReply = m.bid, 1
+	return &msg
+}
+
+// droneOSD 无人机 OSD 数据，调用方需持有锁
+func (g *gateway) droneOSD() map[string]any {
+	d := g.drone
+	remain := int(d.battery * 18) // 按满电 30 分钟估算剩余飞行时间，单位：秒
+	return map[string]any{
+		"mode_code":        d.modeCode(),
+		"latitude":         d.pos.Lat,
+		"longitude":        d.pos.Lng,
+		"height":           g.cfg.altitude + d.pos.Height,
+		"elevation":        d.pos.Height,
+		"attitude_head":    d.head,
+		"attitude_pitch":   0,
+		"attitude_roll":    0,
+		"horizontal_speed": d.hSpeed,
+		"vertical_speed":   d.vSpeed,
+		"home_latitude":    d.home.Lat,
+		"home_longitude":   d.home.Lng,
+		"home_distance":    d.homeDistance(),
+		"gear":             1,
+		"wind_speed":       0,
+		"battery": map[string]any{
+			"capacity_percent":   int(d.battery),
+			"remain_flight_time": remain,
+			"return_home_power":  20,
+			"landing_power":      10,
+		},
+		"low_battery_warning_threshold":         30,
+		"serious_low_battery_warning_threshold": 20,
+		"compatible_status":                     0,
+		"storage":                               map[string]any{"total": 64 * 1024 * 1024, "used": 0}, // 单位：KB
+		g.cfg.payloadIndex: map[string]any{
+			"payload_index": g.cfg.payloadIndex,
+			"gimbal_pitch":  d.gimbalPitch,
+			"gimbal_roll":   0,
+			"gimbal_yaw":    d.gimbalYaw,
+			"zoom_factor":   d.zoomFactor,
+		},
+	}
+}
+
+// drcOSD DRC 链路的 osd_info_push 数据，调用方需持有锁
+func (g *gateway) drcOSD() map[string]any {
+	d := g.drone
+	head := d.head * math.Pi / 180
+	return map[string]any{
+		"latitude":      d.pos.Lat,
+		"longitude":     d.pos.Lng,
+		"height":        g.cfg.altitude + d.pos.Height,
+		"attitude_head": d.head,
+		"speed_x":       d.hSpeed * math.Sin(head),
+		"speed_y":       d.hSpeed * math.Cos(head),
+		"speed_z":       d.vSpeed,
+		"gimbal_pitch":  d.gimbalPitch,
+		"gimbal_yaw":    d.gimbalYaw,
+	}
+}
+
+// gatewayOSD 网关 OSD 数据，位置固定在无人机起飞点
+func (g *gateway) gatewayOSD() dto.GatewayOSDData {
+	return dto.GatewayOSDData{
+		CapacityPercent: 100,
+		Latitude:        g.drone.home.Lat,
+		Longitude:       g.drone.home.Lng,
+		Height:          g.cfg.altitude,
+		FirmwareVersion: thingVersion,
+	}
+}
+
+func (g *gateway) newMessage(method string, data any) message {
+	return message{
+		MessageCommon: dto.MessageCommon{
+			TID:       uuid.New().String(),
+			BID:       uuid.New().String(),
+			Method:    method,
+			Timestamp: time.Now().UnixMilli(),
+		},
+		Gateway: g.sn,
+		Data:    data,
+	}
+}
+
+func (g *gateway) publish(topic string, qos byte, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		g.l.Error("序列化消息失败", slog.String("topic", topic), slog.Any("err", err))
+		return
+	}
+	token := g.client.Publish(topic, qos, false, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		g.l.Warn("发布消息超时", slog.String("topic", topic))
+		return
+	}
+	if token.Error() != nil {
+		g.l.Error("发布消息失败", slog.String("topic", topic), slog.Any("err", token.Error()))
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGimbalReset(t *testing.T) {
+	tests := []struct {
+		name               string
+		mode               int
+		wantResult         int
+		wantPitch, wantYaw float64
+	}{
+		{"回中", dto.GimbalResetModeRecenter, resultOK, 0, 0},
+		{"向下", dto.GimbalResetModeDown, resultOK, gimbalPitchMin, 0},
+		{"偏航回中", dto.GimbalResetModeYawRecenter, resultOK, -30, 0},
+		{"俯仰向下", dto.GimbalResetModePitchDown, resultOK, gimbalPitchMin, 40},
+		{"无效模式", 4, resultRejected, -30, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGateway("gateway", "drone", point{}, nil, &config{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			g.drone.gimbalPitch, g.drone.gimbalYaw = -30, 40
+			data, _ := json.Marshal(dto.GimbalResetData{ResetMode: tt.mode})
+			req := request{Data: data}
+			req.Method = dto.MethodGimbalReset
+
+			assert.Equal(t, tt.wantResult, g.call(req))
+			assert.Equal(t, tt.wantPitch, g.drone.gimbalPitch)
+			assert.Equal(t, tt.wantYaw, g.drone.gimbalYaw)
+		})
+	}
+}
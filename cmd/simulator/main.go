@@ -0,0 +1,170 @@
+// simulator 模拟若干台网关及其挂载的无人机，通过 MQTT 与平台交互，用于在没有真机时联调
+//
+// 每台网关上报拓扑与 1 Hz 的 OSD，应答 services 调用；无人机沿配置的轨迹循环飞行，
+// 或执行平台下发的航线并上报 flighttask_progress。模拟设备首次上线后需在平台审批绑定。
+//
+//	go run ./cmd/simulator -n 2 -track "113.9430,22.5800;113.9450,22.5800;113.9450,22.5815"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func main() {
+	var (
+		broker    = flag.String("broker", envOr("MQTT_BROKER", "tcp://127.0.0.1:1883"), "MQTT Broker 地址")
+		username  = flag.String("username", os.Getenv("MQTT_USERNAME"), "MQTT 用户名")
+		password  = flag.String("password", os.Getenv("MQTT_PASSWORD"), "MQTT 密码")
+		count     = flag.Int("n", 1, "模拟的网关数量，每台网关挂载一架无人机")
+		prefix    = flag.String("prefix", "SIM", "设备 SN 前缀")
+		lat       = flag.Float64("lat", 22.5797, "第一台无人机起飞点纬度")
+		lng       = flag.Float64("lng", 113.9440, "第一台无人机起飞点经度")
+		spacing   = flag.Float64("spacing", 0.001, "相邻网关起飞点与轨迹的纬度间隔，单位：度")
+		altitude  = flag.Float64("altitude", 30, "起飞点椭球高，单位：米")
+		track     = flag.String("track", "", "循环飞行的轨迹，格式为 经度,纬度[,高度];...，为空时无人机停在起飞点")
+		height    = flag.Float64("height", 60, "轨迹未指定高度时的飞行高度，单位：米")
+		speed     = flag.Float64("speed", 10, "默认飞行速度，单位：m/s")
+		rth       = flag.Float64("rth", 100, "默认返航高度，单位：米")
+		droneKey  = flag.String("drone", "0-77-0", "无人机型号，格式为 domain-type-sub_type")
+		gwKey     = flag.String("gateway", "2-144-0", "网关型号，格式为 domain-type-sub_type")
+		payload   = flag.String("payload", "66-0-0", "主相机的 payload_index")
+		debugFlag = flag.Bool("debug", false, "输出调试日志")
+	)
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *debugFlag {
+		level = slog.LevelDebug
+	}
+	l := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+
+	cfg := &config{payloadIndex: *payload, altitude: *altitude, height: *height, speed: *speed, rthHeight: *rth}
+	var err error
+	if cfg.droneTopo, err = parseProductTopo(*droneKey); err != nil {
+		l.Error("无人机型号无效", slog.Any("err", err))
+		os.Exit(1)
+	}
+	if cfg.gatewayTopo, err = parseProductTopo(*gwKey); err != nil {
+		l.Error("网关型号无效", slog.Any("err", err))
+		os.Exit(1)
+	}
+	trackPoints, err := parseTrack(*track, *height)
+	if err != nil {
+		l.Error("轨迹无效", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(*broker)
+	opts.SetClientID(fmt.Sprintf("%s-simulator-%d", strings.ToLower(*prefix), time.Now().Unix()))
+	opts.SetUsername(*username)
+	opts.SetPassword(*password)
+	opts.SetAutoReconnect(true)
+	// 消息处理中会等待发布完成，需要并发执行处理函数
+	opts.SetOrderMatters(false)
+
+	var gateways []*gateway
+	opts.SetOnConnectHandler(func(mqtt.Client) {
+		l.Info("已连接 MQTT Broker", slog.String("broker", *broker))
+		for _, g := range gateways {
+			g.subscribe()
+		}
+	})
+	client := mqtt.NewClient(opts)
+	for i := 0; i < *count; i++ {
+		offset := float64(i) * *spacing
+		home := point{Lat: *lat + offset, Lng: *lng}
+		gatewaySN := fmt.Sprintf("%sGW%03d", *prefix, i+1)
+		droneSN := fmt.Sprintf("%sUAV%03d", *prefix, i+1)
+		gateways = append(gateways, newGateway(gatewaySN, droneSN, home, shiftTrack(trackPoints, offset), cfg, client, l))
+	}
+
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		l.Error("连接 MQTT Broker 失败", slog.String("broker", *broker), slog.Any("err", token.Error()))
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var wg sync.WaitGroup
+	for _, g := range gateways {
+		l.Info("启动模拟设备，首次上线需在平台审批绑定", slog.String("gatewaySN", g.sn), slog.String("droneSN", g.droneSN))
+		wg.Add(1)
+		go func(g *gateway) {
+			defer wg.Done()
+			g.run(ctx)
+		}(g)
+	}
+	wg.Wait()
+	client.Disconnect(250)
+	l.Info("模拟器已退出")
+}
+
+// parseProductTopo 解析 domain-type-sub_type 格式的型号
+func parseProductTopo(key string) (dto.ProductTopo, error) {
+	parts := strings.Split(key, "-")
+	if len(parts) != 3 {
+		return dto.ProductTopo{}, fmt.Errorf("格式应为 domain-type-sub_type: %q", key)
+	}
+	typ, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return dto.ProductTopo{}, err
+	}
+	subType, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return dto.ProductTopo{}, err
+	}
+	return dto.ProductTopo{Domain: parts[0], Type: typ, SubType: subType, ThingVersion: thingVersion}, nil
+}
+
+// parseTrack 解析 “经度,纬度[,高度];...” 格式的轨迹
+func parseTrack(s string, height float64) ([]point, error) {
+	var track []point
+	for _, item := range strings.Split(s, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		lng, lat, err := parseCoordinates(item)
+		if err != nil {
+			return nil, err
+		}
+		p := point{Lat: lat, Lng: lng, Height: height}
+		if parts := strings.Split(item, ","); len(parts) > 2 {
+			if p.Height, err = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64); err != nil {
+				return nil, err
+			}
+		}
+		track = append(track, p)
+	}
+	return track, nil
+}
+
+// shiftTrack 将轨迹整体向北平移 offset 度，避免多台无人机重叠
+func shiftTrack(track []point, offset float64) []point {
+	shifted := make([]point, len(track))
+	for i, p := range track {
+		p.Lat += offset
+		shifted[i] = p
+	}
+	return shifted
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// waylineFile KMZ 中航线执行文件的路径
+const waylineFile = "waylines.wpml"
+
+// wayline 从航线文件解析出的飞行路径
+type wayline struct {
+	ID     int
+	Speed  float64 // 全局飞行速度，单位：m/s，文件未指定时为 0
+	Points []point
+}
+
+// wpmlDocument waylines.wpml 中模拟器关心的部分
+// 字段标签不带命名空间，按本地名匹配 wpml: 前缀的元素
+type wpmlDocument struct {
+	Folders []struct {
+		WaylineID       int     `xml:"waylineId"`
+		AutoFlightSpeed float64 `xml:"autoFlightSpeed"`
+		Placemarks      []struct {
+			Coordinates   string  `xml:"Point>coordinates"`
+			ExecuteHeight float64 `xml:"executeHeight"`
+		} `xml:"Placemark"`
+	} `xml:"Document>Folder"`
+}
+
+// downloadWayline 下载 flighttask_prepare 中的航线文件并解析第一条航线
+func downloadWayline(ctx context.Context, url string) (*wayline, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载航线文件失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载航线文件失败: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取航线文件失败: %w", err)
+	}
+	return parseKMZ(data)
+}
+
+// parseKMZ 从 KMZ 压缩包中找到 waylines.wpml 并解析
+func parseKMZ(data []byte) (*wayline, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("航线文件不是有效的 KMZ: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != waylineFile {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+		return parseWPML(content)
+	}
+	return nil, fmt.Errorf("航线文件中缺少 %s", waylineFile)
+}
+
+// parseWPML 解析 waylines.wpml 中的第一条航线
+func parseWPML(content []byte) (*wayline, error) {
+	var doc wpmlDocument
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", waylineFile, err)
+	}
+	if len(doc.Folders) == 0 || len(doc.Folders[0].Placemarks) == 0 {
+		return nil, errors.New("航线中没有航点")
+	}
+
+	folder := doc.Folders[0]
+	w := &wayline{ID: folder.WaylineID, Speed: folder.AutoFlightSpeed}
+	for i, placemark := range folder.Placemarks {
+		lng, lat, err := parseCoordinates(placemark.Coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个航点坐标无效: %w", i, err)
+		}
+		w.Points = append(w.Points, point{Lat: lat, Lng: lng, Height: placemark.ExecuteHeight})
+	}
+	return w, nil
+}
+
+// parseCoordinates 解析 “经度,纬度” 格式的坐标，允许带高度分量
+func parseCoordinates(s string) (float64, float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ",")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("格式应为 经度,纬度: %q", s)
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lng, lat, nil
+}
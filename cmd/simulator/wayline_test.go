@@ -0,0 +1,80 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWPML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:wpml="http://www.dji.com/wpmz/1.0.2">
+  <Document>
+    <Folder>
+      <wpml:templateId>0</wpml:templateId>
+      <wpml:waylineId>3</wpml:waylineId>
+      <wpml:autoFlightSpeed>8</wpml:autoFlightSpeed>
+      <Placemark>
+        <Point><coordinates>113.9440,22.5797</coordinates></Point>
+        <wpml:index>0</wpml:index>
+        <wpml:executeHeight>50</wpml:executeHeight>
+      </Placemark>
+      <Placemark>
+        <Point><coordinates>113.9450,22.5800</coordinates></Point>
+        <wpml:index>1</wpml:index>
+        <wpml:executeHeight>60</wpml:executeHeight>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+func TestParseKMZ(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("wpmz/waylines.wpml")
+	require.NoError(t, err)
+	_, err = f.Write([]byte(testWPML))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	w, err := parseKMZ(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 3, w.ID)
+	assert.Equal(t, 8.0, w.Speed)
+	assert.Equal(t, []point{
+		{Lat: 22.5797, Lng: 113.9440, Height: 50},
+		{Lat: 22.5800, Lng: 113.9450, Height: 60},
+	}, w.Points)
+
+	_, err = parseKMZ([]byte("not a zip"))
+	assert.Error(t, err)
+}
+
+func TestDroneFliesWaylineAndReturns(t *testing.T) {
+	home := point{Lat: 22.5797, Lng: 113.9440}
+	d := newDrone(home)
+	path := []point{{Lat: 22.5800, Lng: 113.9440, Height: 20}}
+	d.fly(phaseWayline, path, 10, false)
+	assert.Equal(t, dto.ModeCodeAutoTakeoff, d.modeCode())
+
+	for i := 0; i < 60 && d.index < len(path); i++ {
+		d.step(1)
+		if d.phase == phaseWayline {
+			assert.LessOrEqual(t, d.hSpeed, 10.0)
+		}
+	}
+	require.Equal(t, len(path), d.index)
+	assert.InDelta(t, 20, d.pos.Height, arriveHeight)
+
+	assert.True(t, d.returnHome(30))
+	for i := 0; i < 120 && d.airborne(); i++ {
+		d.step(1)
+	}
+	assert.Equal(t, phaseGround, d.phase)
+	assert.Equal(t, dto.ModeCodeStandby, d.modeCode())
+	assert.Equal(t, home, d.pos)
+	assert.False(t, d.returnHome(30))
+}
@@ -14,6 +14,7 @@ type Config struct {
 	MQTT     MQTTConfig     `mapstructure:"mqtt"`
 	Redis    RedisConfig    `mapstructure:"redis"`
 	Platform PlatformConfig `mapstructure:"platform"` // 新增平台配置
+	Audit    AuditConfig    `mapstructure:"audit"`    // MQTT 消息审计
 }
 
 func (c *Config) GetDBStr() string {
@@ -83,6 +84,21 @@ type PlatformConfig struct {
 		Host  string `mapstructure:"host"`  // WebSocket服务地址
 		Token string `mapstructure:"token"` // WebSocket访问令牌
 	} `mapstructure:"ws"`
+	NTPServerHost  string `mapstructure:"ntp_server_host"` // 下发给设备的 NTP 服务地址
+	OfflineTimeout int    `mapstructure:"offline_timeout"` // 设备离线判定时间，单位：秒，超过该时间未收到消息即视为离线
+	App            struct {
+		ID      string `mapstructure:"id"`      // 大疆开发者平台应用ID
+		Key     string `mapstructure:"key"`     // 应用Key
+		License string `mapstructure:"license"` // 应用License
+	} `mapstructure:"app"`
+}
+
+// AuditConfig MQTT 消息审计配置
+type AuditConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`         // 是否记录 MQTT 收发消息
+	SampleRate     float64 `mapstructure:"sample_rate"`     // osd、state 等高频遥测的采样率，取值 (0, 1]，默认全部记录
+	RetentionHours int     `mapstructure:"retention_hours"` // 记录保留时长，单位：小时，0 表示不按时间清理
+	MaxRows        int64   `mapstructure:"max_rows"`        // 最多保留的记录数，0 表示不限制
 }
 
 func LoadConfig() (*Config, error) {
@@ -127,6 +143,17 @@ func LoadConfig() (*Config, error) {
 	_ = viper.BindEnv("platform.api.token", "PLATFORM_API_TOKEN")
 	_ = viper.BindEnv("platform.ws.host", "PLATFORM_WS_HOST")
 	_ = viper.BindEnv("platform.ws.token", "PLATFORM_WS_TOKEN")
+	_ = viper.BindEnv("platform.ntp_server_host", "PLATFORM_NTP_SERVER_HOST")
+	_ = viper.BindEnv("platform.app.id", "PLATFORM_APP_ID")
+	_ = viper.BindEnv("platform.app.key", "PLATFORM_APP_KEY")
+	_ = viper.BindEnv("platform.app.license", "PLATFORM_APP_LICENSE")
+	_ = viper.BindEnv("platform.offline_timeout", "PLATFORM_OFFLINE_TIMEOUT")
+
+	// MQTT 审计相关环境变量
+	_ = viper.BindEnv("audit.enabled", "AUDIT_ENABLED")
+	_ = viper.BindEnv("audit.sample_rate", "AUDIT_SAMPLE_RATE")
+	_ = viper.BindEnv("audit.retention_hours", "AUDIT_RETENTION_HOURS")
+	_ = viper.BindEnv("audit.max_rows", "AUDIT_MAX_ROWS")
 
 	// 反序列化配置文件到结构体
 	var config Config
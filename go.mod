@@ -27,6 +27,7 @@ require (
 	github.com/swaggo/swag v1.16.4
 	golang.org/x/crypto v0.33.0
 	gorm.io/datatypes v1.2.5
+	gorm.io/driver/mysql v1.5.7
 	gorm.io/driver/postgres v1.5.11
 	gorm.io/gorm v1.25.12
 )
@@ -118,5 +119,4 @@ require (
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	gorm.io/driver/mysql v1.5.7 // indirect
 )
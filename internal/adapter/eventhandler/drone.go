@@ -2,8 +2,11 @@ package eventhandler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/asaskevich/EventBus"
 	"github.com/bytedance/sonic"
@@ -12,6 +15,8 @@ import (
 	"github.com/dronesphere/internal/repo"
 	"github.com/dronesphere/internal/service"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
 )
 
 type DroneEventHandler struct {
@@ -19,93 +24,127 @@ type DroneEventHandler struct {
 	l          *slog.Logger
 	svc        service.DroneSvc
 	gatewaySvc service.GatewaySvc
+	bindings   service.DeviceBindingSvc // 拓扑中出现未知设备时生成待审批记录
 	mqtt       mqtt.Client
 	modelRepo  *repo.ModelDefaultRepo // 添加模型仓库依赖
+	gateways   sync.Map               // 已知的网关 SN，用于区分 OSD 消息来自网关还是无人机
+	liveness   *LivenessMonitor       // 设备在线状态监测
 }
 
-func registerDroneHandlers(eb EventBus.Bus, l *slog.Logger, mqtt mqtt.Client, drone service.DroneSvc, gateway service.GatewaySvc, modelRepo *repo.ModelDefaultRepo) {
+func registerDroneHandlers(eb EventBus.Bus, l *slog.Logger, mqtt mqtt.Client, router *TopicRouter, liveness *LivenessMonitor, drone service.DroneSvc, gateway service.GatewaySvc, bindings service.DeviceBindingSvc, modelRepo *repo.ModelDefaultRepo) {
 	handler := &DroneEventHandler{
 		eb:         eb,
 		l:          l,
 		svc:        drone,
 		gatewaySvc: gateway,
+		bindings:   bindings,
 		mqtt:       mqtt,
 		modelRepo:  modelRepo, // 初始化模型仓库
+		liveness:   liveness,
 	}
-	var err error
-	err = eb.Subscribe(event.RemoteControllerLoggedIn, handler.HandleTopoUpdate)
+
+	// 加载已登记的网关，重启后无需等待网关重新上线即可识别其 OSD
+	gateways, err := gateway.Repo().SelectAll(context.Background())
+	if err != nil {
+		l.Error("查询网关列表失败", slog.Any("err", err))
+	}
+	for _, g := range gateways {
+		handler.gateways.Store(g.SN, struct{}{})
+	}
+
+	err = eb.Subscribe(event.RemoteControllerLoggedIn, handler.HandleRemoteControllerLoggedIn)
 	if err != nil {
 		panic(err)
 	}
-	_ = eb.Subscribe(event.DroneConnected, handler.HandleDroneConnected)
-	err = eb.Subscribe(event.DroneConnected, handler.HandleDroneOSD)
+	err = eb.Subscribe(event.DroneConnected, handler.HandleDroneConnected)
 	if err != nil {
 		panic(err)
 	}
-	//err = eb.Subscribe(event.DroneConnected, handler.HandleDroneState)
-	//if err != nil {
-	//	panic(err)
-	//}
+
+	router.Handle(TopicStatus, handler.handleTopoUpdate)
+	router.Handle(TopicOSD, handler.handleOSD)
+	router.Handle(TopicState, handler.handleState)
 }
 
-// HandleTopoUpdate 处理拓扑更新事件
-//
-// 监听 sys/product/{sn}/status 主题，处理无人机拓扑更新事件
-func (d *DroneEventHandler) HandleTopoUpdate(ctx context.Context) error {
+// HandleRemoteControllerLoggedIn 处理遥控器登录事件，记录网关 SN
+func (d *DroneEventHandler) HandleRemoteControllerLoggedIn(ctx context.Context) error {
 	gatewaySN := ctx.Value(event.RemoteControllerLoginSNKey).(string)
-	template := "sys/product/%s/status"
-	subscribeTopic := fmt.Sprintf(template, gatewaySN)
-	d.l.Info("识别网关设备上线", slog.Any("topic", subscribeTopic), slog.Any("desc", "设备上下线、更新拓扑"), slog.Any("gatewaySN", gatewaySN))
+	d.gateways.Store(gatewaySN, struct{}{})
+	d.l.Info("识别网关设备登录", slog.Any("gatewaySN", gatewaySN))
+	return nil
+}
 
-	token := d.mqtt.Subscribe(subscribeTopic, 1, func(c mqtt.Client, m mqtt.Message) {
-		var p struct {
-			dto.MessageCommon
-			Data dto.UpdateTopoPayload `json:"data"`
-		}
-		_ = sonic.Unmarshal(m.Payload(), &p)
-		d.l.Info("接收网关设备上下线消息", slog.Any("topic", m.Topic()), slog.Any("payload", p))
+// isGateway 判断 SN 是否为已知网关
+func (d *DroneEventHandler) isGateway(sn string) bool {
+	_, ok := d.gateways.Load(sn)
+	return ok
+}
 
-		// 保存网关数据
-		if err := d.gatewaySvc.Repo().Save(ctx, gatewaySN, p.Data.Type, p.Data.SubType); err != nil {
-			d.l.Error("保存网关数据失败", slog.Any("error", err))
-		}
+// handleTopoUpdate 处理拓扑更新消息
+//
+// 监听 sys/product/{gateway_sn}/status 主题，处理设备上下线、更新拓扑
+func (d *DroneEventHandler) handleTopoUpdate(gatewaySN string, m mqtt.Message) {
+	var p struct {
+		dto.MessageCommon
+		Data dto.UpdateTopoPayload `json:"data"`
+	}
+	if err := sonic.Unmarshal(m.Payload(), &p); err != nil {
+		d.l.Error("解析网关设备上下线消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
+		return
+	}
+	d.l.Info("接收网关设备上下线消息", slog.Any("topic", m.Topic()), slog.Any("payload", p))
+	// 未绑定的网关只应答，不保存拓扑
+	if !d.bindings.Observe(context.Background(), gatewaySN, p.Data.ProductTopo) {
+		d.replyTopoUpdate(gatewaySN, p.MessageCommon)
+		return
+	}
+	d.gateways.Store(gatewaySN, struct{}{})
+	d.liveness.TouchGateway(gatewaySN)
+	ctx := context.WithValue(context.Background(), event.RemoteControllerLoginSNKey, gatewaySN)
 
-		// SubDevices 够长说明为无人机上线事件，否则为下线事件
-		if len(p.Data.SubDevices) > 0 {
-			droneSN := p.Data.SubDevices[0].SN
-			d.l.Info("识别无人机上线", slog.Any("droneSN", droneSN))
-			ctx = context.WithValue(ctx, event.DroneEventSNKey, droneSN)
-			ctx = context.WithValue(ctx, event.DroneEventTopoKey, p.Data.SubDevices[0].ProductTopo)
-
-			// 使用goroutine异步发布事件，避免死锁
-			go func(eventCtx context.Context) {
-				d.eb.Publish(event.DroneConnected, eventCtx)
-			}(ctx)
-		} else {
-			d.l.Info("识别无人机下线", slog.Any("gatewaySN", gatewaySN))
-		}
+	// 保存网关数据
+	if err := d.gatewaySvc.Repo().Save(ctx, gatewaySN, p.Data.Type, p.Data.SubType); err != nil {
+		d.l.Error("保存网关数据失败", slog.Any("error", err))
+	}
 
-		// 发布成功消息响应
-		r, _ := sonic.Marshal(dto.NewMessageResult(p.MessageCommon, 0))
-		publishTopic := fmt.Sprintf("sys/product/%s/status_reply", gatewaySN)
-		d.l.Info("应答网关设备上下线消息", slog.Any("topic", publishTopic), slog.Any("payload", r))
-		// MQTT客户端的Publish方法返回的是Token，而不是error
-		token := d.mqtt.Publish(publishTopic, 1, false, r)
-		if token.Wait() && token.Error() != nil {
-
-			panic(
-				fmt.Sprintf("发布网关响应消息失败: %s", token.Error()),
-			)
+	// SubDevices 够长说明为无人机上线事件，否则为下线事件，未绑定的无人机按下线处理
+	online := len(p.Data.SubDevices) > 0
+	if online && !d.bindings.Observe(ctx, p.Data.SubDevices[0].SN, p.Data.SubDevices[0].ProductTopo) {
+		d.l.Warn("忽略未绑定的无人机", slog.Any("droneSN", p.Data.SubDevices[0].SN), slog.Any("gatewaySN", gatewaySN))
+		online = false
+	}
+	if online {
+		droneSN := p.Data.SubDevices[0].SN
+		d.l.Info("识别无人机上线", slog.Any("droneSN", droneSN))
+		if err := d.svc.Repo().SaveGatewaySNByDroneSN(ctx, droneSN, gatewaySN); err != nil {
+			d.l.Error("保存无人机网关关系失败", slog.Any("droneSN", droneSN), slog.Any("error", err))
 		}
-	})
-	if token.Wait() && token.Error() != nil {
-		d.l.Error("设备上下线订阅失败", slog.Any("topic", subscribeTopic), slog.Any("err", token.Error()))
-		return token.Error()
+		// 网关换挂无人机时，结束与之前无人机的关联
+		d.disconnectDrones(ctx, gatewaySN, droneSN)
+		ctx = context.WithValue(ctx, event.DroneEventSNKey, droneSN)
+		ctx = context.WithValue(ctx, event.DroneEventTopoKey, p.Data.SubDevices[0].ProductTopo)
+
+		// 使用goroutine异步发布事件，避免死锁
+		go func(eventCtx context.Context) {
+			d.eb.Publish(event.DroneConnected, eventCtx)
+		}(ctx)
 	} else {
-		d.l.Info("设备上下线订阅成功", slog.Any("topic", subscribeTopic))
+		d.l.Info("识别无人机下线", slog.Any("gatewaySN", gatewaySN))
+		d.disconnectDrones(ctx, gatewaySN, "")
 	}
 
-	return nil
+	d.replyTopoUpdate(gatewaySN, p.MessageCommon)
+}
+
+// replyTopoUpdate 应答网关的上下线消息
+func (d *DroneEventHandler) replyTopoUpdate(gatewaySN string, common dto.MessageCommon) {
+	r, _ := sonic.Marshal(dto.NewMessageResult(common, 0))
+	publishTopic := fmt.Sprintf("sys/product/%s/status_reply", gatewaySN)
+	d.l.Info("应答网关设备上下线消息", slog.Any("topic", publishTopic), slog.Any("payload", r))
+	token := d.mqtt.Publish(publishTopic, 1, false, r)
+	if token.Wait() && token.Error() != nil {
+		d.l.Error("发布网关响应消息失败", slog.Any("topic", publishTopic), slog.Any("err", token.Error()))
+	}
 }
 
 // HandleDroneConnected 处理无人机连接事件
@@ -131,84 +170,130 @@ func (d *DroneEventHandler) HandleDroneConnected(ctx context.Context) error {
 	}
 	d.l.Info("保存无人机信息成功", slog.Any("droneSN", droneSN))
 
-	return nil
-}
-
-func (d *DroneEventHandler) ParseHeartBeat(m mqtt.Message) (dto.DroneMessageProperty, bool) {
-	var p struct {
-		dto.MessageCommon
-		Data dto.DroneMessageProperty `json:"data"`
-	}
-	if err := sonic.Unmarshal(m.Payload(), &p); err != nil {
-		d.l.Error("解析无人机心跳消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
-		return dto.DroneMessageProperty{}, false
+	// 记录网关与无人机的关联关系
+	if gatewaySN, ok := ctx.Value(event.RemoteControllerLoginSNKey).(string); ok {
+		if err := d.gatewaySvc.Repo().AddDroneRelation(ctx, gatewaySN, droneSN); err != nil {
+			d.l.Error("添加网关与无人机关联关系失败", slog.Any("gatewaySN", gatewaySN), slog.Any("droneSN", droneSN), slog.Any("err", err))
+		}
 	}
 
-	return p.Data, true
+	return nil
 }
 
-// HandleDroneOSD 处理无人机 OSD 事件
+// disconnectDrones 处理无人机脱离网关拓扑
 //
-// 监听 thing/product/{sn}/osd 主题，处理无人机 OSD 事件
-func (d *DroneEventHandler) HandleDroneOSD(ctx context.Context) error {
-	gatewaySN := ctx.Value(event.RemoteControllerLoginSNKey).(string)
-	droneSN := ctx.Value(event.DroneEventSNKey).(string)
-	template := "thing/product/%s/osd"
-	topic := fmt.Sprintf(template, droneSN)
-	d.l.Info("识别无人机上线", slog.Any("topic", topic), slog.Any("droneSN", droneSN), slog.Any("gatewaySN", gatewaySN))
+// 结束网关下除 keepSN 外的关联关系，将对应无人机标记为离线并停止离线检测，
+// 随后发布 DroneDisconnectedEvent 并通知订阅该无人机的前端连接
+func (d *DroneEventHandler) disconnectDrones(ctx context.Context, gatewaySN, keepSN string) {
+	droneSNs, err := d.gatewaySvc.Repo().EndDroneRelations(ctx, gatewaySN, keepSN)
+	if err != nil {
+		d.l.Error("结束网关与无人机关联关系失败", slog.Any("gatewaySN", gatewaySN), slog.Any("error", err))
+		return
+	}
 
-	token := d.mqtt.Subscribe(topic, 0, func(c mqtt.Client, m mqtt.Message) {
-		d.l.Info("接收无人机 OSD 消息", slog.Any("topic", m.Topic()), slog.Any("message", string(m.Payload())))
-		p, ok := d.ParseHeartBeat(m)
-		if !ok {
+	now := time.Now()
+	for _, droneSN := range droneSNs {
+		d.l.Info("无人机脱离网关拓扑", slog.Any("droneSN", droneSN), slog.Any("gatewaySN", gatewaySN))
+		d.liveness.ForgetDrone(droneSN)
+		if err := d.svc.Repo().SaveOffline(ctx, droneSN, now); err != nil {
+			d.l.Error("保存无人机离线状态失败", slog.Any("droneSN", droneSN), slog.Any("error", err))
+		}
+		if err := d.svc.Repo().DeleteGatewaySNByDroneSN(ctx, droneSN); err != nil {
+			d.l.Error("删除无人机网关关系失败", slog.Any("droneSN", droneSN), slog.Any("error", err))
+		}
 
-			return
+		payload := &event.DroneDisconnectedPayload{
+			SN:        droneSN,
+			GatewaySN: gatewaySN,
+			Timestamp: now.UnixMilli(),
 		}
-		d.l.Info("接收无人机 OSD 消息", slog.Any("topic", m.Topic()), slog.Any("payload", p))
+		d.eb.Publish(event.DroneDisconnectedEvent, payload)
 
-		if err := d.svc.UpdateStateBySN(ctx, droneSN, p); err != nil {
-			d.l.Error("更新无人机实时数据失败", slog.Any("err", err))
-			return
+		push, err := sonic.Marshal(dto.WSbaseModel{
+			TID:       uuid.New().String(),
+			Timestamp: now.Unix(),
+			Method:    dto.WSMethodDroneDisconnected,
+			Data:      payload,
+		})
+		if err != nil {
+			d.l.Error("序列化无人机脱离消息失败", slog.Any("error", err))
+			continue
 		}
-		d.l.Info("更新无人机实时数据成功", slog.Any("droneSN", droneSN))
-	})
-	if token.Wait() && token.Error() != nil {
-		d.l.Error("无人机 OSD 订阅失败", slog.Any("topic", topic), slog.Any("err", token.Error()))
-		return token.Error()
-	} else {
-		d.l.Info("无人机 OSD 订阅成功", slog.Any("topic", topic))
+		d.svc.BroadcastToSN(droneSN, websocket.TextMessage, string(push))
 	}
-
-	return nil
 }
 
-func (d *DroneEventHandler) HandleDroneState(ctx context.Context) error {
-	droneSN := ctx.Value(event.DroneEventSNKey).(string)
-	d.l.Info("Handle drone state event", slog.Any("droneSN", droneSN))
-	template := "thing/product/%s/state"
-	topic := fmt.Sprintf(template, droneSN)
-	d.l.Info("Subscribe drone state topic", slog.Any("topic", topic))
-
-	token := d.mqtt.Subscribe(topic, 1, func(c mqtt.Client, m mqtt.Message) {
-		d.l.Info("Received message", slog.Any("topic", m.Topic()), slog.Any("message", string(m.Payload())))
-		// 解析消息
-		p, ok := d.ParseHeartBeat(m)
-		if !ok {
-			d.l.Error("Parse heartbeat failed")
-			return
+// handleOSD 处理 OSD 消息
+//
+// 监听 thing/product/{sn}/osd 主题，网关与无人机共用该主题，按 SN 区分后分别保存实时数据
+func (d *DroneEventHandler) handleOSD(sn string, m mqtt.Message) {
+	ctx := context.Background()
+	if d.isGateway(sn) {
+		d.liveness.TouchGateway(sn)
+		var p struct {
+			dto.MessageCommon
+			Data dto.GatewayOSDData `json:"data"`
 		}
-
-		if err := d.svc.UpdateStateBySN(ctx, droneSN, p); err != nil {
-			d.l.Error("UpdateCallsign drone online failed", slog.Any("err", err))
+		if err := sonic.Unmarshal(m.Payload(), &p); err != nil {
+			d.l.Error("解析网关 OSD 消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
 			return
 		}
+		if err := d.gatewaySvc.UpdateStateBySN(ctx, sn, p.Data); err != nil {
+			d.l.Error("更新网关实时数据失败", slog.Any("err", err))
+		}
+		return
+	}
 
-		d.l.Info("UpdateCallsign drone online success", slog.Any("droneSN", droneSN))
-	})
-	if token.Wait() && token.Error() != nil {
-		d.l.Error("Subscribe topic failed", slog.Any("err", token.Error()))
-		return token.Error()
+	d.liveness.TouchDrone(sn)
+	var p struct {
+		dto.MessageCommon
+		Data json.RawMessage `json:"data"`
 	}
+	if err := sonic.Unmarshal(m.Payload(), &p); err != nil {
+		d.l.Error("解析无人机心跳消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
+		return
+	}
+	if err := d.svc.UpdateStateBySN(ctx, sn, p.Data); err != nil {
+		d.l.Error("更新无人机实时数据失败", slog.Any("err", err))
+	}
+}
 
-	return nil
+// handleState 处理无人机属性变化消息
+//
+// 监听 thing/product/{sn}/state 主题，state 消息只携带发生变化的属性，
+// 按字段合并到实时数据中，并为每个变化的属性发布 DroneStateChanged 事件
+func (d *DroneEventHandler) handleState(sn string, m mqtt.Message) {
+	if d.isGateway(sn) {
+		d.liveness.TouchGateway(sn)
+		d.l.Debug("忽略网关属性消息", slog.Any("gatewaySN", sn))
+		return
+	}
+	d.liveness.TouchDrone(sn)
+
+	var p struct {
+		dto.MessageCommon
+		Data json.RawMessage `json:"data"`
+	}
+	if err := sonic.Unmarshal(m.Payload(), &p); err != nil {
+		d.l.Error("解析无人机属性消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
+		return
+	}
+
+	changes, err := d.svc.UpdatePropertiesBySN(context.Background(), sn, p.Data)
+	if err != nil {
+		d.l.Error("更新无人机属性失败", slog.Any("droneSN", sn), slog.Any("err", err))
+		return
+	}
+	publishDroneStateChanges(d.eb, changes)
+}
+
+// publishDroneStateChanges 发布无人机属性变化事件
+//
+// 每个属性同时发布到通用事件 DroneStateChanged 与该属性的专属事件
+func publishDroneStateChanges(eb EventBus.Bus, changes []event.DroneStateChangedPayload) {
+	for _, change := range changes {
+		ctx := context.WithValue(context.Background(), event.DroneStateChangedKey, change)
+		eb.Publish(event.DroneStateChanged, ctx)
+		eb.Publish(event.DroneStatePropertyChanged(change.Property), ctx)
+	}
 }
@@ -0,0 +1,201 @@
+package eventhandler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+)
+
+// eventFunc 处理单个 events 方法，gatewaySN 为上报事件的网关
+type eventFunc func(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error
+
+// EventsHandler 设备事件处理器
+//
+// 监听 thing/product/{gateway_sn}/events 主题，按 method 分发到对应的处理方法
+type EventsHandler struct {
+	mqtt        mqtt.Client
+	l           *slog.Logger
+	droneSvc    service.DroneSvc
+	jobSvc      service.JobSvc
+	hmsSvc      service.HMSSvc
+	firmwareSvc service.FirmwareSvc
+	logSvc      service.DeviceLogSvc
+	mediaSvc    service.MediaSvc
+	methods     map[string]eventFunc
+}
+
+// NewEventsHandler 创建设备事件处理器
+func NewEventsHandler(mqtt mqtt.Client, l *slog.Logger, droneSvc service.DroneSvc, jobSvc service.JobSvc, hmsSvc service.HMSSvc, firmwareSvc service.FirmwareSvc, logSvc service.DeviceLogSvc, mediaSvc service.MediaSvc) *EventsHandler {
+	h := &EventsHandler{
+		mqtt:        mqtt,
+		l:           l,
+		droneSvc:    droneSvc,
+		jobSvc:      jobSvc,
+		hmsSvc:      hmsSvc,
+		firmwareSvc: firmwareSvc,
+		logSvc:      logSvc,
+		mediaSvc:    mediaSvc,
+	}
+	h.methods = map[string]eventFunc{
+		dto.MethodFlighttaskProgress:                   h.handleFlighttaskProgress,
+		dto.MethodHMS:                                  h.handleHMS,
+		dto.MethodOTAProgress:                          h.handleOTAProgress,
+		dto.MethodFileuploadProgress:                   h.handleFileuploadProgress,
+		dto.MethodFileUploadCallback:                   h.handleFileUploadCallback,
+		dto.MethodHighestPriorityUploadFlighttaskMedia: h.handleHighestPriorityUpload,
+	}
+	return h
+}
+
+// handleEvents 解析事件消息并分发，需要回复的事件在处理后回复 events_reply
+func (h *EventsHandler) handleEvents(gatewaySN string, m mqtt.Message) {
+	var msg dto.EventsMessage
+	if err := sonic.Unmarshal(m.Payload(), &msg); err != nil {
+		h.l.Error("解析设备事件消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
+		return
+	}
+
+	result := 0
+	fn, ok := h.methods[msg.Method]
+	if !ok {
+		h.l.Debug("忽略未处理的设备事件", slog.Any("gatewaySN", gatewaySN), slog.Any("method", msg.Method))
+	} else if err := fn(context.Background(), gatewaySN, msg); err != nil {
+		h.l.Error("处理设备事件失败", slog.Any("gatewaySN", gatewaySN), slog.Any("method", msg.Method), slog.Any("error", err))
+		result = 1
+	}
+
+	if msg.NeedReply == 1 {
+		h.reply(gatewaySN, msg.MessageCommon, result)
+	}
+}
+
+// reply 应答设备事件
+func (h *EventsHandler) reply(gatewaySN string, com dto.MessageCommon, result int) {
+	com.Timestamp = time.Now().UnixMilli()
+	r, _ := sonic.Marshal(dto.NewMessageResult(com, result))
+	topic := fmt.Sprintf("thing/product/%s/events_reply", gatewaySN)
+	token := h.mqtt.Publish(topic, 1, false, r)
+	if token.Wait() && token.Error() != nil {
+		h.l.Error("应答设备事件失败", slog.Any("topic", topic), slog.Any("error", token.Error()))
+	}
+}
+
+// handleFlighttaskProgress 处理航线任务进度上报，持久化后推送给前端
+func (h *EventsHandler) handleFlighttaskProgress(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.FlighttaskProgressData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+
+	execution, err := h.jobSvc.HandleFlighttaskProgress(ctx, gatewaySN, data)
+	if err != nil {
+		return err
+	}
+
+	push := dto.WSbaseModel{
+		TID:       uuid.New().String(),
+		Timestamp: time.Now().Unix(),
+		Method:    dto.MethodFlighttaskProgress,
+		Data:      execution,
+	}
+	payload, err := sonic.Marshal(push)
+	if err != nil {
+		return err
+	}
+	h.droneSvc.BroadcastToSN(execution.DroneSN, websocket.TextMessage, string(payload))
+	return nil
+}
+
+// handleHMS 处理设备健康告警
+func (h *EventsHandler) handleHMS(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.HMSData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+	return h.hmsSvc.HandleHMS(ctx, gatewaySN, data)
+}
+
+// handleOTAProgress 处理固件升级进度，通过 bid 关联到升级任务
+func (h *EventsHandler) handleOTAProgress(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.OTAProgressData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+	return h.firmwareSvc.HandleOTAProgress(ctx, gatewaySN, msg.BID, data)
+}
+
+// handleFileuploadProgress 处理设备日志上传进度，持久化后推送给前端
+func (h *EventsHandler) handleFileuploadProgress(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.FileuploadProgressData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+
+	logs, err := h.logSvc.HandleFileuploadProgress(ctx, gatewaySN, data)
+	if err != nil {
+		return err
+	}
+	for _, log := range logs {
+		if err := h.push(log.DroneSN, dto.MethodFileuploadProgress, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleFileUploadCallback 登记机场上传完成的媒体文件
+func (h *EventsHandler) handleFileUploadCallback(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.FileUploadCallbackData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+
+	media, err := h.mediaSvc.HandleUploadCallback(ctx, gatewaySN, data.File)
+	if err != nil {
+		return err
+	}
+	return h.push(media.DroneSN, dto.MethodFileUploadCallback, map[string]any{
+		"media":       media,
+		"flight_task": data.FlightTask,
+	})
+}
+
+// handleHighestPriorityUpload 处理机场当前优先上传的航线任务
+func (h *EventsHandler) handleHighestPriorityUpload(ctx context.Context, gatewaySN string, msg dto.EventsMessage) error {
+	var data dto.FlighttaskMediaData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+
+	execution, err := h.mediaSvc.HandleHighestPriority(ctx, gatewaySN, data.FlightID)
+	if err != nil {
+		return err
+	}
+	return h.push(execution.DroneSN, dto.MethodHighestPriorityUploadFlighttaskMedia, data)
+}
+
+// push 向订阅无人机的前端连接推送消息
+func (h *EventsHandler) push(droneSN, method string, data any) error {
+	if droneSN == "" {
+		return nil
+	}
+	payload, err := sonic.Marshal(dto.WSbaseModel{
+		TID:       uuid.New().String(),
+		Timestamp: time.Now().Unix(),
+		Method:    method,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	h.droneSvc.BroadcastToSN(droneSN, websocket.TextMessage, string(payload))
+	return nil
+}
@@ -1,21 +1,41 @@
 package eventhandler
 
 import (
+	"context"
 	"log/slog"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 
 	"github.com/asaskevich/EventBus"
+	"github.com/dronesphere/configs"
 	"github.com/dronesphere/internal/repo"
 	"github.com/dronesphere/internal/service"
 )
 
 // NewHandler 创建事件处理器
-func NewHandler(eb EventBus.Bus, l *slog.Logger, mq mqtt.Client, drone service.DroneSvc, gatewaySvc service.GatewaySvc, modelRepo *repo.ModelDefaultRepo, gatewayRepo repo.GatewayRepo) {
+func NewHandler(eb EventBus.Bus, l *slog.Logger, mq mqtt.Client, cfg *configs.Config, drone service.DroneSvc, gatewaySvc service.GatewaySvc, jobSvc service.JobSvc, hmsSvc service.HMSSvc, firmwareSvc service.FirmwareSvc, logSvc service.DeviceLogSvc, mediaSvc service.MediaSvc, bindingSvc service.DeviceBindingSvc, modelRepo *repo.ModelDefaultRepo, gatewayRepo repo.GatewayRepo) {
+	// 所有设备上行主题由路由器统一订阅，按主题中的 SN 分发，未绑定设备的消息被忽略
+	router := NewTopicRouter(mq, l, bindingSvc.IsBound)
+
+	// 设备离线检测
+	liveness := NewLivenessMonitor(eb, l, drone, gatewaySvc, time.Duration(cfg.Platform.OfflineTimeout)*time.Second)
+	go liveness.Run(context.Background())
+
 	// 注册无人机事件处理器
-	registerDroneHandlers(eb, l, mq, drone, gatewaySvc, modelRepo)
+	registerDroneHandlers(eb, l, mq, router, liveness, drone, gatewaySvc, bindingSvc, modelRepo)
 
 	// 注册网关事件处理器
 	gatewayHandler := NewGatewayHandler(eb, mq, gatewayRepo, l)
 	gatewayHandler.Subscribe(eb)
+
+	// 注册设备事件处理器
+	eventsHandler := NewEventsHandler(mq, l, drone, jobSvc, hmsSvc, firmwareSvc, logSvc, mediaSvc)
+	router.Handle(TopicEvents, eventsHandler.handleEvents)
+
+	// 注册设备请求处理器
+	requestsHandler := NewRequestsHandler(mq, l, cfg)
+	router.Handle(TopicRequests, requestsHandler.handleRequests)
+
+	router.Subscribe()
 }
@@ -0,0 +1,178 @@
+package eventhandler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/dronesphere/internal/event"
+	"github.com/dronesphere/internal/model/ro"
+	"github.com/dronesphere/internal/service"
+)
+
+// defaultOfflineTimeout 未配置离线判定时间时的默认值
+const defaultOfflineTimeout = 30 * time.Second
+
+// LivenessMonitor 设备在线状态监测
+//
+// 记录每台无人机和网关最后一次上报消息的时间，超过 timeout 未收到消息即标记为离线，
+// 写入最后在线时间并在事件总线上发布离线事件
+type LivenessMonitor struct {
+	eb         EventBus.Bus
+	l          *slog.Logger
+	droneSvc   service.DroneSvc
+	gatewaySvc service.GatewaySvc
+	timeout    time.Duration
+
+	mu       sync.Mutex
+	drones   map[string]time.Time // 无人机 SN -> 最后一次收到消息的时间
+	gateways map[string]time.Time // 网关 SN -> 最后一次收到消息的时间
+}
+
+// NewLivenessMonitor 创建设备在线状态监测，timeout 为 0 时使用默认值
+func NewLivenessMonitor(eb EventBus.Bus, l *slog.Logger, droneSvc service.DroneSvc, gatewaySvc service.GatewaySvc, timeout time.Duration) *LivenessMonitor {
+	if timeout <= 0 {
+		timeout = defaultOfflineTimeout
+	}
+	return &LivenessMonitor{
+		eb:         eb,
+		l:          l,
+		droneSvc:   droneSvc,
+		gatewaySvc: gatewaySvc,
+		timeout:    timeout,
+		drones:     make(map[string]time.Time),
+		gateways:   make(map[string]time.Time),
+	}
+}
+
+// TouchDrone 记录无人机的一次上报
+func (m *LivenessMonitor) TouchDrone(sn string) {
+	m.mu.Lock()
+	m.drones[sn] = time.Now()
+	m.mu.Unlock()
+}
+
+// ForgetDrone 停止监测无人机，用于无人机主动脱离拓扑，避免重复产生离线事件
+func (m *LivenessMonitor) ForgetDrone(sn string) {
+	m.mu.Lock()
+	delete(m.drones, sn)
+	m.mu.Unlock()
+}
+
+// TouchGateway 记录网关的一次上报
+func (m *LivenessMonitor) TouchGateway(sn string) {
+	m.mu.Lock()
+	m.gateways[sn] = time.Now()
+	m.mu.Unlock()
+}
+
+// Run 周期性检查设备是否离线，直到 ctx 结束
+//
+// 启动时将重启前在线的设备视为刚刚上报过，重启后不再上报的设备会在 timeout 后被标记离线，
+// 重启前已离线的设备不会重复标记，最后在线时间保持不变
+func (m *LivenessMonitor) Run(ctx context.Context) {
+	m.seed(ctx)
+
+	interval := m.timeout / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	m.l.Info("设备离线检测已启动", slog.Duration("timeout", m.timeout))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.check(ctx, now)
+		}
+	}
+}
+
+// seed 加载实时状态为在线的已登记设备
+func (m *LivenessMonitor) seed(ctx context.Context) {
+	now := time.Now()
+	drones, _, err := m.droneSvc.Repo().SelectAll(ctx, "", "", 0, 0, 0)
+	if err != nil {
+		m.l.Error("查询无人机列表失败", slog.Any("err", err))
+	}
+	gateways, err := m.gatewaySvc.Repo().SelectAll(ctx)
+	if err != nil {
+		m.l.Error("查询网关列表失败", slog.Any("err", err))
+	}
+
+	// 无人机列表已携带实时状态，网关需单独查询
+	var onlineDrones, onlineGateways []string
+	for _, d := range drones {
+		if d.Status == ro.DroneStatusOnline {
+			onlineDrones = append(onlineDrones, d.SN)
+		}
+	}
+	for _, g := range gateways {
+		if state, err := m.gatewaySvc.Repo().FetchStateBySN(ctx, g.SN); err == nil && state.Status == ro.DroneStatusOnline {
+			onlineGateways = append(onlineGateways, g.SN)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sn := range onlineDrones {
+		if _, ok := m.drones[sn]; !ok {
+			m.drones[sn] = now
+		}
+	}
+	for _, sn := range onlineGateways {
+		if _, ok := m.gateways[sn]; !ok {
+			m.gateways[sn] = now
+		}
+	}
+}
+
+// check 找出超时的设备，移出监测列表后标记为离线，设备再次上报时会重新加入
+func (m *LivenessMonitor) check(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	expiredDrones := expire(m.drones, now, m.timeout)
+	expiredGateways := expire(m.gateways, now, m.timeout)
+	m.mu.Unlock()
+
+	for sn, lastOnlineAt := range expiredDrones {
+		m.l.Info("无人机离线", slog.String("sn", sn), slog.Time("lastOnlineAt", lastOnlineAt))
+		if err := m.droneSvc.Repo().SaveOffline(ctx, sn, lastOnlineAt); err != nil {
+			m.l.Error("保存无人机离线状态失败", slog.String("sn", sn), slog.Any("err", err))
+		}
+		m.eb.Publish(event.DroneOfflineEvent, &event.DroneOfflinePayload{
+			SN:           sn,
+			LastOnlineAt: lastOnlineAt,
+			Reason:       "heartbeat timeout",
+		})
+	}
+	for sn, lastOnlineAt := range expiredGateways {
+		m.l.Info("网关离线", slog.String("sn", sn), slog.Time("lastOnlineAt", lastOnlineAt))
+		if err := m.gatewaySvc.Repo().SaveOffline(ctx, sn, lastOnlineAt); err != nil {
+			m.l.Error("保存网关离线状态失败", slog.String("sn", sn), slog.Any("err", err))
+		}
+		m.eb.Publish(event.GatewayOfflineEvent, &event.GatewayOfflinePayload{
+			GatewayEventPayload: event.GatewayEventPayload{
+				SN:        sn,
+				Timestamp: now.UnixMilli(),
+			},
+			Reason: "heartbeat timeout",
+		})
+	}
+}
+
+// expire 移除并返回超过 timeout 未上报的设备
+func expire(seen map[string]time.Time, now time.Time, timeout time.Duration) map[string]time.Time {
+	expired := make(map[string]time.Time)
+	for sn, t := range seen {
+		if now.Sub(t) > timeout {
+			expired[sn] = t
+			delete(seen, sn)
+		}
+	}
+	return expired
+}
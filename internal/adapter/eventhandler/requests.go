@@ -0,0 +1,101 @@
+package eventhandler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/dronesphere/configs"
+	"github.com/dronesphere/internal/model/dto"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// requestFunc 处理单个 requests 方法，返回值作为 requests_reply 的 data
+type requestFunc func(ctx context.Context, gatewaySN string, msg dto.RequestsMessage) (any, error)
+
+// RequestsHandler 设备请求处理器
+//
+// 监听 thing/product/{gateway_sn}/requests 主题，按 method 分发到对应的处理方法并回复 requests_reply
+type RequestsHandler struct {
+	mqtt    mqtt.Client
+	l       *slog.Logger
+	cfg     *configs.Config
+	methods map[string]requestFunc
+}
+
+// NewRequestsHandler 创建设备请求处理器
+func NewRequestsHandler(mqtt mqtt.Client, l *slog.Logger, cfg *configs.Config) *RequestsHandler {
+	h := &RequestsHandler{
+		mqtt:    mqtt,
+		l:       l,
+		cfg:     cfg,
+		methods: make(map[string]requestFunc),
+	}
+	h.Register(dto.MethodConfig, h.handleConfig)
+	return h
+}
+
+// Register 注册指定方法的处理函数，重复注册会覆盖之前的处理函数
+func (h *RequestsHandler) Register(method string, fn requestFunc) {
+	h.methods[method] = fn
+}
+
+// handleRequests 解析设备请求并分发，处理失败或方法不支持时回复非 0 的 result
+func (h *RequestsHandler) handleRequests(gatewaySN string, m mqtt.Message) {
+	var msg dto.RequestsMessage
+	if err := sonic.Unmarshal(m.Payload(), &msg); err != nil {
+		h.l.Error("解析设备请求消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
+		return
+	}
+	h.l.Info("接收设备请求", slog.Any("gatewaySN", gatewaySN), slog.Any("method", msg.Method))
+
+	var data any
+	fn, ok := h.methods[msg.Method]
+	if !ok {
+		h.l.Warn("不支持的设备请求", slog.Any("gatewaySN", gatewaySN), slog.Any("method", msg.Method))
+		data = dto.MessageResultData{Result: 1}
+	} else {
+		out, err := fn(context.Background(), gatewaySN, msg)
+		if err != nil {
+			h.l.Error("处理设备请求失败", slog.Any("gatewaySN", gatewaySN), slog.Any("method", msg.Method), slog.Any("error", err))
+			data = dto.MessageResultData{Result: 1}
+		} else {
+			data = out
+		}
+	}
+
+	h.reply(gatewaySN, msg.MessageCommon, data)
+}
+
+// reply 回复 requests_reply，tid/bid/method 与请求保持一致
+func (h *RequestsHandler) reply(gatewaySN string, com dto.MessageCommon, data any) {
+	com.Timestamp = time.Now().UnixMilli()
+	r, err := sonic.Marshal(dto.RequestsReply{MessageCommon: com, Data: data})
+	if err != nil {
+		h.l.Error("序列化设备请求应答失败", slog.Any("method", com.Method), slog.Any("error", err))
+		return
+	}
+	topic := fmt.Sprintf("thing/product/%s/requests_reply", gatewaySN)
+	token := h.mqtt.Publish(topic, 1, false, r)
+	if token.Wait() && token.Error() != nil {
+		h.l.Error("应答设备请求失败", slog.Any("topic", topic), slog.Any("error", token.Error()))
+	}
+}
+
+// handleConfig 处理 config 请求，返回 NTP 服务地址与应用鉴权信息
+func (h *RequestsHandler) handleConfig(ctx context.Context, gatewaySN string, msg dto.RequestsMessage) (any, error) {
+	var data dto.ConfigRequestData
+	if err := sonic.Unmarshal(msg.Data, &data); err != nil {
+		return nil, err
+	}
+	h.l.Info("处理设备配置请求", slog.Any("gatewaySN", gatewaySN), slog.Any("data", data))
+
+	return dto.ConfigReplyData{
+		NTPServerHost: h.cfg.Platform.NTPServerHost,
+		AppID:         h.cfg.Platform.App.ID,
+		AppKey:        h.cfg.Platform.App.Key,
+		AppLicense:    h.cfg.Platform.App.License,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package eventhandler
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// 路由器统一订阅的通配主题，+ 的位置为设备 SN
+const (
+	TopicOSD      = "thing/product/+/osd"
+	TopicState    = "thing/product/+/state"
+	TopicEvents   = "thing/product/+/events"
+	TopicRequests = "thing/product/+/requests"
+	TopicStatus   = "sys/product/+/status"
+)
+
+// topicFunc 处理路由到的单条消息，sn 为从主题中提取的设备 SN
+type topicFunc func(sn string, m mqtt.Message)
+
+// TopicRouter MQTT 主题路由器
+//
+// 每个通配主题只订阅一次，收到消息后从主题中提取设备 SN 并分发给注册的处理函数，
+// 新接入的设备无需单独订阅即可被处理。
+// 除拓扑主题外，未绑定设备的消息在分发前统一丢弃，拓扑主题用于发现设备并生成待审批记录
+type TopicRouter struct {
+	mqtt  mqtt.Client
+	l     *slog.Logger
+	bound func(sn string) bool // 判断设备是否已绑定
+
+	mu     sync.RWMutex
+	routes map[string][]topicFunc // 通配主题 -> 处理函数
+}
+
+// NewTopicRouter 创建主题路由器
+func NewTopicRouter(mqtt mqtt.Client, l *slog.Logger, bound func(sn string) bool) *TopicRouter {
+	return &TopicRouter{
+		mqtt:   mqtt,
+		l:      l,
+		bound:  bound,
+		routes: make(map[string][]topicFunc),
+	}
+}
+
+// Handle 为通配主题注册处理函数，同一主题可注册多个处理函数，按注册顺序调用
+func (r *TopicRouter) Handle(filter string, fn topicFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[filter] = append(r.routes[filter], fn)
+}
+
+// Subscribe 订阅所有已注册的通配主题，需在注册完处理函数后调用
+func (r *TopicRouter) Subscribe() {
+	r.mu.RLock()
+	filters := make([]string, 0, len(r.routes))
+	for filter := range r.routes {
+		filters = append(filters, filter)
+	}
+	r.mu.RUnlock()
+
+	for _, filter := range filters {
+		token := r.mqtt.Subscribe(filter, 1, r.dispatch(filter))
+		if token.Wait() && token.Error() != nil {
+			r.l.Error("主题订阅失败", slog.Any("topic", filter), slog.Any("error", token.Error()))
+			continue
+		}
+		r.l.Info("主题订阅成功", slog.Any("topic", filter))
+	}
+}
+
+// dispatch 返回指定通配主题的消息回调
+func (r *TopicRouter) dispatch(filter string) mqtt.MessageHandler {
+	return func(_ mqtt.Client, m mqtt.Message) {
+		sn, ok := snFromTopic(filter, m.Topic())
+		if !ok {
+			r.l.Error("无效的主题格式", slog.Any("filter", filter), slog.Any("topic", m.Topic()))
+			return
+		}
+		if filter != TopicStatus && !r.bound(sn) {
+			return
+		}
+
+		r.mu.RLock()
+		handlers := r.routes[filter]
+		r.mu.RUnlock()
+		for _, fn := range handlers {
+			fn(sn, m)
+		}
+	}
+}
+
+// snFromTopic 按通配主题中 + 的位置从实际主题中提取设备 SN
+func snFromTopic(filter, topic string) (string, bool) {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	if len(fs) != len(ts) {
+		return "", false
+	}
+	sn := ""
+	for i := range fs {
+		switch {
+		case fs[i] == "+":
+			sn = ts[i]
+		case fs[i] != ts[i]:
+			return "", false
+		}
+	}
+	return sn, sn != ""
+}
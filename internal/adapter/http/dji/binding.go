@@ -0,0 +1,114 @@
+package dji
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+const deviceSNParamKey = "device_sn"
+
+type BindingRouter struct {
+	svc service.DeviceBindingSvc
+	l   *slog.Logger
+}
+
+func newBindingRouter(handler fiber.Router, svc service.DeviceBindingSvc, l *slog.Logger) {
+	r := &BindingRouter{
+		svc: svc,
+		l:   l,
+	}
+	h := handler.Group("/manage/api/v1/devices")
+	{
+		h.Post("/:"+deviceSNParamKey+"/binding", r.bind)
+		h.Delete("/:"+deviceSNParamKey+"/unbinding", r.unbind)
+		h.Get("/:"+workspaceIDParamKey+"/devices/bound", r.getBoundDevices)
+		h.Get("/:"+workspaceIDParamKey+"/devices/unbound", r.getUnboundDevices)
+	}
+}
+
+// bind 绑定设备到工作空间
+//
+//	@Router			/manage/api/v1/devices/{device_sn}/binding [post]
+//	@Summary		绑定设备
+//	@Description	Pilot 将遥控器与无人机绑定到工作空间，未经管理员审批的设备返回待审批
+//	@Tags			dji
+//	@Accept			json
+//	@Produce		json
+func (r *BindingRouter) bind(c *fiber.Ctx) error {
+	var params dto.DeviceBindingParams
+	if err := c.BodyParser(&params); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	params.DeviceSN = c.Params(deviceSNParamKey)
+	if err := r.svc.Bind(c.Context(), params); err != nil {
+		if errors.Is(err, service.ErrBindingPending) {
+			return c.JSON(NewResponse(InvalidParams.Code, err.Error(), nil))
+		}
+		r.l.Error("Failed to bind device", slog.Any("sn", params.DeviceSN), slog.Any("err", err))
+		return c.JSON(NewResponse(InternalError.Code, err.Error(), nil))
+	}
+	return c.JSON(Success(nil))
+}
+
+// unbind 解绑设备
+//
+//	@Router			/manage/api/v1/devices/{device_sn}/unbinding [delete]
+//	@Summary		解绑设备
+//	@Tags			dji
+//	@Produce		json
+func (r *BindingRouter) unbind(c *fiber.Ctx) error {
+	sn := c.Params(deviceSNParamKey)
+	if err := r.svc.Unbind(c.Context(), sn); err != nil {
+		r.l.Error("Failed to unbind device", slog.Any("sn", sn), slog.Any("err", err))
+		return c.JSON(Fail(NotFound))
+	}
+	return c.JSON(Success(nil))
+}
+
+// getBoundDevices 获取工作空间已绑定的设备
+//
+//	@Router			/manage/api/v1/devices/{workspace_id}/devices/bound [get]
+//	@Summary		获取已绑定设备列表
+//	@Tags			dji
+//	@Produce		json
+func (r *BindingRouter) getBoundDevices(c *fiber.Ctx) error {
+	return r.listDevices(c, true)
+}
+
+// getUnboundDevices 获取待审批或已解绑的设备
+//
+//	@Router			/manage/api/v1/devices/{workspace_id}/devices/unbound [get]
+//	@Summary		获取未绑定设备列表
+//	@Tags			dji
+//	@Produce		json
+func (r *BindingRouter) getUnboundDevices(c *fiber.Ctx) error {
+	return r.listDevices(c, false)
+}
+
+func (r *BindingRouter) listDevices(c *fiber.Ctx, bound bool) error {
+	devices, err := r.svc.ListDevices(c.Context(), bound)
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	type Pagination struct {
+		Page     int `json:"page"`
+		PageSize int `json:"page_size"`
+		Total    int `json:"total"`
+	}
+	type Result struct {
+		List       []dto.BoundDevice `json:"list"`
+		Pagination Pagination        `json:"pagination"`
+	}
+	return c.JSON(Success(&Result{
+		List: devices,
+		Pagination: Pagination{
+			Page:     1,
+			PageSize: len(devices),
+			Total:    len(devices),
+		},
+	}))
+}
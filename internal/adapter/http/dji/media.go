@@ -0,0 +1,84 @@
+package dji
+
+import (
+	"log/slog"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MediaRouter struct {
+	svc service.MediaSvc
+	l   *slog.Logger
+}
+
+func newMediaRouter(handler fiber.Router, svc service.MediaSvc, l *slog.Logger) {
+	r := &MediaRouter{
+		svc: svc,
+		l:   l,
+	}
+	handler.Post("/storage/api/v1/workspaces/:"+workspaceIDParamKey+"/sts", r.getSTS)
+	h := handler.Group("/media/api/v1/workspaces/:" + workspaceIDParamKey)
+	{
+		h.Post("/files/tiny-fingerprints", r.getExistingFingerprints)
+		h.Post("/upload-callback", r.uploadCallback)
+	}
+}
+
+// getSTS 获取上传临时凭证
+//
+//	@Router			/storage/api/v1/workspaces/{workspace_id}/sts [post]
+//	@Summary		获取上传临时凭证
+//	@Description	Pilot 上传媒体文件前获取对象存储的临时凭证，凭证只允许写入媒体桶
+//	@Tags			dji
+//	@Produce		json
+func (r *MediaRouter) getSTS(c *fiber.Ctx) error {
+	sts, err := r.svc.FetchSTS(c.Context(), c.Params(workspaceIDParamKey))
+	if err != nil {
+		r.l.Error("Failed to issue STS credentials", slog.Any("err", err))
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(sts))
+}
+
+// getExistingFingerprints 查询已存在的文件
+//
+//	@Router			/media/api/v1/workspaces/{workspace_id}/files/tiny-fingerprints [post]
+//	@Summary		查询已存在的文件
+//	@Description	Pilot 上传前提交文件的 tiny_fingerprint，返回服务端已存在的部分，Pilot 会跳过这些文件
+//	@Tags			dji
+//	@Accept			json
+//	@Produce		json
+func (r *MediaRouter) getExistingFingerprints(c *fiber.Ctx) error {
+	var params dto.TinyFingerprintsParams
+	if err := c.BodyParser(&params); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	existing, err := r.svc.FilterExistingTinyFingerprints(c.Context(), params.TinyFingerprints)
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(dto.TinyFingerprintsParams{TinyFingerprints: existing}))
+}
+
+// uploadCallback 媒体文件上传结果上报
+//
+//	@Router			/media/api/v1/workspaces/{workspace_id}/upload-callback [post]
+//	@Summary		媒体文件上传结果上报
+//	@Description	Pilot 上传媒体文件到对象存储后上报文件信息，返回文件的对象键
+//	@Tags			dji
+//	@Accept			json
+//	@Produce		json
+func (r *MediaRouter) uploadCallback(c *fiber.Ctx) error {
+	var file dto.MediaFile
+	if err := c.BodyParser(&file); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	media, err := r.svc.HandleUploadCallback(c.Context(), "", file)
+	if err != nil {
+		r.l.Error("Failed to save media", slog.Any("key", file.ObjectKey), slog.Any("err", err))
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(media.ObjectKey))
+}
@@ -22,7 +22,7 @@ import (
 //	@license.name	Apache 2.0
 //	@host			example
 //	@BasePath		/
-func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, drone service.DroneSvc, wayline service.WaylineSvc) {
+func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, drone service.DroneSvc, wayline service.WaylineSvc, media service.MediaSvc, binding service.DeviceBindingSvc) {
 	sfCfg := slogfiber.Config{
 		WithTraceID: true,
 	}
@@ -57,5 +57,7 @@ func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, drone service.Dr
 		})
 		newTSARouter(api, drone, eb, l)
 		NewWaylineRouter(api, wayline, eb, l)
+		newMediaRouter(api, media, l)
+		newBindingRouter(api, binding, l)
 	}
 }
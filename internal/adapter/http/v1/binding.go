@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BindingRouter struct {
+	svc service.DeviceBindingSvc
+	l   *slog.Logger
+}
+
+func newBindingRouter(handler fiber.Router, svc service.DeviceBindingSvc, l *slog.Logger) {
+	r := &BindingRouter{
+		svc: svc,
+		l:   l,
+	}
+
+	h := handler.Group("/device/bindings")
+	{
+		h.Get("/", r.list)                // 设备绑定记录，可按 status 过滤
+		h.Post("/:sn/approve", r.approve) // 审批通过，设备重新上线后开始接收数据
+		h.Post("/:sn/reject", r.reject)   // 拒绝，设备数据持续被忽略
+		h.Delete("/:sn", r.unbind)        // 解绑
+	}
+}
+
+func (r *BindingRouter) list(c *fiber.Ctx) error {
+	var statuses []int
+	if raw := c.Query("status"); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil {
+			return c.JSON(Fail(InvalidParams))
+		}
+		statuses = append(statuses, status)
+	}
+	bindings, err := r.svc.List(context.Background(), statuses...)
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(bindings))
+}
+
+func (r *BindingRouter) approve(c *fiber.Ctx) error {
+	if err := r.svc.Approve(context.Background(), c.Params("sn")); err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(nil))
+}
+
+func (r *BindingRouter) reject(c *fiber.Ctx) error {
+	if err := r.svc.Reject(context.Background(), c.Params("sn")); err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(nil))
+}
+
+func (r *BindingRouter) unbind(c *fiber.Ctx) error {
+	if err := r.svc.Unbind(context.Background(), c.Params("sn")); err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(nil))
+}
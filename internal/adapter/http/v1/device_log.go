@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type DeviceLogRouter struct {
+	svc service.DeviceLogSvc
+	l   *slog.Logger
+}
+
+func newDeviceLogRouter(handler fiber.Router, svc service.DeviceLogSvc, l *slog.Logger) {
+	r := &DeviceLogRouter{
+		svc: svc,
+		l:   l,
+	}
+
+	h := handler.Group("/drone/:sn/logs")
+	{
+		h.Get("/", r.listUploaded)       // 已上传的日志及下载链接
+		h.Get("/files", r.listFiles)     // 设备上的日志列表
+		h.Post("/upload", r.startUpload) // 让设备上传日志
+	}
+}
+
+// listFiles 获取设备上的日志列表，module 为逗号分隔的模块，默认为飞行器和遥控器
+func (r *DeviceLogRouter) listFiles(c *fiber.Ctx) error {
+	var modules []string
+	if m := c.Query("module"); m != "" {
+		modules = strings.Split(m, ",")
+	}
+	files, err := r.svc.ListDeviceFiles(context.Background(), c.Params("sn"), modules)
+	if err != nil {
+		return c.JSON(FailWithMsg("获取设备日志列表失败: " + err.Error()))
+	}
+	return c.JSON(Success(files))
+}
+
+func (r *DeviceLogRouter) startUpload(c *fiber.Ctx) error {
+	var params dto.DeviceLogUploadParams
+	if err := c.BodyParser(&params); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	logs, err := r.svc.StartUpload(context.Background(), c.Params("sn"), params)
+	if err != nil {
+		return c.JSON(FailWithMsg("上传设备日志失败: " + err.Error()))
+	}
+	return c.JSON(Success(logs))
+}
+
+func (r *DeviceLogRouter) listUploaded(c *fiber.Ctx) error {
+	items, err := r.svc.ListUploaded(context.Background(), c.Params("sn"))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(items))
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"sort"
@@ -13,6 +14,7 @@ import (
 	"github.com/asaskevich/EventBus"
 	"github.com/bytedance/sonic"
 	api "github.com/dronesphere/api/http/v1"
+	"github.com/dronesphere/internal/model/dto"
 	"github.com/dronesphere/internal/model/entity"
 	"github.com/dronesphere/internal/model/ro"
 	"github.com/dronesphere/internal/repo"
@@ -30,16 +32,20 @@ type DroneModelOption struct {
 }
 
 type DroneRouter struct {
-	svc service.DroneSvc
-	eb  EventBus.Bus
-	l   *slog.Logger
+	svc    service.DroneSvc
+	hmsSvc service.HMSSvc
+	drcSvc service.DRCSvc
+	eb     EventBus.Bus
+	l      *slog.Logger
 }
 
-func newDroneRouter(handler fiber.Router, svc service.DroneSvc, eb EventBus.Bus, l *slog.Logger) {
+func newDroneRouter(handler fiber.Router, svc service.DroneSvc, hmsSvc service.HMSSvc, drcSvc service.DRCSvc, eb EventBus.Bus, l *slog.Logger) {
 	r := &DroneRouter{
-		svc: svc,
-		eb:  eb,
-		l:   l,
+		svc:    svc,
+		hmsSvc: hmsSvc,
+		drcSvc: drcSvc,
+		eb:     eb,
+		l:      l,
 	}
 	h := handler.Group("/drone")
 	{
@@ -52,6 +58,9 @@ func newDroneRouter(handler fiber.Router, svc service.DroneSvc, eb EventBus.Bus,
 		h.Delete("/:sn", r.delete)             // 添加删除无人机的路由
 		h.Post("/:sn/live/start", r.startLive) // 添加启动直播的路由
 		h.Post("/:sn/live/stop", r.stopLive)   // 添加停止直播的路由
+		h.Get("/:sn/hms", r.listHMS)           // 获取设备健康告警
+		h.Put("/:sn/property", r.setProperty)  // 远程设置设备属性
+		h.Post("/:sn/hms/ack", r.ackHMS)       // 确认设备健康告警
 	}
 	h.Use("/:sn/control", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
@@ -76,6 +85,13 @@ func (r *DroneRouter) handleDroneControl(c *websocket.Conn) {
 	r.l.Info("无人机控制连接已建立", slog.String("sn", sn))
 	ctx := context.Background()
 	r.svc.CheckControlConnection(ctx, c, sn)
+	defer service.ReleaseConn(c)
+	// 进入 DRC 失败时仍保留连接，前端之间的控制消息同步不受影响
+	if err := r.drcSvc.Join(ctx, sn, c); err != nil {
+		r.replyDRCError(c, "drc_mode_enter", err)
+	}
+	defer r.drcSvc.Leave(ctx, sn, c)
+
 	// 处理 WebSocket 消息循环
 	for {
 		// 读取客户端消息
@@ -84,12 +100,35 @@ func (r *DroneRouter) handleDroneControl(c *websocket.Conn) {
 			r.l.Info("无人机控制连接已关闭", slog.String("sn", sn))
 			break
 		}
+
+		var m dto.WSbaseModel
+		if err := json.Unmarshal(msg, &m); err == nil {
+			// 杆量等高频消息只下发到飞行器，不记录日志也不广播
+			direct, err := r.drcSvc.Send(ctx, sn, m)
+			if err != nil {
+				r.replyDRCError(c, m.Method, err)
+			}
+			if direct {
+				continue
+			}
+		}
 		r.l.Info("收到无人机控制消息", slog.String("sn", sn), slog.String("message", string(msg)))
 
 		r.svc.HandleControlSession(ctx, c, sn, mt, string(msg))
 	}
 }
 
+// replyDRCError 向控制连接回复 DRC 指令的错误
+func (r *DroneRouter) replyDRCError(c *websocket.Conn, method string, err error) {
+	r.l.Error("DRC 指令失败", slog.String("method", method), slog.Any("err", err))
+	payload, _ := json.Marshal(dto.WSbaseModel{
+		Timestamp: time.Now().Unix(),
+		Method:    dto.WSMethodDRCError,
+		Data:      map[string]string{"method": method, "message": err.Error()},
+	})
+	_ = service.WriteConn(c, websocket.TextMessage, payload)
+}
+
 type droneItemResult struct {
 	ID                 uint   `json:"id"`                   // ID
 	Callsign           string `json:"callsign"`             // 呼号
@@ -101,6 +140,7 @@ type droneItemResult struct {
 	IsThermalAvailable bool   `json:"is_thermal_available"` // 是否支持热成像
 	CreatedAt          string `json:"created_at"`           // 创建时间
 	LastOnlineAt       string `json:"last_online_at"`       // 最后在线时间
+	HMSActiveCount     int64  `json:"hms_active_count"`     // 未确认且未恢复的健康告警数量
 }
 
 func (r *DroneRouter) list(c *fiber.Ctx) error {
@@ -148,6 +188,9 @@ func (r *DroneRouter) list(c *fiber.Ctx) error {
 		e.ProductModel = d.GetModelName()
 		e.CreatedAt = d.CreatedAt.Format("2006-01-02 15:04:05")
 		e.LastOnlineAt = d.UpdatedAt.Format("2006-01-02 15:04:05")
+		if !d.LastOnlineAt.IsZero() {
+			e.LastOnlineAt = d.LastOnlineAt.Format("2006-01-02 15:04:05")
+		}
 		for _, g := range d.DroneModel.Gimbals {
 			if g.IsThermalAvailable {
 				e.IsThermalAvailable = true
@@ -160,6 +203,19 @@ func (r *DroneRouter) list(c *fiber.Ctx) error {
 		return res[i].ID < res[j].ID
 	})
 
+	// 填充未确认且未恢复的健康告警数量
+	sns := make([]string, 0, len(res))
+	for _, e := range res {
+		sns = append(sns, e.SN)
+	}
+	counts, err := r.hmsSvc.CountActive(ctx, sns)
+	if err != nil {
+		r.l.Error("统计健康告警失败", slog.Any("err", err))
+	}
+	for i := range res {
+		res[i].HMSActiveCount = counts[res[i].SN]
+	}
+
 	return c.JSON(Success(fiber.Map{
 		"total": total,
 		"items": res,
@@ -480,3 +536,48 @@ func (r *DroneRouter) stopLive(c *fiber.Ctx) error {
 	r.l.Info("成功停止无人机直播", slog.String("sn", sn))
 	return c.JSON(Success(nil))
 }
+
+// listHMS 获取设备健康告警，active=true 时只返回未确认且未恢复的告警
+func (r *DroneRouter) listHMS(c *fiber.Ctx) error {
+	sn := c.Params("sn")
+	activeOnly := c.QueryBool("active", false)
+	alerts, err := r.hmsSvc.ListAlerts(context.Background(), sn, activeOnly)
+	if err != nil {
+		return c.JSON(Fail(ErrorBody{Code: 500, Msg: err.Error()}))
+	}
+	return c.JSON(Success(alerts))
+}
+
+// ackHMS 确认设备健康告警，alert_ids 为空时确认全部未确认告警
+func (r *DroneRouter) ackHMS(c *fiber.Ctx) error {
+	sn := c.Params("sn")
+	var req struct {
+		AlertIDs []uint `json:"alert_ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(Fail(InvalidParams))
+		}
+	}
+	n, err := r.hmsSvc.Acknowledge(context.Background(), sn, req.AlertIDs)
+	if err != nil {
+		return c.JSON(Fail(ErrorBody{Code: 500, Msg: err.Error()}))
+	}
+	return c.JSON(Success(fiber.Map{"acknowledged": n}))
+}
+
+// setProperty 远程设置无人机属性，请求体为属性名到属性值的映射
+// 例如 {"height_limit": 120, "obstacle_avoidance": {"horizon": 1}}
+func (r *DroneRouter) setProperty(c *fiber.Ctx) error {
+	sn := c.Params("sn")
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &props); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+
+	results, err := r.svc.SetPropertiesBySN(context.Background(), sn, props)
+	if err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(results))
+}
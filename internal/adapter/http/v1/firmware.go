@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type FirmwareRouter struct {
+	svc service.FirmwareSvc
+	l   *slog.Logger
+}
+
+func newFirmwareRouter(handler fiber.Router, svc service.FirmwareSvc, l *slog.Logger) {
+	r := &FirmwareRouter{
+		svc: svc,
+		l:   l,
+	}
+
+	h := handler.Group("/firmware")
+	{
+		h.Get("/", r.list)
+		h.Post("/", r.upload)
+		h.Delete("/:id", r.delete)
+		h.Post("/:id/upgrade", r.upgrade)
+		h.Get("/upgrade/jobs", r.listUpgradeJobs)
+		h.Get("/upgrade/jobs/:id", r.getUpgradeJob)
+	}
+}
+
+// list 获取固件列表，可按 model_key 过滤
+func (r *FirmwareRouter) list(c *fiber.Ctx) error {
+	firmwares, err := r.svc.List(context.Background(), c.Query("model_key"))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(firmwares))
+}
+
+// upload 上传固件包，表单字段：model_key、version、release_note、file
+func (r *FirmwareRouter) upload(c *fiber.Ctx) error {
+	modelKey := c.FormValue("model_key")
+	version := c.FormValue("version")
+	if modelKey == "" || version == "" {
+		return c.JSON(FailWithMsg("型号和版本不能为空"))
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(FailWithMsg("缺少固件文件"))
+	}
+	file, err := header.Open()
+	if err != nil {
+		r.l.Error("读取固件文件失败", slog.Any("err", err))
+		return c.JSON(Fail(InternalError))
+	}
+	defer file.Close()
+
+	firmware := &po.Firmware{
+		ModelKey:    modelKey,
+		Version:     version,
+		FileName:    header.Filename,
+		ReleaseNote: c.FormValue("release_note"),
+	}
+	if err := r.svc.Upload(context.Background(), firmware, file, header.Size); err != nil {
+		return c.JSON(FailWithMsg("上传固件失败: " + err.Error()))
+	}
+	return c.JSON(Success(firmware))
+}
+
+func (r *FirmwareRouter) delete(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	if err := r.svc.Delete(context.Background(), uint(id)); err != nil {
+		return c.JSON(FailWithMsg("删除固件失败: " + err.Error()))
+	}
+	return c.JSON(Success(nil))
+}
+
+// upgrade 向一台或多台设备下发固件升级
+func (r *FirmwareRouter) upgrade(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	var params dto.FirmwareUpgradeParams
+	if err := c.BodyParser(&params); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	job, err := r.svc.CreateUpgrade(context.Background(), uint(id), params)
+	if err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(job))
+}
+
+func (r *FirmwareRouter) listUpgradeJobs(c *fiber.Ctx) error {
+	jobs, err := r.svc.ListUpgradeJobs(context.Background())
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(jobs))
+}
+
+// getUpgradeJob 获取升级任务详情，包含每台设备的升级状态
+func (r *FirmwareRouter) getUpgradeJob(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	job, err := r.svc.FetchUpgradeJob(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(FailWithMsg("升级任务不存在"))
+	}
+	return c.JSON(Success(job))
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/asaskevich/EventBus"
+	"github.com/dronesphere/internal/model/dto"
 	"github.com/dronesphere/internal/service"
 	"github.com/gofiber/fiber/v2"
 	"github.com/jinzhu/copier"
@@ -30,6 +31,7 @@ func NewGatewayRouter(handler fiber.Router, svc service.GatewaySvc, eb EventBus.
 		h.Put("/:sn", r.update)                  // 更新网关信息
 		h.Get("/sn/:sn", r.getBySN)              // 获取单个网关详情
 		h.Get("/sn/:sn/drones", r.getDronesBySN) // 获取网关关联的无人机列表
+		h.Get("/sn/:sn/state", r.getStateBySN)   // 获取网关实时状态
 	}
 }
 
@@ -62,6 +64,34 @@ type gatewayItemResult struct {
 	ProductModel string `json:"product_model"`  // 产品型号
 	CreatedAt    string `json:"created_at"`     // 创建时间
 	LastOnlineAt string `json:"last_online_at"` // 最后在线时间
+
+	LinkQuality *gatewayLinkQuality `json:"link_quality,omitempty"` // 遥控器链路质量，无实时数据时为空
+}
+
+// gatewayLinkQuality 遥控器图传链路质量
+type gatewayLinkQuality struct {
+	LinkWorkmode int `json:"link_workmode"` // 链路工作模式，0: SDR 模式，1: 4G 融合模式
+	SDRQuality   int `json:"sdr_quality"`   // SDR 信号质量，0-5
+	FourGQuality int `json:"4g_quality"`    // 4G 信号质量，0-5
+	Quality      int `json:"quality"`       // 综合信号质量，0-5，取工作链路中的最大值
+}
+
+// newGatewayLinkQuality 根据网关 OSD 计算链路质量
+func newGatewayLinkQuality(osd dto.GatewayOSDData) *gatewayLinkQuality {
+	if osd.WirelessLink == nil {
+		return nil
+	}
+	link := osd.WirelessLink
+	q := &gatewayLinkQuality{
+		LinkWorkmode: link.LinkWorkmode,
+		SDRQuality:   link.SDRQuality,
+		FourGQuality: link.FourGQuality,
+		Quality:      link.SDRQuality,
+	}
+	if link.LinkWorkmode == 1 && link.FourGQuality > q.Quality {
+		q.Quality = link.FourGQuality
+	}
+	return q
 }
 
 // list 获取网关列表
@@ -82,6 +112,9 @@ func (r *GatewayRouter) list(c *fiber.Ctx) error {
 		e.CreatedAt = g.CreatedAt.Format("2006-01-02 15:04:05")
 		e.LastOnlineAt = g.LastOnlineAt.Format("2006-01-02 15:04:05")
 		e.ProductModel = g.GatewayModel.Name
+		if state, err := r.svc.Repo().FetchStateBySN(ctx, g.SN); err == nil {
+			e.LinkQuality = newGatewayLinkQuality(state.GatewayOSDData)
+		}
 		res = append(res, e)
 	}
 
@@ -97,6 +130,9 @@ type gatewayDetailResult struct {
 	Status       string   `json:"status"`        // 在线状态
 	ProductModel string   `json:"product_model"` // 产品型号
 	DroneList    []string `json:"drone_list"`    // 关联的无人机列表
+
+	OSD         *dto.GatewayOSDData `json:"osd,omitempty"`          // 网关实时数据，无实时数据时为空
+	LinkQuality *gatewayLinkQuality `json:"link_quality,omitempty"` // 遥控器链路质量
 }
 
 // getBySN 根据序列号获取网关详情
@@ -124,9 +160,33 @@ func (r *GatewayRouter) getBySN(c *fiber.Ctx) error {
 		}
 	}
 
+	// 获取实时数据
+	if state, err := r.svc.Repo().FetchStateBySN(ctx, sn); err == nil {
+		res.OSD = &state.GatewayOSDData
+		res.LinkQuality = newGatewayLinkQuality(state.GatewayOSDData)
+	}
+
 	return c.JSON(Success(res))
 }
 
+// getStateBySN 获取网关实时状态
+func (r *GatewayRouter) getStateBySN(c *fiber.Ctx) error {
+	sn := c.Params("sn")
+	ctx := context.Background()
+	state, err := r.svc.Repo().FetchStateBySN(ctx, sn)
+	if err != nil {
+		return c.JSON(Fail(ErrorBody{Code: 500, Msg: err.Error()}))
+	}
+
+	return c.JSON(Success(struct {
+		State       any                 `json:"state"`
+		LinkQuality *gatewayLinkQuality `json:"link_quality,omitempty"`
+	}{
+		State:       state,
+		LinkQuality: newGatewayLinkQuality(state.GatewayOSDData),
+	}))
+}
+
 // droneListResult 无人机列表响应结构
 type droneListResult struct {
 	SN        string `json:"sn"`         // 序列号
@@ -4,8 +4,10 @@ import (
 	"context"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dronesphere/internal/model/dto"
 	"github.com/dronesphere/internal/model/entity"
 	"github.com/dronesphere/internal/model/po"
 	"github.com/dronesphere/internal/model/vo"
@@ -15,18 +17,20 @@ import (
 )
 
 type JobRouter struct {
-	svc      service.JobSvc
-	areaSvc  service.AreaSvc
-	modelSvc service.ModelSvc
-	l        *slog.Logger
+	svc       service.JobSvc
+	areaSvc   service.AreaSvc
+	modelSvc  service.ModelSvc
+	scheduler service.JobSchedulerSvc
+	l         *slog.Logger
 }
 
-func NewJobRouter(handler fiber.Router, svc service.JobSvc, areaSvc service.AreaSvc, modelSvc service.ModelSvc, l *slog.Logger) {
+func NewJobRouter(handler fiber.Router, svc service.JobSvc, areaSvc service.AreaSvc, modelSvc service.ModelSvc, scheduler service.JobSchedulerSvc, l *slog.Logger) {
 	r := &JobRouter{
-		svc:      svc,
-		areaSvc:  areaSvc,
-		modelSvc: modelSvc,
-		l:        l,
+		svc:       svc,
+		areaSvc:   areaSvc,
+		modelSvc:  modelSvc,
+		scheduler: scheduler,
+		l:         l,
 	}
 	h := handler.Group("/job")
 	{
@@ -37,6 +41,15 @@ func NewJobRouter(handler fiber.Router, svc service.JobSvc, areaSvc service.Area
 		h.Post("/", r.create)
 		h.Put("/", r.update)
 		h.Delete("/:id", r.delete)
+		h.Get("/:id/readiness", r.getReadiness)
+		h.Post("/:id/dispatch", r.dispatch)
+		h.Post("/:id/cancel", r.cancel)
+		h.Get("/:id/status/history", r.getStatusHistories)
+		h.Get("/:id/schedule", r.getSchedule)
+		h.Put("/:id/schedule", r.updateSchedule) // 设置周期执行与错过执行的补偿策略
+		h.Get("/:id/executions", r.getExecutions)
+		h.Get("/executions/:eid/progress", r.getExecutionProgress)
+		h.Get("/executions/:eid/commands", r.getExecutionCommands)
 	}
 }
 
@@ -47,13 +60,25 @@ func (r *JobRouter) getJobs(c *fiber.Ctx) error {
 		AreaName          string `query:"area_name"`
 		ScheduleTimeStart string `query:"schedule_time_start"`
 		ScheduleTimeEnd   string `query:"schedule_time_end"`
+		Status            string `query:"status"` // 状态筛选，多个状态用逗号分隔，如 1,3
 	}
 	if err := c.QueryParser(&params); err != nil {
 		return c.JSON(Fail(InvalidParams))
 	}
 	r.l.Debug("getJobs", "params", params)
+	var statuses []int
+	for _, s := range strings.Split(params.Status, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		status, err := strconv.Atoi(s)
+		if _, ok := po.JobStatusNames[status]; err != nil || !ok {
+			return c.JSON(Fail(InvalidParams))
+		}
+		statuses = append(statuses, status)
+	}
 	// 将解析到的时间参数传递给仓储层
-	jobs, err := r.svc.FetchAll(ctx, params.JobName, params.AreaName, params.ScheduleTimeStart, params.ScheduleTimeEnd)
+	jobs, err := r.svc.FetchAll(ctx, params.JobName, params.AreaName, params.ScheduleTimeStart, params.ScheduleTimeEnd, statuses)
 	if err != nil {
 		return c.JSON(Fail(InternalError))
 	}
@@ -64,6 +89,7 @@ func (r *JobRouter) getJobs(c *fiber.Ctx) error {
 		AreaName     string   `json:"area_name"`
 		ScheduleTime string   `json:"schedule_time"` // 任务计划执行时间
 		Drones       []string `json:"drones"`
+		Status       int      `json:"status"`
 	}
 
 	for _, job := range jobs {
@@ -74,12 +100,14 @@ func (r *JobRouter) getJobs(c *fiber.Ctx) error {
 			AreaName     string   `json:"area_name"`
 			ScheduleTime string   `json:"schedule_time"` // 任务计划执行时间
 			Drones       []string `json:"drones"`
+			Status       int      `json:"status"`
 		}
 		item.ID = job.ID
 		item.Name = job.Name
 		item.Description = job.Description
 		item.AreaName = job.Area.Name
 		item.ScheduleTime = job.ScheduleTime.Format("2006-01-02 15:04:05")
+		item.Status = job.Status
 		for _, drone := range job.Drones {
 			item.Drones = append(item.Drones, drone.Key)
 		}
@@ -229,6 +257,9 @@ func (r *JobRouter) create(c *fiber.Ctx) error {
 	if err != nil {
 		return c.JSON(Fail(InternalError))
 	}
+	if err := r.scheduler.Sync(ctx, id); err != nil {
+		r.l.Error("加载任务调度失败", slog.Any("jobID", id), slog.Any("err", err))
+	}
 	return c.JSON(Success(id))
 }
 
@@ -263,6 +294,9 @@ func (r *JobRouter) update(c *fiber.Ctx) error {
 	if err != nil {
 		return c.JSON(Fail(InternalError))
 	}
+	if err := r.scheduler.Sync(ctx, req.ID); err != nil {
+		r.l.Error("加载任务调度失败", slog.Any("jobID", req.ID), slog.Any("err", err))
+	}
 
 	return c.JSON(Success(job))
 }
@@ -276,5 +310,140 @@ func (r *JobRouter) delete(c *fiber.Ctx) error {
 	if err := r.svc.Repo().DeleteByID(ctx, uint(id)); err != nil {
 		return c.JSON(Fail(InternalError))
 	}
+	if err := r.scheduler.Sync(ctx, uint(id)); err != nil {
+		r.l.Error("移除任务调度失败", slog.Any("jobID", id), slog.Any("err", err))
+	}
 	return c.JSON(Success(nil))
 }
+
+// getSchedule 获取任务的调度状态，包括下一次计划执行时间
+func (r *JobRouter) getSchedule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	result, err := r.scheduler.FetchSchedule(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(FailWithMsg("任务不存在"))
+	}
+	return c.JSON(Success(result))
+}
+
+// updateSchedule 设置任务的 cron 表达式与补偿策略，cron 表达式为空时任务只在计划时间执行一次
+func (r *JobRouter) updateSchedule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	var params dto.JobScheduleParams
+	if err := c.BodyParser(&params); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	result, err := r.scheduler.UpdateSchedule(context.Background(), uint(id), params)
+	if err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(result))
+}
+
+// getReadiness 获取任务中每架无人机的起飞前检查报告
+func (r *JobRouter) getReadiness(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	report, err := r.svc.CheckReadiness(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(report))
+}
+
+// dispatch 下发任务到各无人机并开始执行
+func (r *JobRouter) dispatch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	var params dto.JobDispatchParams
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&params); err != nil {
+			return c.JSON(Fail(InvalidParams))
+		}
+	}
+	r.l.Info("dispatch job", slog.Any("id", id), slog.Any("params", params))
+
+	executions, err := r.svc.DispatchJob(context.Background(), uint(id), params)
+	if err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	return c.JSON(Success(executions))
+}
+
+// cancel 取消任务，正在执行的航线会被终止，取消后不再自动调度
+func (r *JobRouter) cancel(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	ctx := context.Background()
+	if err := r.svc.CancelJob(ctx, uint(id)); err != nil {
+		return c.JSON(FailWithMsg(err.Error()))
+	}
+	if err := r.scheduler.Sync(ctx, uint(id)); err != nil {
+		r.l.Error("更新任务调度失败", slog.Any("id", id), slog.Any("error", err))
+	}
+	return c.JSON(Success(nil))
+}
+
+// getStatusHistories 获取任务的状态变化记录
+func (r *JobRouter) getStatusHistories(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	histories, err := r.svc.FetchStatusHistories(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(histories))
+}
+
+// getExecutions 获取任务的下发执行记录
+func (r *JobRouter) getExecutions(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	executions, err := r.svc.FetchExecutions(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(executions))
+}
+
+// getExecutionProgress 获取单次执行的进度历史
+func (r *JobRouter) getExecutionProgress(c *fiber.Ctx) error {
+	eid, err := strconv.Atoi(c.Params("eid"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	progresses, err := r.svc.FetchExecutionProgresses(context.Background(), uint(eid))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(progresses))
+}
+
+// getExecutionCommands 获取单次执行的控制指令历史
+func (r *JobRouter) getExecutionCommands(c *fiber.Ctx) error {
+	eid, err := strconv.Atoi(c.Params("eid"))
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	commands, err := r.svc.FetchExecutionCommands(context.Background(), uint(eid))
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(commands))
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MediaRouter struct {
+	svc service.MediaSvc
+	l   *slog.Logger
+}
+
+func newMediaRouter(handler fiber.Router, svc service.MediaSvc, l *slog.Logger) {
+	r := &MediaRouter{
+		svc: svc,
+		l:   l,
+	}
+
+	h := handler.Group("/media")
+	{
+		h.Get("/", r.list)
+		h.Get("/:id", r.getByID)
+		h.Post("/flight/:flight_id/prioritize", r.prioritize) // 让机场优先上传指定航线任务的媒体文件
+	}
+}
+
+// list 查询媒体文件，可按 job_id、wayline_id、drone_sn、flight_id 过滤
+func (r *MediaRouter) list(c *fiber.Ctx) error {
+	var query dto.MediaQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	items, total, err := r.svc.List(context.Background(), query)
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(map[string]interface{}{
+		"total": total,
+		"items": items,
+	}))
+}
+
+func (r *MediaRouter) getByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	item, err := r.svc.FetchByID(context.Background(), uint(id))
+	if err != nil {
+		return c.JSON(FailWithMsg("媒体文件不存在"))
+	}
+	return c.JSON(Success(item))
+}
+
+func (r *MediaRouter) prioritize(c *fiber.Ctx) error {
+	if err := r.svc.Prioritize(context.Background(), c.Params("flight_id")); err != nil {
+		return c.JSON(FailWithMsg("设置优先上传失败: " + err.Error()))
+	}
+	return c.JSON(Success(nil))
+}
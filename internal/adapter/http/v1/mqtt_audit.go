@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MQTTAuditRouter struct {
+	svc service.MQTTAuditSvc
+	l   *slog.Logger
+}
+
+func newMQTTAuditRouter(handler fiber.Router, svc service.MQTTAuditSvc, l *slog.Logger) {
+	r := &MQTTAuditRouter{
+		svc: svc,
+		l:   l,
+	}
+
+	h := handler.Group("/mqtt/audits")
+	{
+		h.Get("/", r.list)
+	}
+}
+
+// list 查询 MQTT 收发记录，可按 sn、method、direction、tid 与时间范围过滤
+func (r *MQTTAuditRouter) list(c *fiber.Ctx) error {
+	var query dto.MQTTAuditQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.JSON(Fail(InvalidParams))
+	}
+	if query.Direction != "" && query.Direction != "in" && query.Direction != "out" {
+		return c.JSON(Fail(InvalidParams))
+	}
+	items, total, err := r.svc.Query(context.Background(), query)
+	if err != nil {
+		return c.JSON(Fail(InternalError))
+	}
+	return c.JSON(Success(map[string]interface{}{
+		"total": total,
+		"items": items,
+	}))
+}
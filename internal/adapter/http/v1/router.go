@@ -9,6 +9,7 @@ import (
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/asaskevich/EventBus"
 	"github.com/dronesphere/configs"
+	"github.com/dronesphere/internal/pkg/mqttsub"
 	"github.com/dronesphere/internal/service"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -17,7 +18,7 @@ import (
 )
 
 // NewRouter 初始化路由
-func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, svc *service.Container, cfg *configs.Config) {
+func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, svc *service.Container, cfg *configs.Config, mq *mqttsub.Client) {
 	sfCfg := slogfiber.Config{
 		WithTraceID: true,
 		WithSpanID:  true,
@@ -35,9 +36,14 @@ func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, svc *service.Con
 	prometheus.RegisterAt(app, "/metrics")
 	app.Use(prometheus.Middleware)
 
-	// K8s probe
+	// K8s probe，MQTT 断开时返回 503
 	app.Get("/healthz", func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusOK)
+		status := mq.Status()
+		code := fiber.StatusOK
+		if !status.Connected {
+			code = fiber.StatusServiceUnavailable
+		}
+		return c.Status(code).JSON(fiber.Map{"mqtt": status})
 	})
 
 	// Routers
@@ -45,13 +51,18 @@ func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, svc *service.Con
 	{
 		newPlatformRouter(api, l, cfg)
 		newUserRouter(api, svc.User, eb, l)
-		newDroneRouter(api, svc.Drone, eb, l)
+		newDroneRouter(api, svc.Drone, svc.HMS, svc.DRC, eb, l)
 		NewSearchAreaRouter(api, svc.Area, eb, l)
-		NewJobRouter(api, svc.Job, svc.Area, svc.Model, l)
+		NewJobRouter(api, svc.Job, svc.Area, svc.Model, svc.Schedule, l)
 		NewGatewayRouter(api, svc.Gateway, eb, l)
 		NewModelsRouter(api, svc.Model, eb, l)
 		newResultRouter(api, svc.Result, l)
 		NewWaylineRouter(api, svc.Wayline, l)
+		newFirmwareRouter(api, svc.Firmware, l)
+		newDeviceLogRouter(api, svc.Log, l)
+		newMediaRouter(api, svc.Media, l)
+		newBindingRouter(api, svc.Binding, l)
+		newMQTTAuditRouter(api, svc.Audit, l)
 		api.Get("/sse", handleSSE(l))
 	}
 }
@@ -12,7 +12,6 @@ import (
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/robfig/cron/v3"
 	slogfiber "github.com/samber/slog-fiber"
 )
 
@@ -41,7 +40,6 @@ func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, user service.Use
 		return fiber.ErrUpgradeRequired
 	})
 
-	cr := cron.New(cron.WithSeconds())
 	app.Get("/", websocket.New(func(c *websocket.Conn) {
 		for {
 			mt, msg, err := c.ReadMessage()
@@ -116,5 +114,4 @@ func NewRouter(app *fiber.App, eb EventBus.Bus, l *slog.Logger, user service.Use
 			}
 		}
 	}))
-	cr.Start()
 }
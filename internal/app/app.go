@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -16,6 +15,8 @@ import (
 	v1 "github.com/dronesphere/internal/adapter/http/v1"
 	"github.com/dronesphere/internal/adapter/ws"
 	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/pkg/mqttsub"
+	"github.com/dronesphere/internal/pkg/servicecall"
 	"github.com/dronesphere/internal/service"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/gofiber/fiber/v2"
@@ -70,13 +71,11 @@ func Run(cfg *configs.Config) {
 	opts.SetDefaultPublishHandler(func(client mqtt.Client, msg mqtt.Message) {
 		logger.Info("Received message", slog.Any("topic", msg.Topic()), slog.Any("message", string(msg.Payload())))
 	})
-	opts.SetOnConnectHandler(func(client mqtt.Client) {
-		logger.Info("Connected to MQTT broker")
-	})
-	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
-		logger.Error("Connection lost", slog.Any("err", err.Error()))
-	})
-	client := mqtt.NewClient(opts)
+	// 登记所有订阅，断线重连后在 OnConnect 中重放
+	client := mqttsub.New(logger)
+	opts.SetOnConnectHandler(client.OnConnect)
+	opts.SetConnectionLostHandler(client.OnConnectionLost)
+	client.Wrap(mqtt.NewClient(opts))
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
 		panic(token.Error())
 	}
@@ -110,18 +109,59 @@ func Run(cfg *configs.Config) {
 	wlRepo := repo.NewWaylineGormRepo(db, s3Client, logger)
 	jobRepo := repo.NewJobDefaultRepo(db, s3Client, rds, logger)
 	modelRepo := repo.NewModelDefaultRepo(db, logger)
-	gatewayRepo := repo.NewGatewayRepo(db, logger)
+	gatewayRepo := repo.NewGatewayRepo(db, rds, logger)
 	resultRepo := repo.NewResultDefaultRepo(db, logger)
+	hmsRepo := repo.NewHMSDefaultRepo(db, logger)
+	firmwareRepo := repo.NewFirmwareDefaultRepo(db, s3Client, logger)
+	deviceLogRepo := repo.NewDeviceLogDefaultRepo(db, s3Client, logger)
+	mediaRepo := repo.NewMediaDefaultRepo(db, s3Client, logger)
+	bindingRepo := repo.NewDeviceBindingDefaultRepo(db, logger)
+	auditRepo := repo.NewMQTTAuditDefaultRepo(db, logger)
+	// 设备直传对象存储使用的临时凭证
+	storageRepo := repo.NewStorageDefaultRepo("http://"+endpoint, accessKeyID, secretAccessKey, logger)
+
+	// MQTT 消息审计，需在订阅与发布之前挂载旁路
+	auditSvc := service.NewMQTTAuditImpl(auditRepo, service.MQTTAuditOptions{
+		SampleRate: cfg.Audit.SampleRate,
+		Retention:  time.Duration(cfg.Audit.RetentionHours) * time.Hour,
+		MaxRows:    cfg.Audit.MaxRows,
+	}, logger)
+	if cfg.Audit.Enabled {
+		go auditSvc.Run(context.Background())
+		client.SetTap(auditSvc.Record)
+		logger.Info("MQTT 消息审计已开启", slog.Float64("sampleRate", cfg.Audit.SampleRate))
+	}
+
+	// MQTT services 调用客户端，统一处理 services_reply
+	caller := servicecall.New(client, logger, servicecall.DefaultTimeout)
+	if err := caller.Subscribe(); err != nil {
+		panic(err)
+	}
 
 	// Services
 	userSvc := service.NewUserSvc(userRepo, logger)
-	droneSvc := service.NewDroneImpl(droneRepo, modelRepo, logger, client)
 	saSvc := service.NewAreaImpl(saRepo, logger, client)
 	wlSvc := service.NewWaylineImpl(wlRepo, logger)
-	jobSvc := service.NewJobImpl(jobRepo, saRepo, droneRepo, modelRepo, wlRepo, wlSvc, logger)
+	jobSvc := service.NewJobImpl(jobRepo, saRepo, droneRepo, modelRepo, wlRepo, wlSvc, hmsRepo, logger, caller)
+	droneSvc := service.NewDroneImpl(droneRepo, modelRepo, jobSvc, logger, client, caller)
+	schedulerSvc := service.NewJobSchedulerImpl(jobRepo, jobSvc, logger)
 	modelSvc := service.NewModelImpl(modelRepo, logger)
 	gatewaySvc := service.NewGatewayImpl(gatewayRepo, logger)
 	resultSvc := service.NewResultImpl(resultRepo, jobRepo, droneRepo, logger)
+	hmsSvc := service.NewHMSImpl(hmsRepo, gatewayRepo, logger)
+	firmwareSvc := service.NewFirmwareImpl(firmwareRepo, droneRepo, gatewayRepo, caller, logger)
+	deviceLogSvc := service.NewDeviceLogImpl(deviceLogRepo, droneRepo, storageRepo, caller, logger)
+	mediaSvc := service.NewMediaImpl(mediaRepo, storageRepo, jobRepo, gatewayRepo, caller, logger)
+	bindingSvc := service.NewDeviceBindingImpl(bindingRepo, cfg.Platform.WorkspaceID, logger)
+	// DRC 链路复用设备上云使用的 MQTT 账号
+	drcSvc := service.NewDRCImpl(droneRepo, client, caller, dto.DRCBroker{
+		Address:  cfg.Platform.Thing.Host,
+		Username: cfg.Platform.Thing.Username,
+		Password: cfg.Platform.Thing.Password,
+	}, logger)
+	if err := drcSvc.Subscribe(); err != nil {
+		panic(err)
+	}
 
 	// Service Container
 	container := service.NewContainer(
@@ -133,58 +173,35 @@ func Run(cfg *configs.Config) {
 		modelSvc,
 		gatewaySvc,
 		resultSvc,
+		hmsSvc,
+		firmwareSvc,
+		deviceLogSvc,
+		mediaSvc,
+		drcSvc,
+		bindingSvc,
+		auditSvc,
+		schedulerSvc,
 		logger,
 	)
 
 	// Event Handlers
-	eventhandler.NewHandler(eb, logger, client, droneSvc, gatewaySvc, modelRepo, gatewayRepo)
+	eventhandler.NewHandler(eb, logger, client, cfg, droneSvc, gatewaySvc, jobSvc, hmsSvc, firmwareSvc, deviceLogSvc, mediaSvc, bindingSvc, modelRepo, gatewayRepo)
+
+	// 任务调度需在事件处理注册后启动，补执行的任务才能收到进度上报
+	if err := schedulerSvc.Start(context.Background()); err != nil {
+		panic(err)
+	}
 
 	// 初始化各服务
 	httpV1 := fiber.New()
-	v1.NewRouter(httpV1, eb, logger, container, cfg)
+	v1.NewRouter(httpV1, eb, logger, container, cfg, client)
 
 	httpDJI := fiber.New()
-	dji.NewRouter(httpDJI, eb, logger, droneSvc, wlSvc)
+	dji.NewRouter(httpDJI, eb, logger, droneSvc, wlSvc, mediaSvc, bindingSvc)
 
 	wss := fiber.New()
 	ws.NewRouter(wss, eb, logger, userSvc, droneSvc)
 
-	ctx := context.Background()
-	drones, _, err := droneRepo.SelectAll(ctx, "", "", 0, 0, 0)
-	if err != nil {
-		logger.Error("查询无人机列表失败", slog.Any("err", err))
-		return
-	}
-	for _, drone := range drones {
-		topic := fmt.Sprintf("thing/product/%s/osd", drone.SN)
-
-		token := client.Subscribe(topic, 0, func(c mqtt.Client, m mqtt.Message) {
-			//logger.Info("接收无人机 OSD 消息", slog.Any("topic", m.Topic()), slog.Any("message", string(m.Payload())))
-			var p struct {
-				dto.MessageCommon
-				Data dto.DroneMessageProperty `json:"data"`
-			}
-			if err := json.Unmarshal(m.Payload(), &p); err != nil {
-				logger.Error("解析无人机心跳消息失败", slog.Any("topic", m.Topic()), slog.Any("error", err))
-				return
-			}
-
-			//logger.Info("接收无人机 OSD 消息", slog.Any("topic", m.Topic()), slog.Any("payload", p))
-
-			if err := droneSvc.UpdateStateBySN(ctx, drone.SN, p.Data); err != nil {
-				logger.Error("更新无人机实时数据失败", slog.Any("err", err))
-				return
-			}
-			//logger.Info("更新无人机实时数据成功", slog.Any("droneSN", drone.SN))
-		})
-		if token.Wait() && token.Error() != nil {
-			logger.Error("无人机 OSD 订阅失败", slog.Any("topic", topic), slog.Any("err", token.Error()))
-			return
-		} else {
-			logger.Info("无人机 OSD 订阅成功", slog.Any("topic", topic))
-		}
-	}
-
 	var wg sync.WaitGroup
 	// 启动所有服务器
 	bootServers(cfg, &wg, logger, httpV1, httpDJI, wss)
@@ -202,6 +219,7 @@ func Run(cfg *configs.Config) {
 
 	// 关闭所有服务器
 	shutdownServers(ctx, logger, httpV1, httpDJI, wss)
+	schedulerSvc.Stop()
 
 	// 等待所有服务器关闭
 	wg.Wait()
@@ -1,11 +1,53 @@
 package event
 
+import (
+	"encoding/json"
+	"time"
+)
+
 const (
 	DroneEventSNKey   = "drone.sn"
 	DroneEventTopoKey = "drone.topo"
 )
 
 const (
-	DroneConnected   = "drone.connected"
-	DroneOnlineEvent = "drone.online"
+	DroneConnected    = "drone.connected"
+	DroneOnlineEvent  = "drone.online"
+	DroneOfflineEvent = "drone.offline" // 无人机离线事件，载荷为 *DroneOfflinePayload
+
+	DroneDisconnectedEvent = "drone.disconnected" // 无人机脱离网关拓扑事件，载荷为 *DroneDisconnectedPayload
+)
+
+const (
+	DroneStateChangedKey = "drone.state_changed.payload"
+	DroneStateChanged    = "drone.state_changed" // 无人机属性变化事件，每个变化的属性发布一次
 )
+
+// DroneStatePropertyChanged 返回指定属性的变化事件名，便于只订阅关心的属性
+// 例如 DroneStatePropertyChanged("firmware_version")
+func DroneStatePropertyChanged(property string) string {
+	return DroneStateChanged + "." + property
+}
+
+// DroneStateChangedPayload 无人机属性变化事件载荷
+type DroneStateChangedPayload struct {
+	SN        string          `json:"sn"`        // 无人机序列号
+	Property  string          `json:"property"`  // 属性名，与物模型字段一致
+	Old       json.RawMessage `json:"old"`       // 变化前的值，首次上报时为空
+	New       json.RawMessage `json:"new"`       // 变化后的值
+	Timestamp int64           `json:"timestamp"` // 变化时间，毫秒
+}
+
+// DroneOfflinePayload 无人机离线事件载荷
+type DroneOfflinePayload struct {
+	SN           string    `json:"sn"`             // 无人机序列号
+	LastOnlineAt time.Time `json:"last_online_at"` // 最后一次收到消息的时间
+	Reason       string    `json:"reason"`         // 离线原因
+}
+
+// DroneDisconnectedPayload 无人机脱离网关拓扑事件载荷
+type DroneDisconnectedPayload struct {
+	SN        string `json:"sn"`         // 无人机序列号
+	GatewaySN string `json:"gateway_sn"` // 原挂载的网关序列号
+	Timestamp int64  `json:"timestamp"`  // 脱离时间，毫秒
+}
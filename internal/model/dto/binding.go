@@ -0,0 +1,21 @@
+package dto
+
+// DeviceBindingParams Pilot 发起设备绑定的请求体
+type DeviceBindingParams struct {
+	DeviceSN    string `json:"device_sn"`
+	UserID      string `json:"user_id"`
+	WorkspaceID string `json:"workspace_id"`
+	Nickname    string `json:"nickname,omitempty"`
+}
+
+// BoundDevice 已绑定或未绑定设备列表的单项
+type BoundDevice struct {
+	DeviceSN    string `json:"device_sn"`
+	Nickname    string `json:"nickname"`
+	WorkspaceID string `json:"workspace_id"`
+	Domain      string `json:"domain"`
+	Type        int    `json:"type"`
+	SubType     int    `json:"sub_type"`
+	BoundStatus bool   `json:"bound_status"`
+	BoundTime   string `json:"bound_time,omitempty"` // 绑定时间，格式为 2006-01-02 15:04:05
+}
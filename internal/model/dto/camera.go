@@ -0,0 +1,28 @@
+package dto
+
+// 相机与云台控制相关方法（services）
+const (
+	MethodCameraModeSwitch = "camera_mode_switch" // 切换拍照、录像模式
+	MethodLiveLensChange   = "live_lens_change"   // 切换直播镜头
+	MethodCameraScreenDrag = "camera_screen_drag" // 拖动画面控制云台转动
+)
+
+// CameraModeSwitchData camera_mode_switch 请求数据
+type CameraModeSwitchData struct {
+	PayloadIndex string `json:"payload_index"`
+	CameraMode   int    `json:"camera_mode"` // 取值见 CameraModeMap
+}
+
+// LiveLensChangeData live_lens_change 请求数据
+type LiveLensChangeData struct {
+	VideoID   string `json:"video_id"`   // 正在直播的视频流 ID
+	VideoType string `json:"video_type"` // 镜头类型：wide、zoom、ir
+}
+
+// CameraScreenDragData camera_screen_drag 请求数据
+type CameraScreenDragData struct {
+	PayloadIndex string  `json:"payload_index"`
+	Locked       bool    `json:"locked"`      // 是否锁定机头，锁定时只转动云台
+	PitchSpeed   float64 `json:"pitch_speed"` // 俯仰转动速度，单位：rad/s
+	YawSpeed     float64 `json:"yaw_speed"`   // 偏航转动速度，单位：rad/s
+}
@@ -0,0 +1,82 @@
+package dto
+
+// DRC（指令飞行）相关方法
+const (
+	MethodDRCModeEnter        = "drc_mode_enter"        // 进入指令飞行模式（services）
+	MethodDRCModeExit         = "drc_mode_exit"         // 退出指令飞行模式（services）
+	MethodFlightAuthorityGrab = "flight_authority_grab" // 抢夺飞行控制权（services）
+	MethodReturnHome          = "return_home"           // 一键返航（services）
+	MethodCameraFocalLenSet   = "camera_focal_length_set"
+	MethodGimbalReset         = "gimbal_reset"
+
+	// 以下方法通过 thing/product/{gateway_sn}/drc/down 下发
+	MethodDRCHeartBeat        = "heart_beat"           // 心跳
+	MethodDRCStickControl     = "stick_control"        // 杆量控制
+	MethodDRCEmergencyStop    = "drone_emergency_stop" // 紧急停桨
+	MethodDRCOSDInfoPush      = "osd_info_push"        // 高频 OSD 推送（drc/up）
+	MethodDRCStatusNotify     = "drc_status_notify"    // DRC 链路状态（drc/up）
+	WSMethodDRCError          = "drc_error"            // 推送给前端的 DRC 错误
+	WSMethodDRCSessionEntered = "drc_entered"          // 推送给前端的 DRC 已进入
+)
+
+// 杆量范围，1024 为中位
+const (
+	StickMin     = 364
+	StickNeutral = 1024
+	StickMax     = 1684
+)
+
+// DRCBroker 设备建立 DRC 链路使用的 MQTT 中继信息
+type DRCBroker struct {
+	Address    string `json:"address"` // host:port，不带协议前缀
+	ClientID   string `json:"client_id"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	ExpireTime int64  `json:"expire_time"` // 凭证过期时间，秒级时间戳
+	EnableTLS  bool   `json:"enable_tls"`
+}
+
+// DRCModeEnterData drc_mode_enter 请求数据
+type DRCModeEnterData struct {
+	MQTTBroker   DRCBroker `json:"mqtt_broker"`
+	OSDFrequency int       `json:"osd_frequency"` // osd_info_push 频率，单位：Hz
+	HSIFrequency int       `json:"hsi_frequency"` // hsi_info_push 频率，单位：Hz
+}
+
+// DRCMessage drc/down 与 drc/up 的消息体
+type DRCMessage struct {
+	Method    string `json:"method"`
+	Seq       int64  `json:"seq,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Data      any    `json:"data"`
+}
+
+// StickControlData stick_control 杆量数据
+type StickControlData struct {
+	Roll        int `json:"roll"`
+	Pitch       int `json:"pitch"`
+	Throttle    int `json:"throttle"`
+	Yaw         int `json:"yaw"`
+	GimbalPitch int `json:"gimbal_pitch"`
+}
+
+// CameraFocalLengthSetData camera_focal_length_set 请求数据
+type CameraFocalLengthSetData struct {
+	PayloadIndex string  `json:"payload_index"`
+	CameraType   string  `json:"camera_type"` // zoom 或 ir
+	ZoomFactor   float64 `json:"zoom_factor"`
+}
+
+// gimbal_reset 的重置模式
+const (
+	GimbalResetModeRecenter    = 0 // 回中
+	GimbalResetModeDown        = 1 // 向下，偏航回中且俯仰向下
+	GimbalResetModeYawRecenter = 2 // 仅偏航回中
+	GimbalResetModePitchDown   = 3 // 仅俯仰向下
+)
+
+// GimbalResetData gimbal_reset 请求数据
+type GimbalResetData struct {
+	PayloadIndex string `json:"payload_index"`
+	ResetMode    int    `json:"reset_mode"` // 0: 回中，1: 向下，2: 偏航回中，3: 俯仰向下
+}
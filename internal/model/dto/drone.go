@@ -7,9 +7,18 @@ type DroneModelOption struct {
 	Name string `json:"name"` // 型号名称
 }
 
+// WSMethodDroneDisconnected 推送给前端的无人机脱离拓扑消息
+const WSMethodDroneDisconnected = "drone_disconnected"
+
 type WSbaseModel struct {
 	TID       string      `json:"tid"`       // 事务 ID, UUID
 	Timestamp int64       `json:"timestamp"` // 时间戳, 秒
 	Method    string      `json:"method"`    // 方法名
 	Data      interface{} `json:"data"`      // 数据
 }
+
+// ControlReply 控制消息的执行结果，回复给发起控制的连接
+type ControlReply struct {
+	Result  int    `json:"result"`            // 0 表示成功，设备拒绝时为设备返回码，其余错误为 -1
+	Message string `json:"message,omitempty"` // 失败原因
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "encoding/json"
+
+// EventsMessage 设备事件上报的通用消息体
+// Topic: thing/product/*{gateway_sn}*/events
+type EventsMessage struct {
+	MessageCommon
+	NeedReply int             `json:"need_reply"` // 是否需要回复，1 为需要
+	Gateway   string          `json:"gateway"`    // 网关设备的序列号
+	Data      json.RawMessage `json:"data"`       // 各事件的数据内容不同，由对应的处理方法解析
+}
@@ -0,0 +1,126 @@
+package dto
+
+// 设备日志上传相关方法
+// Topic: thing/product/*{gateway_sn}*/services
+const (
+	MethodFileuploadList     = "fileupload_list"     // 获取设备日志列表
+	MethodFileuploadStart    = "fileupload_start"    // 开始上传设备日志
+	MethodFileuploadProgress = "fileupload_progress" // 日志上传进度（events）
+)
+
+// 日志所属模块
+const (
+	FileuploadModuleAircraft = "0" // 飞行器
+	FileuploadModuleRC       = "3" // 遥控器或机场
+)
+
+// 日志上传状态，与 fileupload_progress 上报的 status 一致
+const (
+	FileuploadStatusSent       = "sent"
+	FileuploadStatusInProgress = "in_progress"
+	FileuploadStatusOK         = "ok"
+	FileuploadStatusFailed     = "failed"
+	FileuploadStatusCanceled   = "canceled"
+	FileuploadStatusTimeout    = "timeout"
+)
+
+// StorageCredentials 设备上传文件使用的对象存储凭证
+type StorageCredentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	Expire          int64  `json:"expire"` // 凭证有效期，单位：秒
+	SecurityToken   string `json:"security_token"`
+}
+
+// StorageConfig 设备直传对象存储所需的配置
+type StorageConfig struct {
+	Endpoint    string             `json:"endpoint"`
+	Bucket      string             `json:"bucket"`
+	Region      string             `json:"region"`
+	Provider    string             `json:"provider"` // 存储服务商：ali、aws、minio
+	Credentials StorageCredentials `json:"credentials"`
+}
+
+// FileuploadListData fileupload_list 请求数据
+type FileuploadListData struct {
+	ModuleList []string `json:"module_list"`
+}
+
+// FileuploadListOutput fileupload_list 应答输出
+type FileuploadListOutput struct {
+	Files []FileuploadDeviceFiles `json:"files"`
+}
+
+// FileuploadDeviceFiles 单个模块的日志列表
+type FileuploadDeviceFiles struct {
+	DeviceSN string              `json:"device_sn"`
+	Module   string              `json:"module"`
+	Result   int                 `json:"result"`
+	List     []FileuploadLogFile `json:"list"`
+}
+
+// FileuploadLogFile 一次开机产生的日志
+type FileuploadLogFile struct {
+	BootIndex int   `json:"boot_index"` // 开机序号
+	StartTime int64 `json:"start_time"` // 开始时间，秒级时间戳
+	EndTime   int64 `json:"end_time"`   // 结束时间，秒级时间戳
+	Size      int64 `json:"size"`       // 文件大小，单位：字节
+}
+
+// FileuploadStartData fileupload_start 请求数据
+type FileuploadStartData struct {
+	StorageConfig
+	Params FileuploadStartParams `json:"params"`
+}
+
+// FileuploadStartParams fileupload_start 上传参数
+type FileuploadStartParams struct {
+	Files []FileuploadStartFile `json:"files"`
+}
+
+// FileuploadStartFile 单个模块的上传参数，同一模块的多个日志打包为一个对象
+type FileuploadStartFile struct {
+	ObjectKey string                `json:"object_key"`
+	Module    string                `json:"module"`
+	List      []FileuploadBootIndex `json:"list"`
+}
+
+type FileuploadBootIndex struct {
+	BootIndex int `json:"boot_index"`
+}
+
+// FileuploadProgressData fileupload_progress 事件数据
+type FileuploadProgressData struct {
+	Output struct {
+		Status string `json:"status"`
+		Ext    struct {
+			Files []FileuploadProgressFile `json:"files"`
+		} `json:"ext"`
+	} `json:"output"`
+}
+
+// FileuploadProgressFile 单个对象的上传进度
+type FileuploadProgressFile struct {
+	DeviceSN    string `json:"device_sn"`
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	Module      string `json:"module"`
+	Size        int64  `json:"size"`
+	Progress    struct {
+		CurrentStep int    `json:"current_step"`
+		TotalStep   int    `json:"total_step"`
+		Progress    int    `json:"progress"`
+		Result      int    `json:"result"`
+		Status      string `json:"status"`
+		UploadRate  int64  `json:"upload_rate"`
+		FinishTime  int64  `json:"finish_time"`
+	} `json:"progress"`
+}
+
+// DeviceLogUploadParams 发起日志上传的请求参数
+type DeviceLogUploadParams struct {
+	Files []struct {
+		Module      string `json:"module"`       // 日志模块，0: 飞行器，3: 遥控器
+		BootIndexes []int  `json:"boot_indexes"` // 需要上传的开机序号
+	} `json:"files"`
+}
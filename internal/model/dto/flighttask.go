@@ -0,0 +1,143 @@
+package dto
+
+import "encoding/json"
+
+// 航线任务相关方法名
+// Topic: thing/product/*{gateway_sn}*/services
+const (
+	MethodFlighttaskPrepare  = "flighttask_prepare"
+	MethodFlighttaskExecute  = "flighttask_execute"
+	MethodFlighttaskPause    = "flighttask_pause"    // 暂停航线
+	MethodFlighttaskRecovery = "flighttask_recovery" // 恢复航线
+	MethodFlighttaskStop     = "flighttask_stop"     // 终止航线
+	MethodReturnHomeCancel   = "return_home_cancel"  // 取消返航
+)
+
+// 控制连接 wayline 方法支持的航线控制动作
+const (
+	FlighttaskActionTakeoff      = "takeoff"       // 起飞由任务下发完成，控制连接收到时返回错误
+	FlighttaskActionPause        = "pause"         // 暂停航线
+	FlighttaskActionResume       = "resume"        // 恢复航线
+	FlighttaskActionFinish       = "finish"        // 终止航线
+	FlighttaskActionReturn       = "return"        // 一键返航
+	FlighttaskActionCancelReturn = "cancel_return" // 取消返航
+)
+
+// 任务类型
+const (
+	FlighttaskTypeImmediate   = 0 // 立即任务
+	FlighttaskTypeTimed       = 1 // 定时任务
+	FlighttaskTypeConditional = 2 // 条件任务
+)
+
+// 航线失控动作
+const (
+	OutOfControlActionGoHome  = 0 // 返航
+	OutOfControlActionHover   = 1 // 悬停
+	OutOfControlActionLanding = 2 // 降落
+)
+
+// ServicesRequest 对应 MQTT services 方法的通用请求体
+type ServicesRequest struct {
+	MessageCommon
+	Data any `json:"data"`
+}
+
+// ServicesReply 对应 MQTT services_reply 方法的通用应答体
+type ServicesReply struct {
+	MessageCommon
+	Data ServicesReplyData `json:"data"`
+}
+
+type ServicesReplyData struct {
+	Result int             `json:"result"`           // 返回码，非 0 代表错误
+	Output json.RawMessage `json:"output,omitempty"` // 输出内容，各方法不同
+}
+
+// FlighttaskFile 航线文件对象
+type FlighttaskFile struct {
+	URL         string `json:"url"`         // 文件 URL
+	Fingerprint string `json:"fingerprint"` // 文件 MD5 签名
+}
+
+// FlighttaskPrepareData 对应 flighttask_prepare 方法的请求数据
+type FlighttaskPrepareData struct {
+	FlightID              string         `json:"flight_id"`                 // 计划 ID
+	ExecuteTime           int64          `json:"execute_time"`              // 开始执行时间，毫秒时间戳
+	TaskType              int            `json:"task_type"`                 // 任务类型
+	WaylineType           int            `json:"wayline_type"`              // 航线类型，0 为航点航线
+	File                  FlighttaskFile `json:"file"`                      // 航线文件对象
+	RTHAltitude           int            `json:"rth_altitude"`              // 返航高度，单位：米
+	OutOfControlAction    int            `json:"out_of_control_action"`     // 遥控器失控动作
+	ExitWaylineWhenRCLost int            `json:"exit_wayline_when_rc_lost"` // 航线失控动作，0 为继续执行航线，1 为退出航线
+}
+
+// FlighttaskExecuteData 对应 flighttask_execute 方法的请求数据
+type FlighttaskExecuteData struct {
+	FlightID string `json:"flight_id"` // 计划 ID
+}
+
+// JobDispatchParams 下发任务时由操作员指定的参数
+type JobDispatchParams struct {
+	RTHAltitude           int  `json:"rth_altitude"`              // 返航高度，单位：米
+	OutOfControlAction    int  `json:"out_of_control_action"`     // 遥控器失控动作
+	ExitWaylineWhenRCLost int  `json:"exit_wayline_when_rc_lost"` // 航线失控动作
+	IgnoreReadiness       bool `json:"ignore_readiness"`          // 忽略未通过的起飞前检查，强制下发
+}
+
+// 航线任务进度上报
+// Topic: thing/product/*{gateway_sn}*/events
+const MethodFlighttaskProgress = "flighttask_progress"
+
+// 航线任务进度状态
+const (
+	FlighttaskStatusCanceled      = "canceled"       // 取消或终止
+	FlighttaskStatusFailed        = "failed"         // 失败
+	FlighttaskStatusInProgress    = "in_progress"    // 执行中
+	FlighttaskStatusOK            = "ok"             // 执行成功
+	FlighttaskStatusPartiallyDone = "partially_done" // 部分完成
+	FlighttaskStatusPaused        = "paused"         // 暂停
+	FlighttaskStatusRejected      = "rejected"       // 拒绝
+	FlighttaskStatusSent          = "sent"           // 已下发
+	FlighttaskStatusTimeout       = "timeout"        // 超时
+)
+
+// FlighttaskProgressData 对应 flighttask_progress 事件的数据
+type FlighttaskProgressData struct {
+	Result int                      `json:"result"` // 返回码，非 0 代表错误
+	Output FlighttaskProgressOutput `json:"output"`
+}
+
+type FlighttaskProgressOutput struct {
+	Ext      FlighttaskProgressExt `json:"ext"`
+	Progress FlighttaskProgress    `json:"progress"`
+	Status   string                `json:"status"` // 任务状态
+}
+
+type FlighttaskProgressExt struct {
+	CurrentWaypointIndex int                   `json:"current_waypoint_index"` // 当前执行到的航点数
+	WaylineMissionState  int                   `json:"wayline_mission_state"`  // 航线任务状态
+	MediaCount           int                   `json:"media_count"`            // 本次航线任务执行产生的媒体文件数量
+	TrackID              string                `json:"track_id"`               // 轨迹 ID
+	FlightID             string                `json:"flight_id"`              // 计划 ID
+	WaylineID            int                   `json:"wayline_id"`             // 当前航线 ID
+	BreakPoint           *FlighttaskBreakPoint `json:"break_point,omitempty"`  // 航线断点信息
+}
+
+type FlighttaskProgress struct {
+	CurrentStep int `json:"current_step"` // 执行步骤
+	Percent     int `json:"percent"`      // 进度百分比
+}
+
+// FlighttaskBreakPoint 航线断点信息
+type FlighttaskBreakPoint struct {
+	Index        int     `json:"index"`         // 断点序号
+	State        int     `json:"state"`         // 断点状态，0 为在航段上，1 为在航点上
+	Progress     float64 `json:"progress"`      // 当前航段进度
+	WaylineID    int     `json:"wayline_id"`    // 航线 ID
+	BreakReason  int     `json:"break_reason"`  // 中断原因
+	Latitude     float64 `json:"latitude"`      // 断点纬度
+	Longitude    float64 `json:"longitude"`     // 断点经度
+	Height       float64 `json:"height"`        // 断点相对地球椭球面高度
+	AttitudeHead float64 `json:"attitude_head"` // 断点偏航角
+}
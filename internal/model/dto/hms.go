@@ -0,0 +1,33 @@
+package dto
+
+// MethodHMS 设备健康管理（HMS）告警事件
+const MethodHMS = "hms"
+
+// HMS 告警等级
+const (
+	HMSLevelNotice  = 0 // 通知
+	HMSLevelCaution = 1 // 提醒
+	HMSLevelWarning = 2 // 警告
+)
+
+// HMSData hms 事件的 data 字段
+type HMSData struct {
+	List []HMSItem `json:"list"`
+}
+
+// HMSItem 单条健康告警
+type HMSItem struct {
+	Level      int     `json:"level"`       // 告警等级，0: 通知，1: 提醒，2: 警告
+	Module     int     `json:"module"`      // 事件模块，0: 航线任务，1: 设备管理，2: 媒体，3: HMS
+	InTheSky   int     `json:"in_the_sky"`  // 是否在空中，0: 地面，1: 空中
+	Code       string  `json:"code"`        // 告警码
+	DeviceType string  `json:"device_type"` // 设备类型，格式 {domain}-{type}-{sub_type}
+	Imminent   int     `json:"imminent"`    // 是否为紧急告警
+	Args       HMSArgs `json:"args"`        // 告警码参数
+}
+
+// HMSArgs 告警码参数
+type HMSArgs struct {
+	ComponentIndex int `json:"component_index"` // 部件索引
+	SensorIndex    int `json:"sensor_index"`    // 传感器索引
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "time"
+
+// 起飞前检查结果等级
+const (
+	ReadinessPass = "pass" // 通过
+	ReadinessWarn = "warn" // 存在风险，仍可下发
+	ReadinessFail = "fail" // 未通过，除非操作员忽略否则不能下发
+)
+
+// 起飞前检查项
+const (
+	ReadinessCheckOnline     = "online"     // 在线状态
+	ReadinessCheckBattery    = "battery"    // 电量
+	ReadinessCheckWind       = "wind"       // 风速
+	ReadinessCheckStorage    = "storage"    // 存储空间
+	ReadinessCheckHMS        = "hms"        // 健康告警
+	ReadinessCheckCompatible = "compatible" // 固件一致性
+)
+
+// ReadinessCheck 单个检查项的结果
+type ReadinessCheck struct {
+	Name    string `json:"name"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// DroneReadiness 单架无人机的起飞前检查结果，Level 为各检查项中最严重的等级
+type DroneReadiness struct {
+	DroneKey string           `json:"drone_key"`
+	DroneSN  string           `json:"drone_sn"`
+	Level    string           `json:"level"`
+	Checks   []ReadinessCheck `json:"checks"`
+}
+
+// JobReadinessReport 任务的起飞前检查报告，Level 为各无人机中最严重的等级
+type JobReadinessReport struct {
+	JobID       uint             `json:"job_id"`
+	Level       string           `json:"level"`
+	CheckedTime time.Time        `json:"checked_time"`
+	Drones      []DroneReadiness `json:"drones"`
+}
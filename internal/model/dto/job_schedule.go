@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// JobScheduleParams 设置任务的周期执行与错过执行的补偿策略
+type JobScheduleParams struct {
+	CronExpr        string `json:"cron_expr"`         // cron 表达式，支持 5 段或带秒的 6 段，为空表示只在计划时间执行一次
+	MissedRunPolicy string `json:"missed_run_policy"` // skip、once 或 all，为空时为 skip
+}
+
+// JobScheduleResult 任务的调度状态
+type JobScheduleResult struct {
+	JobID           uint       `json:"job_id"`
+	ScheduleTime    time.Time  `json:"schedule_time"`
+	CronExpr        string     `json:"cron_expr"`
+	MissedRunPolicy string     `json:"missed_run_policy"`
+	LastRunTime     *time.Time `json:"last_run_time"`
+	NextRunTime     *time.Time `json:"next_run_time"` // 下一次计划执行时间，没有待执行的计划时为空
+}
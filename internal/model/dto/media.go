@@ -0,0 +1,77 @@
+package dto
+
+// 媒体文件上传相关方法
+const (
+	MethodFileUploadCallback                   = "file_upload_callback"                     // 媒体文件上传结果（events）
+	MethodHighestPriorityUploadFlighttaskMedia = "highest_priority_upload_flighttask_media" // 优先上传的航线任务（events）
+	MethodUploadFlighttaskMediaPrioritize      = "upload_flighttask_media_prioritize"       // 设置优先上传的航线任务（services）
+)
+
+// STSResult Pilot 获取上传凭证接口的返回数据
+type STSResult struct {
+	StorageConfig
+	ObjectKeyPrefix string `json:"object_key_prefix"` // 对象键前缀，设备上传的文件都位于该前缀下
+}
+
+// MediaFile 已上传的媒体文件，机场 file_upload_callback 与 Pilot upload-callback 共用
+type MediaFile struct {
+	ObjectKey       string            `json:"object_key"`
+	Path            string            `json:"path"`
+	Name            string            `json:"name"`
+	Fingerprint     string            `json:"fingerprint,omitempty"`
+	TinyFingerprint string            `json:"tiny_fingerprint,omitempty"`
+	SubFileType     int               `json:"sub_file_type,omitempty"` // 0: 普通文件，1: 全景图
+	Ext             MediaFileExt      `json:"ext"`
+	Metadata        MediaFileMetadata `json:"metadata"`
+}
+
+// MediaFileExt 媒体文件扩展信息
+type MediaFileExt struct {
+	SN              string `json:"sn,omitempty"`        // 拍摄的无人机，仅 Pilot 上报
+	FlightID        string `json:"flight_id,omitempty"` // 航线任务 ID
+	DroneModelKey   string `json:"drone_model_key"`
+	PayloadModelKey string `json:"payload_model_key"`
+	IsOriginal      bool   `json:"is_original"` // 是否为原图
+}
+
+// MediaFileMetadata 媒体文件拍摄信息
+type MediaFileMetadata struct {
+	GimbalYawDegree  float64 `json:"gimbal_yaw_degree"`
+	AbsoluteAltitude float64 `json:"absolute_altitude"`
+	RelativeAltitude float64 `json:"relative_altitude"`
+	CreateTime       string  `json:"create_time"` // 拍摄时间，RFC3339 格式
+	ShootPosition    struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	} `json:"shoot_position"`
+}
+
+// FileUploadCallbackData file_upload_callback 事件数据
+type FileUploadCallbackData struct {
+	File       MediaFile `json:"file"`
+	FlightTask struct {
+		ExpectedFileCount int `json:"expected_file_count"`
+		UploadedFileCount int `json:"uploaded_file_count"`
+		FlightType        int `json:"flight_type"`
+	} `json:"flight_task"`
+}
+
+// FlighttaskMediaData highest_priority_upload_flighttask_media 与 upload_flighttask_media_prioritize 的数据
+type FlighttaskMediaData struct {
+	FlightID string `json:"flight_id"`
+}
+
+// TinyFingerprintsParams Pilot 查询已存在的文件
+type TinyFingerprintsParams struct {
+	TinyFingerprints []string `json:"tiny_fingerprints"`
+}
+
+// MediaQuery 媒体文件查询参数
+type MediaQuery struct {
+	JobID     uint   `query:"job_id"`
+	WaylineID uint   `query:"wayline_id"`
+	DroneSN   string `query:"drone_sn"`
+	FlightID  string `query:"flight_id"`
+	Page      int    `query:"page"`
+	PageSize  int    `query:"page_size"`
+}
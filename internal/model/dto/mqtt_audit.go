@@ -0,0 +1,13 @@
+package dto
+
+// MQTTAuditQuery MQTT 审计记录查询参数
+type MQTTAuditQuery struct {
+	SN        string `query:"sn"`
+	Method    string `query:"method"`
+	Direction string `query:"direction"` // in 或 out
+	TID       string `query:"tid"`       // 按 tid 或 bid 查询同一次交互的请求与应答
+	Start     int64  `query:"start"`     // 开始时间，毫秒时间戳
+	End       int64  `query:"end"`       // 结束时间，毫秒时间戳
+	Page      int    `query:"page"`
+	PageSize  int    `query:"page_size"`
+}
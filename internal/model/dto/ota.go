@@ -0,0 +1,62 @@
+package dto
+
+// 固件升级相关方法
+const (
+	MethodOTACreate   = "ota_create"   // 创建固件升级任务
+	MethodOTAProgress = "ota_progress" // 固件升级进度上报
+)
+
+// 固件升级类型
+const (
+	FirmwareUpgradeTypeNormal      = 2 // 普通升级
+	FirmwareUpgradeTypeConsistency = 3 // 一致性升级
+)
+
+// 固件升级状态，与 ota_progress 上报的 status 一致
+const (
+	OTAStatusSent       = "sent"        // 已下发
+	OTAStatusInProgress = "in_progress" // 升级中
+	OTAStatusOK         = "ok"          // 升级成功
+	OTAStatusPaused     = "paused"      // 暂停
+	OTAStatusRejected   = "rejected"    // 拒绝
+	OTAStatusFailed     = "failed"      // 失败
+	OTAStatusCanceled   = "canceled"    // 取消
+	OTAStatusTimeout    = "timeout"     // 超时
+)
+
+// OTACreateData ota_create 请求数据
+type OTACreateData struct {
+	Devices []OTADevice `json:"devices"`
+}
+
+// OTADevice 单个待升级设备
+type OTADevice struct {
+	SN                  string `json:"sn"`                    // 设备序列号
+	ProductVersion      string `json:"product_version"`       // 目标固件版本
+	FileURL             string `json:"file_url"`              // 固件文件地址
+	MD5                 string `json:"md5"`                   // 固件文件 MD5
+	FileSize            int64  `json:"file_size"`             // 固件文件大小，单位：字节
+	FileName            string `json:"file_name"`             // 固件文件名
+	FirmwareUpgradeType int    `json:"firmware_upgrade_type"` // 升级类型，2: 普通升级，3: 一致性升级
+}
+
+// OTAProgressData ota_progress 事件数据
+type OTAProgressData struct {
+	Result int               `json:"result"`
+	Output OTAProgressOutput `json:"output"`
+}
+
+// OTAProgressOutput ota_progress 事件输出
+type OTAProgressOutput struct {
+	Status   string `json:"status"` // 升级状态
+	Progress struct {
+		Percent     int    `json:"percent"`      // 进度百分比
+		CurrentStep string `json:"current_step"` // 当前步骤
+	} `json:"progress"`
+}
+
+// FirmwareUpgradeParams 创建固件升级任务参数
+type FirmwareUpgradeParams struct {
+	SNs                 []string `json:"sns"`                   // 待升级的设备序列号
+	FirmwareUpgradeType int      `json:"firmware_upgrade_type"` // 升级类型，默认为普通升级
+}
@@ -0,0 +1,39 @@
+package dto
+
+import "encoding/json"
+
+// 设备主动请求的方法名
+// Topic: thing/product/*{gateway_sn}*/requests
+const (
+	MethodConfig           = "config"
+	MethodStorageConfigGet = "storage_config_get"
+	MethodFlightAreasGet   = "flight_areas_get"
+)
+
+// RequestsMessage 设备请求的通用消息体
+type RequestsMessage struct {
+	MessageCommon
+	Gateway string          `json:"gateway"` // 网关设备的序列号
+	Data    json.RawMessage `json:"data"`    // 各方法的数据内容不同，由对应的处理方法解析
+}
+
+// RequestsReply 对设备请求的应答
+// Topic: thing/product/*{gateway_sn}*/requests_reply
+type RequestsReply struct {
+	MessageCommon
+	Data any `json:"data"`
+}
+
+// ConfigRequestData 对应 config 请求的数据
+type ConfigRequestData struct {
+	ConfigType  string `json:"config_type"`  // 配置类型，固定为 json
+	ConfigScope string `json:"config_scope"` // 配置范围，固定为 product
+}
+
+// ConfigReplyData 对应 config 请求的应答数据
+type ConfigReplyData struct {
+	NTPServerHost string `json:"ntp_server_host"` // NTP 服务地址
+	AppID         string `json:"app_id"`          // 应用 ID
+	AppKey        string `json:"app_key"`         // 应用 Key
+	AppLicense    string `json:"app_license"`     // 应用 License
+}
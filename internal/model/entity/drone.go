@@ -28,10 +28,11 @@ type Drone struct {
 	Variation    po.DroneVariation `json:"variation"`      // 无人机变体配置
 
 	// 状态信息
-	Status    string `json:"status"` // 在线状态
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	GwSN      string `json:"gw_sn,omitempty"` // 从 ro.Drone 继承或单独设置的网关SN
+	Status       string    `json:"status"`         // 在线状态
+	LastOnlineAt time.Time `json:"last_online_at"` // 最后在线时间
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	GwSN         string `json:"gw_sn,omitempty"` // 从 ro.Drone 继承或单独设置的网关SN
 	dto.DroneMessageProperty
 }
 
@@ -11,7 +11,11 @@ type Job struct {
 	Name                    string                        `json:"name"`
 	Description             string                        `json:"description"`
 	Area                    Area                          `json:"area"`
+	Status                  int                           `json:"status"`        // 生命周期状态
 	ScheduleTime            time.Time                     `json:"schedule_time"` // 任务计划执行时间
+	CronExpr                string                        `json:"cron_expr"`     // 周期任务的 cron 表达式
+	MissedRunPolicy         string                        `json:"missed_run_policy"`
+	LastRunTime             *time.Time                    `json:"last_run_time"`
 	Drones                  []JobDrone                    `json:"drones"`
 	Waylines                []po.JobWaylinePO             `json:"waylines"`
 	CommandDrones           []po.JobCommandDronePO        `json:"command_drones"`
@@ -0,0 +1,34 @@
+package po
+
+import "time"
+
+// 设备绑定状态
+const (
+	DeviceBindingStatusRejected = -1 // 管理员拒绝，设备数据持续被忽略
+	DeviceBindingStatusPending  = 0  // 未知设备等待管理员审批
+	DeviceBindingStatusBound    = 1  // 已绑定到工作空间
+	DeviceBindingStatusUnbound  = 2  // 已解绑，再次上线时重新进入待审批
+)
+
+// DeviceBinding 设备与工作空间的绑定关系
+//
+// 只有已绑定的设备才会被保存并接收遥测，未知设备首次出现或 Pilot 发起绑定时生成待审批记录
+type DeviceBinding struct {
+	ID          uint       `json:"id" gorm:"primaryKey;column:binding_id"`
+	CreatedTime time.Time  `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime time.Time  `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	SN          string     `json:"sn" gorm:"unique;column:sn"`
+	WorkspaceID string     `json:"workspace_id" gorm:"column:workspace_id"`
+	Domain      string     `json:"domain" gorm:"column:domain"` // 0: 无人机，2: 遥控器，3: 机场
+	Type        int        `json:"type" gorm:"column:type"`
+	SubType     int        `json:"sub_type" gorm:"column:sub_type"`
+	Nickname    string     `json:"nickname" gorm:"column:nickname"`
+	UserID      string     `json:"user_id" gorm:"column:user_id"` // Pilot 发起绑定的用户
+	Status      int        `json:"status" gorm:"default:0;column:status"`
+	BoundAt     *time.Time `json:"bound_at" gorm:"column:bound_at"`
+}
+
+// TableName 指定 DeviceBinding 表名为 tb_device_bindings
+func (b DeviceBinding) TableName() string {
+	return "tb_device_bindings"
+}
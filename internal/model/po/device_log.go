@@ -0,0 +1,29 @@
+package po
+
+import "time"
+
+// DeviceLog 设备日志上传记录，每个模块对应对象存储中的一个对象
+type DeviceLog struct {
+	ID          uint       `json:"device_log_id" gorm:"primaryKey;column:device_log_id"`
+	CreatedTime time.Time  `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime time.Time  `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	DroneSN     string     `json:"drone_sn" gorm:"index;column:drone_sn"`   // 发起上传的无人机
+	GatewaySN   string     `json:"gateway_sn" gorm:"column:gateway_sn"`     // 下发 fileupload_start 的网关
+	DeviceSN    string     `json:"device_sn" gorm:"column:device_sn"`       // 日志所属设备，由进度上报回填
+	BID         string     `json:"bid" gorm:"index;column:bid"`             // fileupload_start 的 bid
+	Module      string     `json:"module" gorm:"column:module"`             // 0: 飞行器，3: 遥控器
+	BootIndexes string     `json:"boot_indexes" gorm:"column:boot_indexes"` // 上传的开机序号，逗号分隔
+	S3Key       string     `json:"s3_key" gorm:"index;column:s3_key"`
+	Fingerprint string     `json:"fingerprint" gorm:"column:fingerprint"`
+	Size        int64      `json:"size" gorm:"column:size"`
+	Status      string     `json:"status" gorm:"column:status"` // 上传状态，取值同 fileupload_progress 的 status
+	Progress    int        `json:"progress" gorm:"column:progress"`
+	Result      int        `json:"result" gorm:"column:result"`
+	Message     string     `json:"message" gorm:"column:message"`
+	FinishedAt  *time.Time `json:"finished_at" gorm:"column:finished_at"`
+}
+
+// TableName 指定 DeviceLog 表名为 tb_device_logs
+func (l DeviceLog) TableName() string {
+	return "tb_device_logs"
+}
@@ -7,14 +7,15 @@ import (
 )
 
 type Drone struct {
-	ID          uint      `json:"drone_id" gorm:"primaryKey;column:drone_id"`
-	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
-	UpdatedTime time.Time `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
-	State       int       `json:"state" gorm:"default:0;column:state"`               // -1: deleted, 0: active
-	SN          string    `json:"sn" gorm:"column:sn"`                               // 序列号
-	Callsign    string    `json:"callsign" gorm:"column:callsign"`                   // 呼号
-	Description string    `json:"drone_description" gorm:"column:drone_description"` // 描述
-	Status      int       `json:"status" gorm:"-"`                                   // 0: offline, 1: online
+	ID           uint      `json:"drone_id" gorm:"primaryKey;column:drone_id"`
+	CreatedTime  time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime  time.Time `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	State        int       `json:"state" gorm:"default:0;column:state"`               // -1: deleted, 0: active
+	SN           string    `json:"sn" gorm:"column:sn"`                               // 序列号
+	Callsign     string    `json:"callsign" gorm:"column:callsign"`                   // 呼号
+	Description  string    `json:"drone_description" gorm:"column:drone_description"` // 描述
+	Status       int       `json:"status" gorm:"-"`                                   // 0: offline, 1: online
+	LastOnlineAt time.Time `json:"last_online_at" gorm:"column:last_online_at"`       // 最后在线时间，离线检测时写入
 
 	// 与 DroneModel 的关联（多对一）
 	DroneModelID uint       `json:"drone_model_id" gorm:"index;column:drone_model_id"` // 无人机型号ID
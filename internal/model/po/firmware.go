@@ -0,0 +1,68 @@
+package po
+
+import "time"
+
+// Firmware 固件包，按设备型号登记，文件存放在对象存储中
+type Firmware struct {
+	ID          uint      `json:"firmware_id" gorm:"primaryKey;column:firmware_id"`
+	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime time.Time `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	State       int       `json:"state" gorm:"default:0;column:state"`                       // -1: deleted, 0: active
+	ModelKey    string    `json:"model_key" gorm:"index:idx_model_version;column:model_key"` // 设备型号，格式 {domain}-{type}-{sub_type}
+	Version     string    `json:"version" gorm:"index:idx_model_version;column:version"`     // 固件版本
+	FileName    string    `json:"file_name" gorm:"column:file_name"`                         // 原始文件名
+	S3Key       string    `json:"s3_key" gorm:"column:s3_key"`                               // 对象存储中的键
+	FileSize    int64     `json:"file_size" gorm:"column:file_size"`                         // 文件大小，单位：字节
+	MD5         string    `json:"md5" gorm:"column:md5"`                                     // 文件 MD5
+	ReleaseNote string    `json:"release_note" gorm:"column:release_note"`                   // 版本说明
+}
+
+// TableName 指定 Firmware 表名为 tb_firmwares
+func (f Firmware) TableName() string {
+	return "tb_firmwares"
+}
+
+// 升级任务整体状态
+const (
+	FirmwareUpgradeJobStatusRunning  = 0 // 进行中
+	FirmwareUpgradeJobStatusFinished = 1 // 所有设备均已结束
+)
+
+// FirmwareUpgradeJob 固件升级任务，一次升级请求对应一条记录
+type FirmwareUpgradeJob struct {
+	ID          uint                    `json:"upgrade_job_id" gorm:"primaryKey;column:upgrade_job_id"`
+	CreatedTime time.Time               `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime time.Time               `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	FirmwareID  uint                    `json:"firmware_id" gorm:"column:firmware_id"`
+	Version     string                  `json:"version" gorm:"column:version"`                    // 目标固件版本
+	UpgradeType int                     `json:"firmware_upgrade_type" gorm:"column:upgrade_type"` // 升级类型
+	Status      int                     `json:"status" gorm:"column:status"`                      // 0: 进行中，1: 已结束
+	Devices     []FirmwareUpgradeDevice `json:"devices" gorm:"foreignKey:UpgradeJobID"`
+}
+
+// TableName 指定 FirmwareUpgradeJob 表名为 tb_firmware_upgrade_jobs
+func (j FirmwareUpgradeJob) TableName() string {
+	return "tb_firmware_upgrade_jobs"
+}
+
+// FirmwareUpgradeDevice 升级任务中单台设备的升级状态
+type FirmwareUpgradeDevice struct {
+	ID           uint      `json:"id" gorm:"primaryKey;column:id"`
+	CreatedTime  time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime  time.Time `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	UpgradeJobID uint      `json:"upgrade_job_id" gorm:"index;column:upgrade_job_id"`
+	SN           string    `json:"sn" gorm:"column:sn"`                 // 设备序列号
+	GatewaySN    string    `json:"gateway_sn" gorm:"column:gateway_sn"` // 下发 ota_create 的网关
+	BID          string    `json:"bid" gorm:"index;column:bid"`         // ota_create 的 bid，用于关联进度上报
+	FromVersion  string    `json:"from_version" gorm:"column:from_version"`
+	Status       string    `json:"status" gorm:"column:status"` // 升级状态，取值同 ota_progress 的 status
+	Percent      int       `json:"percent" gorm:"column:percent"`
+	CurrentStep  string    `json:"current_step" gorm:"column:current_step"`
+	Result       int       `json:"result" gorm:"column:result"` // 设备返回码
+	Message      string    `json:"message" gorm:"column:message"`
+}
+
+// TableName 指定 FirmwareUpgradeDevice 表名为 tb_firmware_upgrade_devices
+func (d FirmwareUpgradeDevice) TableName() string {
+	return "tb_firmware_upgrade_devices"
+}
@@ -0,0 +1,35 @@
+package po
+
+import "time"
+
+// HMSAlert 设备健康告警
+//
+// 同一设备、告警码与部件的未确认告警只保留一条，重复上报时更新最后出现时间与次数，
+// 确认后再次上报会产生新的告警记录。网关后续的上报中不再包含该告警时标记为已恢复，再次上报时重新打开
+type HMSAlert struct {
+	ID             uint       `json:"alert_id" gorm:"primaryKey;column:alert_id"`
+	CreatedTime    time.Time  `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime    time.Time  `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	SN             string     `json:"sn" gorm:"index;column:sn"`             // 告警设备序列号
+	GatewaySN      string     `json:"gateway_sn" gorm:"column:gateway_sn"`   // 上报告警的网关序列号
+	Code           string     `json:"code" gorm:"column:code"`               // 告警码
+	Level          int        `json:"level" gorm:"column:level"`             // 告警等级，0: 通知，1: 提醒，2: 警告
+	Module         int        `json:"module" gorm:"column:module"`           // 事件模块
+	DeviceType     string     `json:"device_type" gorm:"column:device_type"` // 设备类型
+	ComponentIndex int        `json:"component_index" gorm:"column:component_index"`
+	SensorIndex    int        `json:"sensor_index" gorm:"column:sensor_index"`
+	InTheSky       bool       `json:"in_the_sky" gorm:"column:in_the_sky"`       // 最近一次上报时是否在空中
+	Imminent       bool       `json:"imminent" gorm:"column:imminent"`           // 是否为紧急告警
+	Count          int        `json:"count" gorm:"column:count"`                 // 上报次数
+	FirstSeenAt    time.Time  `json:"first_seen_at" gorm:"column:first_seen_at"` // 首次出现时间
+	LastSeenAt     time.Time  `json:"last_seen_at" gorm:"column:last_seen_at"`   // 最近出现时间
+	Acknowledged   bool       `json:"acknowledged" gorm:"default:false;column:acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at" gorm:"column:acknowledged_at"`
+	Resolved       bool       `json:"resolved" gorm:"default:false;column:resolved"` // 故障是否已恢复
+	ResolvedAt     *time.Time `json:"resolved_at" gorm:"column:resolved_at"`
+}
+
+// TableName 指定 HMSAlert 表名为 tb_hms_alerts
+func (a HMSAlert) TableName() string {
+	return "tb_hms_alerts"
+}
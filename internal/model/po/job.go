@@ -11,17 +11,67 @@ type Job struct {
 	ID                      uint                                           `json:"job_id" gorm:"primaryKey;column:job_id"`
 	CreatedTime             time.Time                                      `json:"created_time" gorm:"autoCreateTime;column:created_time"`
 	UpdatedTime             time.Time                                      `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
-	State                   int                                            `json:"state" gorm:"default:0;column:state"` // -1: deleted, 0: active
+	State                   int                                            `json:"state" gorm:"default:0;column:state"`   // -1: deleted, 0: active
+	Status                  int                                            `json:"status" gorm:"default:0;column:status"` // 生命周期状态，见 JobStatus*
 	Name                    string                                         `json:"job_name" gorm:"column:job_name"`
 	Description             string                                         `json:"job_description" gorm:"column:job_description"`
 	AreaID                  uint                                           `json:"area_id" gorm:"column:area_id"`
-	ScheduleTime            time.Time                                      `json:"schedule_time" gorm:"column:schedule_time"` // 任务计划执行时间
+	ScheduleTime            time.Time                                      `json:"schedule_time" gorm:"column:schedule_time"` // 任务计划执行时间，周期任务从该时间开始生效
+	CronExpr                string                                         `json:"cron_expr" gorm:"column:cron_expr"`         // 周期任务的 cron 表达式，为空时只在 ScheduleTime 执行一次
+	MissedRunPolicy         string                                         `json:"missed_run_policy" gorm:"default:skip;column:missed_run_policy"`
+	LastRunTime             *time.Time                                     `json:"last_run_time" gorm:"column:last_run_time"` // 最近一次已触发或已跳过的计划执行时间
 	Drones                  datatypes.JSONSlice[JobDronePO]                `json:"drones" gorm:"column:drones"`
 	Waylines                datatypes.JSONSlice[JobWaylinePO]              `json:"waylines" gorm:"column:waylines"`
 	CommandDrones           datatypes.JSONSlice[JobCommandDronePO]         `json:"command_drones" gorm:"column:command_drones"`
 	WaylineGenerationParams datatypes.JSONType[JobWaylineGenerationParams] `json:"wayline_generation_params" gorm:"column:wayline_generation_params"`
 }
 
+// 任务生命周期状态
+const (
+	JobStatusDraft       = 0 // 草稿，没有待执行的计划
+	JobStatusScheduled   = 1 // 等待计划时间到达
+	JobStatusDispatching = 2 // 正在下发到各无人机
+	JobStatusExecuting   = 3 // 有无人机正在执行航线
+	JobStatusPaused      = 4 // 所有执行中的航线均已暂停
+	JobStatusCompleted   = 5 // 本轮航线均已结束，且至少一架执行成功
+	JobStatusFailed      = 6 // 下发失败或本轮航线均执行失败
+	JobStatusCancelled   = 7 // 人工取消或航线被终止
+)
+
+// JobStatusNames 任务状态的名称
+var JobStatusNames = map[int]string{
+	JobStatusDraft:       "草稿",
+	JobStatusScheduled:   "待执行",
+	JobStatusDispatching: "下发中",
+	JobStatusExecuting:   "执行中",
+	JobStatusPaused:      "已暂停",
+	JobStatusCompleted:   "已完成",
+	JobStatusFailed:      "失败",
+	JobStatusCancelled:   "已取消",
+}
+
+// JobStatusHistory 任务状态变化的历史记录
+type JobStatusHistory struct {
+	ID          uint      `json:"id" gorm:"primaryKey;column:history_id"`
+	CreatedTime time.Time `json:"created_time" gorm:"column:created_time"` // 状态变化时间
+	JobID       uint      `json:"job_id" gorm:"column:job_id"`
+	FromStatus  int       `json:"from_status" gorm:"column:from_status"`
+	ToStatus    int       `json:"to_status" gorm:"column:to_status"`
+	Reason      string    `json:"reason" gorm:"column:reason"` // 触发变化的原因
+}
+
+// TableName 指定 JobStatusHistory 表名为 tb_job_status_histories
+func (h JobStatusHistory) TableName() string {
+	return "tb_job_status_histories"
+}
+
+// 停机期间错过的计划执行的补偿策略
+const (
+	JobMissedRunSkip = "skip" // 跳过错过的执行
+	JobMissedRunOnce = "once" // 只补执行一次
+	JobMissedRunAll  = "all"  // 逐次补执行
+)
+
 func (j Job) TableName() string {
 	return "tb_jobs" // 添加 tb_ 前缀到表名
 }
@@ -0,0 +1,101 @@
+package po
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 任务下发执行状态
+const (
+	JobExecutionStatusFailed    = -1 // 下发失败或设备返回错误
+	JobExecutionStatusPreparing = 0  // 已下发 flighttask_prepare，等待应答
+	JobExecutionStatusPrepared  = 1  // 准备成功，已下发 flighttask_execute，等待应答
+	JobExecutionStatusExecuting = 2  // 设备已接受执行指令
+	JobExecutionStatusFinished  = 3  // 航线执行结束
+)
+
+// JobExecution 任务下发到单架无人机的执行记录
+type JobExecution struct {
+	ID            uint      `json:"id" gorm:"primaryKey;column:execution_id"`
+	CreatedTime   time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime   time.Time `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	State         int       `json:"state" gorm:"default:0;column:state"` // -1: deleted, 0: active
+	JobID         uint      `json:"job_id" gorm:"column:job_id"`
+	JobDroneKey   string    `json:"job_drone_key" gorm:"column:job_drone_key"`
+	DroneSN       string    `json:"drone_sn" gorm:"column:drone_sn"`
+	GatewaySN     string    `json:"gateway_sn" gorm:"column:gateway_sn"`
+	WaylineID     uint      `json:"wayline_id" gorm:"column:wayline_id"`
+	FlightID      string    `json:"flight_id" gorm:"unique;column:flight_id"` // 下发给设备的计划 ID，同时作为 MQTT 消息的 bid
+	Status        int       `json:"status" gorm:"default:0;column:status"`
+	PrepareResult *int      `json:"prepare_result" gorm:"column:prepare_result"` // flighttask_prepare 应答的 result
+	ExecuteResult *int      `json:"execute_result" gorm:"column:execute_result"` // flighttask_execute 应答的 result
+	Message       string    `json:"message" gorm:"column:message"`               // 失败原因
+
+	// 以下为 flighttask_progress 上报的最新进度
+	ProgressStatus       string                               `json:"progress_status" gorm:"column:progress_status"`
+	Percent              int                                  `json:"percent" gorm:"column:percent"`
+	CurrentWaypointIndex int                                  `json:"current_waypoint_index" gorm:"column:current_waypoint_index"`
+	BreakPoint           datatypes.JSONType[*JobBreakPointPO] `json:"break_point" gorm:"column:break_point;type:json"`
+}
+
+// TableName 指定 JobExecution 表名为 tb_job_executions
+func (e JobExecution) TableName() string {
+	return "tb_job_executions"
+}
+
+// JobExecutionProgress 航线执行进度的历史记录，每条 flighttask_progress 上报对应一行
+type JobExecutionProgress struct {
+	ID                   uint                                 `json:"id" gorm:"primaryKey;column:progress_id"`
+	CreatedTime          time.Time                            `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	ExecutionID          uint                                 `json:"execution_id" gorm:"column:execution_id"`
+	JobID                uint                                 `json:"job_id" gorm:"column:job_id"`
+	WaylineID            uint                                 `json:"wayline_id" gorm:"column:wayline_id"`
+	FlightID             string                               `json:"flight_id" gorm:"column:flight_id"`
+	Result               int                                  `json:"result" gorm:"column:result"`
+	Status               string                               `json:"status" gorm:"column:status"`
+	CurrentStep          int                                  `json:"current_step" gorm:"column:current_step"`
+	Percent              int                                  `json:"percent" gorm:"column:percent"`
+	CurrentWaypointIndex int                                  `json:"current_waypoint_index" gorm:"column:current_waypoint_index"`
+	WaylineMissionState  int                                  `json:"wayline_mission_state" gorm:"column:wayline_mission_state"`
+	MediaCount           int                                  `json:"media_count" gorm:"column:media_count"`
+	BreakPoint           datatypes.JSONType[*JobBreakPointPO] `json:"break_point" gorm:"column:break_point;type:json"`
+}
+
+// TableName 指定 JobExecutionProgress 表名为 tb_job_execution_progresses
+func (p JobExecutionProgress) TableName() string {
+	return "tb_job_execution_progresses"
+}
+
+// JobExecutionCommand 航线执行过程中下发的控制指令，被拒绝的指令同样记录
+type JobExecutionCommand struct {
+	ID          uint      `json:"id" gorm:"primaryKey;column:command_id"`
+	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	ExecutionID uint      `json:"execution_id" gorm:"index;column:execution_id"`
+	JobID       uint      `json:"job_id" gorm:"column:job_id"`
+	FlightID    string    `json:"flight_id" gorm:"column:flight_id"`
+	DroneSN     string    `json:"drone_sn" gorm:"column:drone_sn"`
+	Action      string    `json:"action" gorm:"column:action"`       // 控制动作，如 pause、resume
+	Method      string    `json:"method" gorm:"column:method"`       // 下发的 services 方法
+	ModeCode    int       `json:"mode_code" gorm:"column:mode_code"` // 下发时无人机的飞行模式
+	Result      int       `json:"result" gorm:"column:result"`       // 0 表示成功，设备拒绝时为设备返回码，未下发时为 -1
+	Message     string    `json:"message" gorm:"column:message"`     // 失败原因
+}
+
+// TableName 指定 JobExecutionCommand 表名为 tb_job_execution_commands
+func (c JobExecutionCommand) TableName() string {
+	return "tb_job_execution_commands"
+}
+
+// JobBreakPointPO 航线断点信息
+type JobBreakPointPO struct {
+	Index        int     `json:"index"`
+	State        int     `json:"state"`
+	Progress     float64 `json:"progress"`
+	WaylineID    int     `json:"wayline_id"`
+	BreakReason  int     `json:"break_reason"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Height       float64 `json:"height"`
+	AttitudeHead float64 `json:"attitude_head"`
+}
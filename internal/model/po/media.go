@@ -0,0 +1,35 @@
+package po
+
+import "time"
+
+// Media 设备上传的照片和视频，按对象键唯一
+type Media struct {
+	ID               uint       `json:"media_id" gorm:"primaryKey;column:media_id"`
+	CreatedTime      time.Time  `json:"created_time" gorm:"autoCreateTime;column:created_time"`
+	UpdatedTime      time.Time  `json:"updated_time" gorm:"autoUpdateTime;column:updated_time"`
+	ObjectKey        string     `json:"object_key" gorm:"uniqueIndex;size:255;column:object_key"`
+	Name             string     `json:"name" gorm:"column:name"`
+	Path             string     `json:"path" gorm:"column:path"` // 设备上的文件路径
+	Fingerprint      string     `json:"fingerprint" gorm:"column:fingerprint"`
+	TinyFingerprint  string     `json:"tiny_fingerprint" gorm:"index;column:tiny_fingerprint"`
+	SubFileType      int        `json:"sub_file_type" gorm:"column:sub_file_type"`
+	IsOriginal       bool       `json:"is_original" gorm:"column:is_original"`
+	DroneModelKey    string     `json:"drone_model_key" gorm:"column:drone_model_key"`
+	PayloadModelKey  string     `json:"payload_model_key" gorm:"column:payload_model_key"`
+	DroneSN          string     `json:"drone_sn" gorm:"index;column:drone_sn"`
+	GatewaySN        string     `json:"gateway_sn" gorm:"column:gateway_sn"`
+	FlightID         string     `json:"flight_id" gorm:"index;column:flight_id"`
+	JobID            uint       `json:"job_id" gorm:"index;column:job_id"`
+	WaylineID        uint       `json:"wayline_id" gorm:"column:wayline_id"`
+	ShootTime        *time.Time `json:"shoot_time" gorm:"column:shoot_time"`
+	Latitude         float64    `json:"latitude" gorm:"column:latitude"`
+	Longitude        float64    `json:"longitude" gorm:"column:longitude"`
+	AbsoluteAltitude float64    `json:"absolute_altitude" gorm:"column:absolute_altitude"`
+	RelativeAltitude float64    `json:"relative_altitude" gorm:"column:relative_altitude"`
+	GimbalYawDegree  float64    `json:"gimbal_yaw_degree" gorm:"column:gimbal_yaw_degree"`
+}
+
+// TableName 指定 Media 表名为 tb_media
+func (m Media) TableName() string {
+	return "tb_media"
+}
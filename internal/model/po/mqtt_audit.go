@@ -0,0 +1,21 @@
+package po
+
+import "time"
+
+// MQTTAudit MQTT 消息审计记录
+type MQTTAudit struct {
+	ID          uint      `json:"id" gorm:"primaryKey;column:audit_id"`
+	CreatedTime time.Time `json:"created_time" gorm:"index;column:created_time"` // 收发时间
+	Direction   string    `json:"direction" gorm:"column:direction"`             // in: 设备上行，out: 平台下行
+	Topic       string    `json:"topic" gorm:"column:topic"`
+	SN          string    `json:"sn" gorm:"index;column:sn"` // 主题中的设备序列号
+	Method      string    `json:"method" gorm:"index;column:method"`
+	TID         string    `json:"tid" gorm:"index;column:tid"`
+	BID         string    `json:"bid" gorm:"column:bid"`
+	Payload     string    `json:"payload" gorm:"type:mediumtext;column:payload"`
+}
+
+// TableName 指定 MQTTAudit 表名为 tb_mqtt_audits
+func (a MQTTAudit) TableName() string {
+	return "tb_mqtt_audits"
+}
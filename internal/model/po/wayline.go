@@ -20,6 +20,7 @@ type Wayline struct {
 	PayloadModelKeys  datatypes.JSONSlice[string]           `json:"payload_model_keys" gorm:"column:payload_model_keys;type:json"`
 	TemplateTypes     datatypes.JSONSlice[int]              `json:"template_types" gorm:"column:template_types;type:json"`
 	S3Key             string                                `json:"s3_key" gorm:"column:s3_key"`
+	Fingerprint       string                                `json:"fingerprint" gorm:"column:fingerprint"` // kmz 文件 MD5
 }
 
 type StartWaylinePoint struct {
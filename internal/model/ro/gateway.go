@@ -0,0 +1,10 @@
+package ro
+
+import "github.com/dronesphere/internal/model/dto"
+
+// Gateway 网关设备（遥控器、机场）实时状态
+type Gateway struct {
+	SN     string `json:"sn"`
+	Status string `json:"online_status"`
+	dto.GatewayOSDData
+}
@@ -0,0 +1,188 @@
+package mqttsub
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// subscription 一条已登记的订阅
+type subscription struct {
+	qos     byte
+	handler mqtt.MessageHandler
+}
+
+// Direction 消息方向
+type Direction string
+
+const (
+	DirectionIn  Direction = "in"  // 设备上行，由订阅回调收到
+	DirectionOut Direction = "out" // 平台下行，由 Publish 发出
+)
+
+// Tap 消息旁路回调，在订阅回调与 Publish 中同步调用，实现方不应阻塞
+type Tap func(direction Direction, topic string, payload []byte)
+
+// Status MQTT 连接状态
+type Status struct {
+	Connected       bool      `json:"connected"`                   // 当前是否已连接
+	Subscriptions   int       `json:"subscriptions"`               // 已登记的订阅数量
+	LastConnectedAt time.Time `json:"last_connected_at,omitempty"` // 最近一次连接成功时间
+	LastLostAt      time.Time `json:"last_lost_at,omitempty"`      // 最近一次连接断开时间
+	LastError       string    `json:"last_error,omitempty"`        // 最近一次断开原因
+}
+
+// Client 带订阅登记的 MQTT 客户端
+//
+// 通过 Client 发起的订阅都会被登记，重连后在 OnConnect 中重放，
+// 避免 broker 重启后订阅丢失、遥测数据静默中断
+type Client struct {
+	mqtt.Client
+	l *slog.Logger
+
+	mu     sync.RWMutex
+	subs   map[string]subscription // 主题 -> 订阅
+	status Status
+	tap    Tap
+}
+
+// New 创建订阅登记器，需在 mqtt.NewClient 之后调用 Wrap 绑定底层客户端
+func New(l *slog.Logger) *Client {
+	return &Client{
+		l:    l,
+		subs: make(map[string]subscription),
+	}
+}
+
+// Wrap 绑定底层 MQTT 客户端
+func (c *Client) Wrap(client mqtt.Client) *Client {
+	c.Client = client
+	return c
+}
+
+// SetTap 设置消息旁路回调，之前与之后发起的订阅都会生效，传入 nil 关闭旁路
+func (c *Client) SetTap(tap Tap) {
+	c.mu.Lock()
+	c.tap = tap
+	c.mu.Unlock()
+}
+
+// emit 调用旁路回调
+func (c *Client) emit(direction Direction, topic string, payload []byte) {
+	c.mu.RLock()
+	tap := c.tap
+	c.mu.RUnlock()
+	if tap != nil {
+		tap(direction, topic, payload)
+	}
+}
+
+// wrap 包装订阅回调，收到消息时先调用旁路回调
+func (c *Client) wrap(callback mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(client mqtt.Client, m mqtt.Message) {
+		c.emit(DirectionIn, m.Topic(), m.Payload())
+		callback(client, m)
+	}
+}
+
+// Publish 发布消息，[]byte 与 string 类型的消息体会经过旁路回调
+func (c *Client) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	switch p := payload.(type) {
+	case []byte:
+		c.emit(DirectionOut, topic, p)
+	case string:
+		c.emit(DirectionOut, topic, []byte(p))
+	}
+	return c.Client.Publish(topic, qos, retained, payload)
+}
+
+// Subscribe 登记并订阅主题，同一主题重复订阅会覆盖之前的处理函数
+func (c *Client) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	callback = c.wrap(callback)
+	c.mu.Lock()
+	c.subs[topic] = subscription{qos: qos, handler: callback}
+	c.mu.Unlock()
+	return c.Client.Subscribe(topic, qos, callback)
+}
+
+// SubscribeMultiple 登记并订阅多个主题
+func (c *Client) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	callback = c.wrap(callback)
+	c.mu.Lock()
+	for topic, qos := range filters {
+		c.subs[topic] = subscription{qos: qos, handler: callback}
+	}
+	c.mu.Unlock()
+	return c.Client.SubscribeMultiple(filters, callback)
+}
+
+// Unsubscribe 取消订阅并移除登记
+func (c *Client) Unsubscribe(topics ...string) mqtt.Token {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.subs, topic)
+	}
+	c.mu.Unlock()
+	return c.Client.Unsubscribe(topics...)
+}
+
+// OnConnect 连接建立时的回调，重放所有已登记的订阅
+//
+// 用于 mqtt.ClientOptions.SetOnConnectHandler，首次连接时没有登记的订阅，不会产生任何请求
+func (c *Client) OnConnect(client mqtt.Client) {
+	c.mu.Lock()
+	c.status.Connected = true
+	c.status.LastConnectedAt = time.Now()
+	subs := make(map[string]subscription, len(c.subs))
+	for topic, sub := range c.subs {
+		subs[topic] = sub
+	}
+	c.mu.Unlock()
+
+	c.l.Info("MQTT 连接成功，重放订阅", slog.Int("count", len(subs)))
+	for topic, sub := range subs {
+		token := client.Subscribe(topic, sub.qos, sub.handler)
+		if token.Wait() && token.Error() != nil {
+			c.l.Error("重放订阅失败", slog.Any("topic", topic), slog.Any("err", token.Error()))
+			continue
+		}
+		c.l.Debug("重放订阅成功", slog.Any("topic", topic))
+	}
+}
+
+// OnConnectionLost 连接断开时的回调，记录断开原因
+func (c *Client) OnConnectionLost(_ mqtt.Client, err error) {
+	c.mu.Lock()
+	c.status.Connected = false
+	c.status.LastLostAt = time.Now()
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	c.mu.Unlock()
+	c.l.Error("MQTT 连接断开", slog.Any("err", err))
+}
+
+// Status 返回当前连接状态
+func (c *Client) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := c.status
+	s.Subscriptions = len(c.subs)
+	if c.Client != nil {
+		s.Connected = c.Client.IsConnectionOpen()
+	}
+	return s
+}
+
+// Topics 返回已登记的订阅主题
+func (c *Client) Topics() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	topics := make([]string, 0, len(c.subs))
+	for topic := range c.subs {
+		topics = append(topics, topic)
+	}
+	return topics
+}
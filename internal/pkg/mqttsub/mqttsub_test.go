@@ -0,0 +1,95 @@
+package mqttsub
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"testing"
+
+	"github.com/dronesphere/tools/mock_tool"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestOnConnectReplaysSubscriptions(t *testing.T) {
+	mq := mock_tool.NewMockMQTTClient()
+	c := New(slog.Default()).Wrap(mq)
+	noop := func(mqtt.Client, mqtt.Message) {}
+
+	c.Subscribe("thing/product/+/osd", 0, noop)
+	c.SubscribeMultiple(map[string]byte{"thing/product/+/state": 1}, noop)
+	c.Subscribe("thing/product/+/events", 1, noop)
+	c.Unsubscribe("thing/product/+/events")
+	// 清空初次订阅产生的请求
+	for len(mq.SubscribeCh) > 0 {
+		<-mq.SubscribeCh
+	}
+	<-mq.UnsubCh
+
+	c.OnConnectionLost(mq, errors.New("broker restarted"))
+	c.OnConnect(mq)
+
+	var got []string
+	for len(mq.SubscribeCh) > 0 {
+		got = append(got, (<-mq.SubscribeCh).Topics...)
+	}
+	sort.Strings(got)
+	want := []string{"thing/product/+/osd", "thing/product/+/state"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("replayed topics = %v, want %v", got, want)
+	}
+
+	s := c.Status()
+	if s.Subscriptions != 2 || s.LastError != "broker restarted" || s.LastLostAt.IsZero() || s.LastConnectedAt.IsZero() {
+		t.Errorf("unexpected status: %+v", s)
+	}
+}
+
+// fakeMessage 测试用的 MQTT 消息
+type fakeMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m fakeMessage) Topic() string   { return m.topic }
+func (m fakeMessage) Payload() []byte { return m.payload }
+
+func TestTapSeesPublishedAndReceivedMessages(t *testing.T) {
+	mq := mock_tool.NewMockMQTTClient()
+	c := New(slog.Default()).Wrap(mq)
+
+	type tapped struct {
+		direction Direction
+		topic     string
+		payload   string
+	}
+	var got []tapped
+	received := 0
+	c.Subscribe("thing/product/+/events", 0, func(mqtt.Client, mqtt.Message) { received++ })
+	// 旁路在订阅之后设置，同样要对之前的订阅生效
+	c.SetTap(func(direction Direction, topic string, payload []byte) {
+		got = append(got, tapped{direction, topic, string(payload)})
+	})
+
+	c.Publish("thing/product/gw/services", 0, false, []byte(`{"method":"a"}`))
+	<-mq.PublishCh
+	c.subs["thing/product/+/events"].handler(mq, fakeMessage{topic: "thing/product/gw/events", payload: []byte(`{"method":"b"}`)})
+
+	want := []tapped{
+		{DirectionOut, "thing/product/gw/services", `{"method":"a"}`},
+		{DirectionIn, "thing/product/gw/events", `{"method":"b"}`},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("tapped = %+v, want %+v", got, want)
+	}
+	if received != 1 {
+		t.Errorf("handler called %d times, want 1", received)
+	}
+
+	c.SetTap(nil)
+	c.Publish("thing/product/gw/services", 0, false, []byte(`{}`))
+	<-mq.PublishCh
+	if len(got) != len(want) {
+		t.Errorf("tap still called after SetTap(nil)")
+	}
+}
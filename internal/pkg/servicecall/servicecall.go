@@ -0,0 +1,231 @@
+package servicecall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// ReplyTopic 所有网关 services_reply 的订阅主题
+const ReplyTopic = "thing/product/+/services_reply"
+
+// PropertySetReplyTopic 所有设备 property/set_reply 的订阅主题
+const PropertySetReplyTopic = "thing/product/+/property/set_reply"
+
+// DefaultTimeout 等待设备应答的默认超时时间
+const DefaultTimeout = 10 * time.Second
+
+// ErrTimeout 在超时时间内未收到设备应答
+var ErrTimeout = errors.New("等待设备应答超时")
+
+// ResultError 设备应答的 result 非 0 时返回的错误
+type ResultError struct {
+	Method string // 调用的方法名
+	Result int    // 设备返回码
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("设备拒绝执行 %s，返回码 %d", e.Method, e.Result)
+}
+
+// Client 通过 MQTT 调用设备 services 方法或设置设备属性，并等待 tid 匹配的应答
+type Client struct {
+	mqtt    mqtt.Client
+	l       *slog.Logger
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]chan []byte // tid -> 等待应答的通道，传递原始应答消息
+}
+
+// New 创建服务调用客户端，timeout 为 0 时使用 DefaultTimeout
+func New(mqtt mqtt.Client, l *slog.Logger, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		mqtt:    mqtt,
+		l:       l,
+		timeout: timeout,
+		pending: make(map[string]chan []byte),
+	}
+}
+
+// Subscribe 订阅所有网关的 services_reply 与 property/set_reply 主题
+func (c *Client) Subscribe() error {
+	for _, topic := range []string{ReplyTopic, PropertySetReplyTopic} {
+		token := c.mqtt.Subscribe(topic, 1, func(_ mqtt.Client, m mqtt.Message) {
+			c.HandleReply(m.Payload())
+		})
+		if token.Wait() && token.Error() != nil {
+			c.l.Error("服务应答主题订阅失败", slog.Any("topic", topic), slog.Any("error", token.Error()))
+			return token.Error()
+		}
+		c.l.Info("服务应答主题订阅成功", slog.Any("topic", topic))
+	}
+	return nil
+}
+
+// HandleReply 处理一条应答消息，返回是否匹配到等待中的调用
+func (c *Client) HandleReply(payload []byte) bool {
+	var reply dto.MessageCommon
+	if err := json.Unmarshal(payload, &reply); err != nil {
+		c.l.Error("解析服务应答消息失败", slog.Any("payload", string(payload)), slog.Any("error", err))
+		return false
+	}
+
+	c.mu.Lock()
+	ch, ok := c.pending[reply.TID]
+	if ok {
+		delete(c.pending, reply.TID)
+	}
+	c.mu.Unlock()
+	if !ok {
+		c.l.Debug("忽略未匹配的服务应答", slog.Any("tid", reply.TID), slog.Any("method", reply.Method))
+		return false
+	}
+
+	// 通道带缓冲且只会写入一次，不会阻塞
+	ch <- payload
+	return true
+}
+
+// Call 以新的 tid/bid 调用网关的 services 方法并等待应答
+func (c *Client) Call(ctx context.Context, gatewaySN, method string, data any) (*dto.ServicesReply, error) {
+	return c.CallWithBID(ctx, gatewaySN, uuid.New().String(), method, data)
+}
+
+// CallWithBID 使用指定的 bid 调用网关的 services 方法并等待应答
+// 同一业务的多次调用（如 flighttask_prepare 与 flighttask_execute）可共用 bid
+func (c *Client) CallWithBID(ctx context.Context, gatewaySN, bid, method string, data any) (*dto.ServicesReply, error) {
+	req := dto.ServicesRequest{
+		MessageCommon: dto.MessageCommon{
+			TID:       uuid.New().String(),
+			BID:       bid,
+			Method:    method,
+			Timestamp: time.Now().UnixMilli(),
+		},
+		Data: data,
+	}
+	topic := fmt.Sprintf("thing/product/%s/services", gatewaySN)
+	payload, err := c.roundTrip(ctx, topic, method, req.TID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply dto.ServicesReply
+	if err := json.Unmarshal(payload, &reply); err != nil {
+		return nil, fmt.Errorf("解析 %s 应答失败: %w", method, err)
+	}
+	c.l.Info("收到服务应答", slog.String("method", method), slog.String("tid", req.TID), slog.Int("result", reply.Data.Result))
+	if reply.Data.Result != 0 {
+		return &reply, &ResultError{Method: method, Result: reply.Data.Result}
+	}
+	return &reply, nil
+}
+
+// SetProperty 通过 property/set 设置设备属性并等待 property/set_reply
+// 返回每个属性的设置结果，0 表示成功
+func (c *Client) SetProperty(ctx context.Context, sn string, props map[string]any) (map[string]int, error) {
+	req := dto.ServicesRequest{
+		MessageCommon: dto.MessageCommon{
+			TID:       uuid.New().String(),
+			BID:       uuid.New().String(),
+			Timestamp: time.Now().UnixMilli(),
+		},
+		Data: props,
+	}
+	topic := fmt.Sprintf("thing/product/%s/property/set", sn)
+	payload, err := c.roundTrip(ctx, topic, "property/set", req.TID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &reply); err != nil {
+		return nil, fmt.Errorf("解析 property/set 应答失败: %w", err)
+	}
+	results := make(map[string]int, len(props))
+	for key := range props {
+		raw, ok := reply.Data[key]
+		if !ok {
+			// 设备未返回该属性的结果，视为失败
+			results[key] = -1
+			continue
+		}
+		results[key] = propertyResult(raw)
+	}
+	c.l.Info("收到属性设置应答", slog.String("sn", sn), slog.Any("results", results))
+	return results, nil
+}
+
+// propertyResult 解析单个属性的设置结果
+// 简单属性的结果形如 {"result": 0}，结构体属性的结果按子字段给出，取第一个非 0 的结果
+func propertyResult(raw json.RawMessage) int {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return -1
+	}
+	if r, ok := fields["result"]; ok {
+		var result int
+		if err := json.Unmarshal(r, &result); err != nil {
+			return -1
+		}
+		return result
+	}
+	for _, sub := range fields {
+		if result := propertyResult(sub); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// roundTrip 发布请求并等待 tid 匹配的应答，返回原始应答消息
+func (c *Client) roundTrip(ctx context.Context, topic, method, tid string, req any) ([]byte, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 %s 请求失败: %w", method, err)
+	}
+
+	ch := make(chan []byte, 1)
+	c.mu.Lock()
+	c.pending[tid] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, tid)
+		c.mu.Unlock()
+	}()
+
+	c.l.Info("发送服务调用", slog.String("topic", topic), slog.String("payload", string(payload)))
+	token := c.mqtt.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(c.timeout) {
+		return nil, fmt.Errorf("发送 %s 消息超时", method)
+	}
+	if token.Error() != nil {
+		return nil, fmt.Errorf("发送 %s 消息失败: %w", method, token.Error())
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case reply := <-ch:
+		return reply, nil
+	case <-timer.C:
+		c.l.Error("等待服务应答超时", slog.String("method", method), slog.String("tid", tid), slog.String("topic", topic))
+		return nil, fmt.Errorf("%s: %w", method, ErrTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
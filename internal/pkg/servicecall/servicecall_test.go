@@ -0,0 +1,146 @@
+package servicecall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/tools/mock_tool"
+)
+
+// replyTo 读取客户端发布的请求，并按请求的 tid 构造应答
+func replyTo(t *testing.T, mq *mock_tool.MQTTClient, tidOverride string, result int) []byte {
+	t.Helper()
+	packet := <-mq.PublishCh
+	var req dto.ServicesRequest
+	if err := json.Unmarshal(packet.Payload, &req); err != nil {
+		t.Fatalf("解析请求失败: %v", err)
+	}
+	reply := dto.ServicesReply{MessageCommon: req.MessageCommon}
+	if tidOverride != "" {
+		reply.TID = tidOverride
+	}
+	reply.Data.Result = result
+	b, _ := json.Marshal(reply)
+	return b
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    int
+		wantErr   bool
+		wantCode  int
+		wrongTID  bool
+		wantMatch bool
+	}{
+		{name: "设备接受", result: 0, wantMatch: true},
+		{name: "设备拒绝", result: 314000, wantErr: true, wantCode: 314000, wantMatch: true},
+		{name: "tid 不匹配", result: 0, wantErr: true, wrongTID: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := mock_tool.NewMockMQTTClient()
+			c := New(mq, slog.Default(), 200*time.Millisecond)
+
+			errCh := make(chan error, 1)
+			go func() {
+				_, err := c.Call(context.Background(), "GW-SN", "live_start_push", nil)
+				errCh <- err
+			}()
+
+			tid := ""
+			if tt.wrongTID {
+				tid = "other"
+			}
+			matched := c.HandleReply(replyTo(t, mq, tid, tt.result))
+			if matched != tt.wantMatch {
+				t.Fatalf("HandleReply() = %v, want %v", matched, tt.wantMatch)
+			}
+
+			err := <-errCh
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantCode != 0 {
+				var re *ResultError
+				if !errors.As(err, &re) || re.Result != tt.wantCode {
+					t.Fatalf("Call() error = %v, want ResultError %d", err, tt.wantCode)
+				}
+			}
+			if tt.wrongTID && !errors.Is(err, ErrTimeout) {
+				t.Fatalf("Call() error = %v, want ErrTimeout", err)
+			}
+		})
+	}
+}
+
+func TestCallPublishTopic(t *testing.T) {
+	mq := mock_tool.NewMockMQTTClient()
+	c := New(mq, slog.Default(), 50*time.Millisecond)
+
+	go func() {
+		_, _ = c.CallWithBID(context.Background(), "GW-SN", "flight-1", "flighttask_execute", nil)
+	}()
+
+	packet := <-mq.PublishCh
+	if want := fmt.Sprintf("thing/product/%s/services", "GW-SN"); packet.TopicName != want {
+		t.Errorf("topic = %s, want %s", packet.TopicName, want)
+	}
+	var req dto.ServicesRequest
+	if err := json.Unmarshal(packet.Payload, &req); err != nil {
+		t.Fatalf("解析请求失败: %v", err)
+	}
+	if req.BID != "flight-1" || req.Method != "flighttask_execute" || req.TID == "" {
+		t.Errorf("unexpected request: %+v", req.MessageCommon)
+	}
+}
+
+func TestSetProperty(t *testing.T) {
+	mq := mock_tool.NewMockMQTTClient()
+	c := New(mq, slog.Default(), 200*time.Millisecond)
+
+	type result struct {
+		res map[string]int
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		res, err := c.SetProperty(context.Background(), "DRONE-SN", map[string]any{
+			"height_limit":       120,
+			"obstacle_avoidance": map[string]int{"horizon": 1, "upside": 0},
+			"night_lights_state": 1,
+		})
+		resCh <- result{res, err}
+	}()
+
+	packet := <-mq.PublishCh
+	if want := "thing/product/DRONE-SN/property/set"; packet.TopicName != want {
+		t.Fatalf("topic = %s, want %s", packet.TopicName, want)
+	}
+	var req dto.MessageCommon
+	if err := json.Unmarshal(packet.Payload, &req); err != nil {
+		t.Fatalf("解析请求失败: %v", err)
+	}
+	reply := fmt.Sprintf(`{"tid":%q,"bid":%q,"data":{"height_limit":{"result":0},"obstacle_avoidance":{"horizon":{"result":0},"upside":{"result":327}}}}`, req.TID, req.BID)
+	if !c.HandleReply([]byte(reply)) {
+		t.Fatal("HandleReply() = false, want true")
+	}
+
+	got := <-resCh
+	if got.err != nil {
+		t.Fatalf("SetProperty() error = %v", got.err)
+	}
+	want := map[string]int{"height_limit": 0, "obstacle_avoidance": 327, "night_lights_state": -1}
+	for k, v := range want {
+		if got.res[k] != v {
+			t.Errorf("result[%s] = %d, want %d", k, got.res[k], v)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/dronesphere/internal/model/po"
+	"gorm.io/gorm"
+)
+
+type DeviceBindingDefaultRepo struct {
+	tx *gorm.DB
+	l  *slog.Logger
+}
+
+func NewDeviceBindingDefaultRepo(db *gorm.DB, l *slog.Logger) *DeviceBindingDefaultRepo {
+	return &DeviceBindingDefaultRepo{
+		tx: db,
+		l:  l,
+	}
+}
+
+func (r *DeviceBindingDefaultRepo) Save(ctx context.Context, binding *po.DeviceBinding) error {
+	if err := r.tx.WithContext(ctx).Save(binding).Error; err != nil {
+		r.l.Error("保存设备绑定关系失败", slog.Any("sn", binding.SN), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// SelectBySN 查询设备的绑定记录，设备没有记录时返回 nil
+func (r *DeviceBindingDefaultRepo) SelectBySN(ctx context.Context, sn string) (*po.DeviceBinding, error) {
+	var binding po.DeviceBinding
+	err := r.tx.WithContext(ctx).Where("sn = ?", sn).First(&binding).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.l.Error("查询设备绑定关系失败", slog.Any("sn", sn), slog.Any("err", err))
+		return nil, err
+	}
+	return &binding, nil
+}
+
+// SelectAll 查询设备绑定关系，statuses 为空时返回全部
+func (r *DeviceBindingDefaultRepo) SelectAll(ctx context.Context, statuses ...int) ([]po.DeviceBinding, error) {
+	var bindings []po.DeviceBinding
+	tx := r.tx.WithContext(ctx)
+	if len(statuses) > 0 {
+		tx = tx.Where("status IN ?", statuses)
+	}
+	if err := tx.Order("binding_id DESC").Find(&bindings).Error; err != nil {
+		r.l.Error("查询设备绑定关系失败", slog.Any("statuses", statuses), slog.Any("err", err))
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// SeedBound 将已登记但没有绑定记录的无人机与网关标记为已绑定，返回新增的记录数
+//
+// 绑定审批上线前登记的设备视为已信任，避免升级后全部进入待审批
+func (r *DeviceBindingDefaultRepo) SeedBound(ctx context.Context, workspaceID string) (int, error) {
+	tx := r.tx.WithContext(ctx)
+	existing := tx.Model(&po.DeviceBinding{}).Select("sn")
+	now := time.Now()
+	var bindings []po.DeviceBinding
+
+	var drones []po.Drone
+	if err := tx.Preload("DroneModel").Where("state = 0 AND sn <> '' AND sn NOT IN (?)", existing).
+		Find(&drones).Error; err != nil {
+		r.l.Error("查询待初始化绑定的无人机失败", slog.Any("err", err))
+		return 0, err
+	}
+	for _, d := range drones {
+		bindings = append(bindings, po.DeviceBinding{
+			SN:          d.SN,
+			WorkspaceID: workspaceID,
+			Domain:      strconv.Itoa(d.DroneModel.Domain),
+			Type:        d.DroneModel.Type,
+			SubType:     d.DroneModel.SubType,
+			Nickname:    d.Callsign,
+			Status:      po.DeviceBindingStatusBound,
+			BoundAt:     &now,
+		})
+	}
+
+	var gateways []po.Gateway
+	if err := tx.Preload("GatewayModel").Where("state = 0 AND sn <> '' AND sn NOT IN (?)", existing).
+		Find(&gateways).Error; err != nil {
+		r.l.Error("查询待初始化绑定的网关失败", slog.Any("err", err))
+		return 0, err
+	}
+	for _, g := range gateways {
+		bindings = append(bindings, po.DeviceBinding{
+			SN:          g.SN,
+			WorkspaceID: workspaceID,
+			Domain:      strconv.Itoa(g.GatewayModel.Domain),
+			Type:        g.GatewayModel.Type,
+			SubType:     g.GatewayModel.SubType,
+			Nickname:    g.Callsign,
+			Status:      po.DeviceBindingStatusBound,
+			BoundAt:     &now,
+		})
+	}
+
+	if len(bindings) == 0 {
+		return 0, nil
+	}
+	if err := tx.Create(&bindings).Error; err != nil {
+		r.l.Error("初始化设备绑定关系失败", slog.Any("err", err))
+		return 0, err
+	}
+	return len(bindings), nil
+}
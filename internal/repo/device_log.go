@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/dronesphere/internal/model/po"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+// deviceLogURLExpiry 日志下载链接有效期
+const deviceLogURLExpiry = time.Hour
+
+type DeviceLogDefaultRepo struct {
+	tx     *gorm.DB
+	s3     *minio.Client
+	l      *slog.Logger
+	bucket string
+}
+
+func NewDeviceLogDefaultRepo(db *gorm.DB, s3 *minio.Client, l *slog.Logger) *DeviceLogDefaultRepo {
+	return &DeviceLogDefaultRepo{
+		tx:     db,
+		s3:     s3,
+		l:      l,
+		bucket: "logs",
+	}
+}
+
+// Bucket 设备日志存放的桶，下发 fileupload_start 时告知设备
+func (r *DeviceLogDefaultRepo) Bucket() string {
+	return r.bucket
+}
+
+func (r *DeviceLogDefaultRepo) Save(ctx context.Context, log *po.DeviceLog) error {
+	if err := r.tx.WithContext(ctx).Save(log).Error; err != nil {
+		r.l.Error("保存设备日志记录失败", slog.Any("key", log.S3Key), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (r *DeviceLogDefaultRepo) SelectByS3Key(ctx context.Context, key string) (*po.DeviceLog, error) {
+	var log po.DeviceLog
+	if err := r.tx.WithContext(ctx).Where("s3_key = ?", key).First(&log).Error; err != nil {
+		r.l.Error("查询设备日志记录失败", slog.Any("key", key), slog.Any("err", err))
+		return nil, err
+	}
+	return &log, nil
+}
+
+// SelectByDroneSN 获取无人机的日志上传记录，按创建时间倒序
+func (r *DeviceLogDefaultRepo) SelectByDroneSN(ctx context.Context, droneSN string) ([]po.DeviceLog, error) {
+	var logs []po.DeviceLog
+	if err := r.tx.WithContext(ctx).Where("drone_sn = ?", droneSN).Order("created_time DESC").Find(&logs).Error; err != nil {
+		r.l.Error("查询设备日志记录失败", slog.Any("drone_sn", droneSN), slog.Any("err", err))
+		return nil, err
+	}
+	return logs, nil
+}
+
+// PresignDownloadURL 生成日志对象的临时下载链接
+func (r *DeviceLogDefaultRepo) PresignDownloadURL(ctx context.Context, key string) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", "attachment; filename=\""+path.Base(key)+"\"")
+	u, err := r.s3.PresignedGetObject(ctx, r.bucket, key, deviceLogURLExpiry, params)
+	if err != nil {
+		r.l.Error("生成日志下载链接失败", slog.Any("key", key), slog.Any("err", err))
+		return "", err
+	}
+	return u.String(), nil
+}
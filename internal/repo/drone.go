@@ -2,10 +2,12 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/dronesphere/internal/model/dto"
@@ -228,6 +230,66 @@ func (r *DroneDefaultRepo) SaveState(ctx context.Context, state ro.Drone) error
 	return nil
 }
 
+// FetchRawStateBySN 获取无人机实时状态的原始字段，键为顶层属性名
+func (r *DroneDefaultRepo) FetchRawStateBySN(ctx context.Context, sn string) (map[string]json.RawMessage, error) {
+	t, err := r.rds.JSONGet(ctx, r.rdsPrefix+sn, ".").Result()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if t == "" {
+		return fields, nil
+	}
+	if err := json.Unmarshal([]byte(t), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// MergeState 按字段合并无人机实时状态
+//
+// 只覆盖 fields 中出现的顶层属性，未出现的属性保持原值，避免 OSD 与 state 消息互相覆盖
+func (r *DroneDefaultRepo) MergeState(ctx context.Context, sn string, fields map[string]json.RawMessage) error {
+	droneKey := r.rdsPrefix + sn
+	// 文档不存在时先创建空文档，NX 模式不会覆盖已有数据
+	if err := r.rds.JSONSetMode(ctx, droneKey, "$", "{}", "NX").Err(); err != nil && !errors.Is(err, redis.Nil) {
+		r.l.Error("初始化实时状态失败", slog.Any("droneKey", droneKey), slog.Any("err", err))
+		return err
+	}
+	pipe := r.rds.TxPipeline()
+	for k, v := range fields {
+		pipe.JSONSet(ctx, droneKey, statePath(k), []byte(v))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.l.Error("合并实时状态失败", slog.Any("droneKey", droneKey), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// statePathEscaper 转义 JSONPath 字符串中的反斜杠与双引号
+var statePathEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// statePath 生成顶层属性的 JSONPath
+//
+// 使用方括号写法，DJI 负载属性的键（如 "39-0-7"）不是合法的点号标识符
+func statePath(key string) string {
+	return `$["` + statePathEscaper.Replace(key) + `"]`
+}
+
+// SaveOffline 将无人机标记为离线，实时数据保留最后一次上报的内容，仅修改在线状态
+func (r *DroneDefaultRepo) SaveOffline(ctx context.Context, sn string, lastOnlineAt time.Time) error {
+	droneKey := r.rdsPrefix + sn
+	if n, err := r.rds.Exists(ctx, droneKey).Result(); err == nil && n > 0 {
+		if err := r.rds.JSONSet(ctx, droneKey, "$.online_status", `"`+ro.DroneStatusOffline+`"`).Err(); err != nil {
+			r.l.Error("更新在线状态失败", slog.Any("droneKey", droneKey), slog.Any("err", err))
+			return err
+		}
+	}
+	return r.tx.WithContext(ctx).Model(&po.Drone{}).Where("sn = ?", sn).
+		Update("last_online_at", lastOnlineAt).Error
+}
+
 // SelectAllByID 根据 ID 列出所有无人机
 func (r *DroneDefaultRepo) SelectAllByID(ctx context.Context, ids []uint) ([]entity.Drone, error) {
 	var drones []entity.Drone
@@ -276,11 +338,26 @@ func (r *DroneDefaultRepo) FetchDroneModelOptions(ctx context.Context) ([]dto.Dr
 	return models, nil
 }
 
+// SaveGatewaySNByDroneSN 在 Redis 中记录无人机当前挂载的网关SN
+// 网关上报拓扑时写入，下发 services 指令时通过 FetchGatewaySNByDroneSN 读取
+func (r *DroneDefaultRepo) SaveGatewaySNByDroneSN(ctx context.Context, droneSN, gatewaySN string) error {
+	key := "topology:" + droneSN
+	if err := r.rds.Set(ctx, key, gatewaySN, 0).Err(); err != nil {
+		r.l.Error("保存无人机网关SN失败", slog.String("drone_sn", droneSN), slog.String("gw_sn", gatewaySN), slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// DeleteGatewaySNByDroneSN 删除无人机挂载的网关SN，无人机脱离拓扑时调用
+func (r *DroneDefaultRepo) DeleteGatewaySNByDroneSN(ctx context.Context, droneSN string) error {
+	return r.rds.Del(ctx, "topology:"+droneSN).Err()
+}
+
 // FetchGatewaySNByDroneSN 从 Redis 获取无人机关联的网关SN
-// 注意：这个实现假设网关SN直接作为字符串存储在Redis中，键为 "gateway_of_drone:{droneSN}"
-// 你需要根据实际情况调整键名和数据解析逻辑
+// 网关SN直接作为字符串存储在Redis中，键为 "topology:{droneSN}"
 func (r *DroneDefaultRepo) FetchGatewaySNByDroneSN(ctx context.Context, droneSN string) (string, error) {
-	key := "topology:" + droneSN // 示例键名，请根据实际情况修改
+	key := "topology:" + droneSN
 	gwSN, err := r.rds.Get(ctx, key).Result()
 	if err == redis.Nil {
 		r.l.Warn("Redis中未找到无人机的网关SN", slog.String("drone_sn", droneSN), slog.String("redis_key", key))
@@ -0,0 +1,19 @@
+package repo
+
+import "testing"
+
+func TestStatePath(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"mode_code", `$["mode_code"]`},
+		{"39-0-7", `$["39-0-7"]`},
+		{`a"b\c`, `$["a\"b\\c"]`},
+	}
+	for _, tt := range tests {
+		if got := statePath(tt.key); got != tt.want {
+			t.Errorf("statePath(%q) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/dronesphere/internal/model/po"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+// firmwareURLExpiry 固件下载链接有效期，设备可能在升级排队后才开始下载
+const firmwareURLExpiry = 24 * time.Hour
+
+type FirmwareDefaultRepo struct {
+	tx     *gorm.DB
+	s3     *minio.Client
+	l      *slog.Logger
+	bucket string
+}
+
+func NewFirmwareDefaultRepo(db *gorm.DB, s3 *minio.Client, l *slog.Logger) *FirmwareDefaultRepo {
+	return &FirmwareDefaultRepo{
+		tx:     db,
+		s3:     s3,
+		l:      l,
+		bucket: "firmware",
+	}
+}
+
+// SaveFirmware 上传固件文件到对象存储并登记固件包，上传时同步计算文件 MD5
+func (r *FirmwareDefaultRepo) SaveFirmware(ctx context.Context, firmware *po.Firmware, file io.Reader, size int64) error {
+	firmware.S3Key = firmware.ModelKey + "/" + uuid.New().String() + path.Ext(firmware.FileName)
+
+	h := md5.New()
+	info, err := r.s3.PutObject(ctx, r.bucket, firmware.S3Key, io.TeeReader(file, h), size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		r.l.Error("上传固件文件失败", slog.Any("key", firmware.S3Key), slog.Any("err", err))
+		return err
+	}
+	firmware.FileSize = info.Size
+	firmware.MD5 = hex.EncodeToString(h.Sum(nil))
+
+	if err := r.tx.WithContext(ctx).Create(firmware).Error; err != nil {
+		r.l.Error("保存固件信息失败", slog.Any("firmware", firmware), slog.Any("err", err))
+		return err
+	}
+	r.l.Info("固件已登记", slog.Any("model_key", firmware.ModelKey), slog.Any("version", firmware.Version))
+	return nil
+}
+
+// SelectFirmwares 获取固件列表，modelKey 为空时返回全部型号
+func (r *FirmwareDefaultRepo) SelectFirmwares(ctx context.Context, modelKey string) ([]po.Firmware, error) {
+	var firmwares []po.Firmware
+	query := r.tx.WithContext(ctx).Where("state = 0")
+	if modelKey != "" {
+		query = query.Where("model_key = ?", modelKey)
+	}
+	if err := query.Order("created_time DESC").Find(&firmwares).Error; err != nil {
+		r.l.Error("查询固件列表失败", slog.Any("model_key", modelKey), slog.Any("err", err))
+		return nil, err
+	}
+	return firmwares, nil
+}
+
+func (r *FirmwareDefaultRepo) SelectFirmwareByID(ctx context.Context, id uint) (*po.Firmware, error) {
+	var firmware po.Firmware
+	if err := r.tx.WithContext(ctx).Where("state = 0 AND firmware_id = ?", id).First(&firmware).Error; err != nil {
+		r.l.Error("查询固件失败", slog.Any("id", id), slog.Any("err", err))
+		return nil, err
+	}
+	return &firmware, nil
+}
+
+// DeleteFirmwareByID 软删除固件并移除对象存储中的文件
+func (r *FirmwareDefaultRepo) DeleteFirmwareByID(ctx context.Context, id uint) error {
+	var firmware po.Firmware
+	result := r.tx.WithContext(ctx).
+		Model(&po.Firmware{}).
+		Where("firmware_id = ? AND state = 0", id).
+		First(&firmware).
+		Update("state", -1)
+	if result.Error != nil {
+		r.l.Error("删除固件失败", slog.Any("id", id), slog.Any("err", result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	// 数据库记录已删除，文件删除失败只记录日志
+	if err := r.s3.RemoveObject(ctx, r.bucket, firmware.S3Key, minio.RemoveObjectOptions{}); err != nil {
+		r.l.Error("删除固件文件失败", slog.Any("id", id), slog.Any("key", firmware.S3Key), slog.Any("err", err))
+	}
+	return nil
+}
+
+// SaveUpgradeJob 保存升级任务，同时保存其中的设备记录
+func (r *FirmwareDefaultRepo) SaveUpgradeJob(ctx context.Context, job *po.FirmwareUpgradeJob) error {
+	if err := r.tx.WithContext(ctx).Save(job).Error; err != nil {
+		r.l.Error("保存固件升级任务失败", slog.Any("job", job), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (r *FirmwareDefaultRepo) SaveUpgradeDevice(ctx context.Context, device *po.FirmwareUpgradeDevice) error {
+	if err := r.tx.WithContext(ctx).Save(device).Error; err != nil {
+		r.l.Error("保存设备升级状态失败", slog.Any("sn", device.SN), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// SelectUpgradeJobs 获取升级任务列表，按创建时间倒序
+func (r *FirmwareDefaultRepo) SelectUpgradeJobs(ctx context.Context) ([]po.FirmwareUpgradeJob, error) {
+	var jobs []po.FirmwareUpgradeJob
+	if err := r.tx.WithContext(ctx).Preload("Devices").Order("created_time DESC").Find(&jobs).Error; err != nil {
+		r.l.Error("查询固件升级任务失败", slog.Any("err", err))
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *FirmwareDefaultRepo) SelectUpgradeJobByID(ctx context.Context, id uint) (*po.FirmwareUpgradeJob, error) {
+	var job po.FirmwareUpgradeJob
+	if err := r.tx.WithContext(ctx).Preload("Devices").Where("upgrade_job_id = ?", id).First(&job).Error; err != nil {
+		r.l.Error("查询固件升级任务失败", slog.Any("id", id), slog.Any("err", err))
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SelectUpgradeDevicesByBID 获取同一次 ota_create 下发的设备升级记录
+func (r *FirmwareDefaultRepo) SelectUpgradeDevicesByBID(ctx context.Context, bid string) ([]po.FirmwareUpgradeDevice, error) {
+	var devices []po.FirmwareUpgradeDevice
+	if err := r.tx.WithContext(ctx).Where("bid = ?", bid).Find(&devices).Error; err != nil {
+		r.l.Error("查询设备升级状态失败", slog.Any("bid", bid), slog.Any("err", err))
+		return nil, err
+	}
+	return devices, nil
+}
+
+// UpdateUpgradeJobStatus 更新升级任务整体状态
+func (r *FirmwareDefaultRepo) UpdateUpgradeJobStatus(ctx context.Context, id uint, status int) error {
+	return r.tx.WithContext(ctx).Model(&po.FirmwareUpgradeJob{}).
+		Where("upgrade_job_id = ?", id).
+		Update("status", status).Error
+}
+
+// PresignDownloadURL 生成固件文件的临时下载链接
+func (r *FirmwareDefaultRepo) PresignDownloadURL(ctx context.Context, key string) (string, error) {
+	u, err := r.s3.PresignedGetObject(ctx, r.bucket, key, firmwareURLExpiry, nil)
+	if err != nil {
+		r.l.Error("生成固件下载链接失败", slog.Any("key", key), slog.Any("err", err))
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -2,11 +2,15 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
+	"github.com/bytedance/sonic"
 	"github.com/dronesphere/internal/model/entity"
 	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/model/ro"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
@@ -31,21 +35,33 @@ type (
 		// 关联关系管理方法
 		AddDroneRelation(ctx context.Context, gatewaySN, droneSN string) error
 		RemoveDroneRelation(ctx context.Context, gatewaySN, droneSN string) error
+		// EndDroneRelations 结束网关下除 keepSN 外所有生效中的关联关系，返回被结束关联的无人机 SN
+		EndDroneRelations(ctx context.Context, gatewaySN, keepSN string) ([]string, error)
 		GetConnectedDrones(ctx context.Context, gatewaySN string) ([]po.Drone, error)
+
+		// 实时状态相关方法
+		FetchStateBySN(ctx context.Context, sn string) (ro.Gateway, error)
+		SaveState(ctx context.Context, state ro.Gateway) error
+		// SaveOffline 将网关标记为离线，并记录最后在线时间
+		SaveOffline(ctx context.Context, sn string, lastOnlineAt time.Time) error
 	}
 
 	// GatewayDefaultRepo 网关设备仓储默认实现
 	GatewayDefaultRepo struct {
-		tx *gorm.DB
-		l  *slog.Logger
+		tx        *gorm.DB
+		rds       *redis.Client
+		l         *slog.Logger
+		rdsPrefix string
 	}
 )
 
 // NewGatewayRepo 创建网关设备仓储实例
-func NewGatewayRepo(tx *gorm.DB, l *slog.Logger) GatewayRepo {
+func NewGatewayRepo(tx *gorm.DB, rds *redis.Client, l *slog.Logger) GatewayRepo {
 	return &GatewayDefaultRepo{
-		tx: tx,
-		l:  l,
+		tx:        tx,
+		rds:       rds,
+		l:         l,
+		rdsPrefix: "gateway:",
 	}
 }
 
@@ -190,12 +206,24 @@ func (r *GatewayDefaultRepo) AddDroneRelation(ctx context.Context, gatewaySN, dr
 		return err
 	}
 
+	// 已存在生效中的关联时不重复创建
+	var count int64
+	if err := r.tx.WithContext(ctx).Model(&po.GatewayDroneRelation{}).
+		Where("gateway_sn = ? AND drone_sn = ? AND state = 0", gatewaySN, droneSN).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
 	// 创建关联记录
 	relation := &po.GatewayDroneRelation{
-		GatewayID: gateway.ID,
-		GatewaySN: gateway.SN,
-		DroneID:   drone.ID,
-		DroneSN:   drone.SN,
+		GatewayID:   gateway.ID,
+		GatewaySN:   gateway.SN,
+		DroneID:     drone.ID,
+		DroneSN:     drone.SN,
+		ConnectedAt: time.Now(),
 	}
 
 	return r.tx.WithContext(ctx).Create(relation).Error
@@ -208,12 +236,41 @@ func (r *GatewayDefaultRepo) RemoveDroneRelation(ctx context.Context, gatewaySN,
 		Delete(&po.GatewayDroneRelation{}).Error
 }
 
+// EndDroneRelations 结束网关下除 keepSN 外所有生效中的关联关系
+// 关联记录保留用于追溯，仅将 state 置为 -1
+func (r *GatewayDefaultRepo) EndDroneRelations(ctx context.Context, gatewaySN, keepSN string) ([]string, error) {
+	var relations []po.GatewayDroneRelation
+	query := r.tx.WithContext(ctx).Where("gateway_sn = ? AND state = 0", gatewaySN)
+	if keepSN != "" {
+		query = query.Where("drone_sn <> ?", keepSN)
+	}
+	if err := query.Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	if len(relations) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(relations))
+	droneSNs := make([]string, 0, len(relations))
+	for _, rel := range relations {
+		ids = append(ids, rel.ID)
+		droneSNs = append(droneSNs, rel.DroneSN)
+	}
+	if err := r.tx.WithContext(ctx).Model(&po.GatewayDroneRelation{}).
+		Where("id IN ?", ids).Update("state", -1).Error; err != nil {
+		r.l.Error("结束网关与无人机关联关系失败", "error", err, "gateway_sn", gatewaySN)
+		return nil, err
+	}
+	return droneSNs, nil
+}
+
 // GetConnectedDrones 获取连接到指定网关的所有无人机
 func (r *GatewayDefaultRepo) GetConnectedDrones(ctx context.Context, gatewaySN string) ([]po.Drone, error) {
 	var drones []po.Drone
 	err := r.tx.WithContext(ctx).
 		Preload("DroneModel"). // 预加载无人机型号信息
-		Joins("JOIN tb_gateway_drone_relations ON tb_gateway_drone_relations.drone_id = tb_drones.id").
+		Joins("JOIN tb_gateway_drone_relations ON tb_gateway_drone_relations.drone_id = tb_drones.drone_id").
 		Where("tb_gateway_drone_relations.gateway_sn = ? AND tb_gateway_drone_relations.state = 0", gatewaySN).
 		Find(&drones).Error
 	if err != nil {
@@ -222,3 +279,47 @@ func (r *GatewayDefaultRepo) GetConnectedDrones(ctx context.Context, gatewaySN s
 	}
 	return drones, nil
 }
+
+// FetchStateBySN 根据SN获取网关实时状态
+func (r *GatewayDefaultRepo) FetchStateBySN(ctx context.Context, sn string) (ro.Gateway, error) {
+	var rg ro.Gateway
+	t, err := r.rds.JSONGet(ctx, r.rdsPrefix+sn, ".").Result()
+	if err != nil {
+		r.l.Error("网关实时数据获取失败", slog.Any("sn", sn), slog.Any("err", err))
+		return rg, err
+	}
+	if t == "" {
+		return rg, errors.New(ErrNoRTData)
+	}
+	if err := sonic.UnmarshalString(t, &rg); err != nil {
+		r.l.Error("网关实时数据解析失败", slog.Any("sn", sn), slog.Any("err", err))
+		return rg, err
+	}
+	return rg, nil
+}
+
+// SaveState 保存网关实时状态
+func (r *GatewayDefaultRepo) SaveState(ctx context.Context, state ro.Gateway) error {
+	key := r.rdsPrefix + state.SN
+	if err := r.rds.JSONSet(ctx, key, ".", state).Err(); err != nil {
+		r.l.Error("保存网关实时状态失败", slog.Any("key", key), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// SaveOffline 将网关标记为离线，实时数据保留最后一次上报的内容，仅修改在线状态
+func (r *GatewayDefaultRepo) SaveOffline(ctx context.Context, sn string, lastOnlineAt time.Time) error {
+	key := r.rdsPrefix + sn
+	if n, err := r.rds.Exists(ctx, key).Result(); err == nil && n > 0 {
+		if err := r.rds.JSONSet(ctx, key, "$.online_status", `"`+ro.DroneStatusOffline+`"`).Err(); err != nil {
+			r.l.Error("更新网关在线状态失败", slog.Any("key", key), slog.Any("err", err))
+			return err
+		}
+	}
+	return r.tx.WithContext(ctx).Model(&po.Gateway{}).Where("sn = ?", sn).
+		Updates(map[string]interface{}{
+			"status":         0,
+			"last_online_at": lastOnlineAt,
+		}).Error
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/dronesphere/internal/model/po"
+	"gorm.io/gorm"
+)
+
+type HMSDefaultRepo struct {
+	tx *gorm.DB
+	l  *slog.Logger
+}
+
+func NewHMSDefaultRepo(db *gorm.DB, l *slog.Logger) *HMSDefaultRepo {
+	return &HMSDefaultRepo{
+		tx: db,
+		l:  l,
+	}
+}
+
+// SaveAlert 保存一次告警上报
+// 存在同一设备、告警码与部件的未确认告警时更新最后出现时间并累加次数，已恢复的告警重新打开，否则新建告警
+func (r *HMSDefaultRepo) SaveAlert(ctx context.Context, alert *po.HMSAlert) error {
+	var existing po.HMSAlert
+	err := r.tx.WithContext(ctx).
+		Where("sn = ? AND code = ? AND component_index = ? AND sensor_index = ? AND acknowledged = ?",
+			alert.SN, alert.Code, alert.ComponentIndex, alert.SensorIndex, false).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		alert.Count = 1
+		alert.FirstSeenAt = alert.LastSeenAt
+		if err := r.tx.WithContext(ctx).Create(alert).Error; err != nil {
+			r.l.Error("创建 HMS 告警失败", slog.Any("sn", alert.SN), slog.Any("code", alert.Code), slog.Any("err", err))
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"level":        alert.Level,
+		"module":       alert.Module,
+		"gateway_sn":   alert.GatewaySN,
+		"in_the_sky":   alert.InTheSky,
+		"imminent":     alert.Imminent,
+		"last_seen_at": alert.LastSeenAt,
+		"count":        gorm.Expr("count + 1"),
+		"resolved":     false,
+		"resolved_at":  nil,
+	}
+	alert.ID = existing.ID
+	return r.tx.WithContext(ctx).Model(&existing).Updates(updates).Error
+}
+
+// SelectAlertsBySN 获取设备的告警，activeOnly 为 true 时只返回未确认且未恢复的告警
+func (r *HMSDefaultRepo) SelectAlertsBySN(ctx context.Context, sn string, activeOnly bool) ([]po.HMSAlert, error) {
+	var alerts []po.HMSAlert
+	query := r.tx.WithContext(ctx).Where("sn = ?", sn)
+	if activeOnly {
+		query = query.Where("acknowledged = ? AND resolved = ?", false, false)
+	}
+	if err := query.Order("last_seen_at DESC").Find(&alerts).Error; err != nil {
+		r.l.Error("查询 HMS 告警失败", slog.Any("sn", sn), slog.Any("err", err))
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// SelectCurrentAlertsBySN 获取设备当前仍在上报的告警，不区分是否已确认
+func (r *HMSDefaultRepo) SelectCurrentAlertsBySN(ctx context.Context, sn string) ([]po.HMSAlert, error) {
+	var alerts []po.HMSAlert
+	if err := r.tx.WithContext(ctx).Where("sn = ? AND resolved = ?", sn, false).
+		Order("last_seen_at DESC").Find(&alerts).Error; err != nil {
+		r.l.Error("查询 HMS 告警失败", slog.Any("sn", sn), slog.Any("err", err))
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ResolveAlerts 将网关上报的、不在 keep 中的未恢复告警标记为已恢复，返回恢复的数量
+func (r *HMSDefaultRepo) ResolveAlerts(ctx context.Context, gatewaySN string, keep []uint) (int64, error) {
+	query := r.tx.WithContext(ctx).Model(&po.HMSAlert{}).Where("gateway_sn = ? AND resolved = ?", gatewaySN, false)
+	if len(keep) > 0 {
+		query = query.Where("alert_id NOT IN ?", keep)
+	}
+	res := query.Updates(map[string]interface{}{
+		"resolved":    true,
+		"resolved_at": time.Now(),
+	})
+	if res.Error != nil {
+		r.l.Error("恢复 HMS 告警失败", slog.Any("gatewaySN", gatewaySN), slog.Any("err", res.Error))
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
+
+// AcknowledgeAlerts 确认设备的告警，ids 为空时确认该设备的全部未确认告警，返回确认的数量
+func (r *HMSDefaultRepo) AcknowledgeAlerts(ctx context.Context, sn string, ids []uint) (int64, error) {
+	query := r.tx.WithContext(ctx).Model(&po.HMSAlert{}).Where("sn = ? AND acknowledged = ?", sn, false)
+	if len(ids) > 0 {
+		query = query.Where("alert_id IN ?", ids)
+	}
+	res := query.Updates(map[string]interface{}{
+		"acknowledged":    true,
+		"acknowledged_at": time.Now(),
+	})
+	if res.Error != nil {
+		r.l.Error("确认 HMS 告警失败", slog.Any("sn", sn), slog.Any("err", res.Error))
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
+
+// CountActiveBySNs 统计多个设备未确认且未恢复的告警数量
+func (r *HMSDefaultRepo) CountActiveBySNs(ctx context.Context, sns []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(sns))
+	if len(sns) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		SN    string
+		Count int64
+	}
+	if err := r.tx.WithContext(ctx).Model(&po.HMSAlert{}).
+		Select("sn, COUNT(*) AS count").
+		Where("sn IN ? AND acknowledged = ? AND resolved = ?", sns, false, false).
+		Group("sn").
+		Scan(&rows).Error; err != nil {
+		r.l.Error("统计 HMS 告警失败", slog.Any("err", err))
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.SN] = row.Count
+	}
+	return counts, nil
+}
@@ -2,7 +2,14 @@ package repo
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
 	"log/slog"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/dronesphere/internal/model/dto"
@@ -71,15 +78,20 @@ func (j *JobDefaultRepo) SelectByID(ctx context.Context, id uint) (*po.Job, erro
 	return &jobPO, nil
 }
 
-func (j *JobDefaultRepo) SelectAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string) ([]po.Job, error) {
+func (j *JobDefaultRepo) SelectAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string, statuses []int) ([]po.Job, error) {
 	j.l.Info("查询所有任务",
 		slog.Any("jobName", jobName),
 		slog.Any("areaName", areaName),
 		slog.Any("scheduleTimeStart", scheduleTimeStart),
-		slog.Any("scheduleTimeEnd", scheduleTimeEnd))
+		slog.Any("scheduleTimeEnd", scheduleTimeEnd),
+		slog.Any("statuses", statuses))
 
 	query := j.tx.WithContext(ctx).Where("state = 0")
 
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
 	if jobName != "" {
 		query = query.Where("job_name LIKE ?", "%"+jobName+"%")
 	}
@@ -163,6 +175,13 @@ const contentType = "application/zip"
 func (j *JobDefaultRepo) SaveWayline(ctx context.Context, wayline po.Wayline, kmzFile string) (*po.Wayline, error) {
 	// 使用uuid生成唯一的 S3Key
 	wayline.S3Key = uuid.New().String() + ".kmz"
+	// 计算 kmz 文件 MD5，下发航线任务时作为文件签名
+	fingerprint, err := fileMD5(kmzFile)
+	if err != nil {
+		j.l.Error("Failed to compute wayline fingerprint", slog.Any("err", err))
+		return nil, err
+	}
+	wayline.Fingerprint = fingerprint
 	// 上传到 S3
 	inf, err := j.s3.FPutObject(ctx, "kmz", wayline.S3Key, kmzFile, minio.PutObjectOptions{
 		ContentType: contentType,
@@ -195,3 +214,205 @@ func (j *JobDefaultRepo) SaveWaylineAndKmzKey(ctx context.Context, wayline po.Wa
 	j.l.Info("Saved wayline to database", slog.Any("wayline", wayline))
 	return &wayline, nil
 }
+
+// FetchWaylineFingerprint 获取航线文件的 MD5 签名
+// 历史航线未记录签名时，退化为读取 S3 对象的 ETag
+func (j *JobDefaultRepo) FetchWaylineFingerprint(ctx context.Context, wayline po.Wayline) (string, error) {
+	if wayline.Fingerprint != "" {
+		return wayline.Fingerprint, nil
+	}
+	info, err := j.s3.StatObject(ctx, "kmz", wayline.S3Key, minio.StatObjectOptions{})
+	if err != nil {
+		j.l.Error("获取航线文件信息失败", slog.Any("s3Key", wayline.S3Key), slog.Any("err", err))
+		return "", err
+	}
+	return strings.Trim(info.ETag, "\""), nil
+}
+
+func (j *JobDefaultRepo) SaveExecution(ctx context.Context, execution *po.JobExecution) error {
+	if err := j.tx.WithContext(ctx).Save(execution).Error; err != nil {
+		j.l.Error("保存任务执行记录失败", slog.Any("execution", execution), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (j *JobDefaultRepo) SelectExecutionByFlightID(ctx context.Context, flightID string) (*po.JobExecution, error) {
+	var execution po.JobExecution
+	if err := j.tx.WithContext(ctx).
+		Where("state = 0 AND flight_id = ?", flightID).
+		First(&execution).Error; err != nil {
+		j.l.Error("获取任务执行记录失败", slog.Any("flightID", flightID), slog.Any("err", err))
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (j *JobDefaultRepo) SelectExecutionsByJobID(ctx context.Context, jobID uint) ([]po.JobExecution, error) {
+	var executions []po.JobExecution
+	if err := j.tx.WithContext(ctx).
+		Where("state = 0 AND job_id = ?", jobID).
+		Order("execution_id DESC").
+		Find(&executions).Error; err != nil {
+		j.l.Error("获取任务执行记录失败", slog.Any("jobID", jobID), slog.Any("err", err))
+		return nil, err
+	}
+	return executions, nil
+}
+
+// fileMD5 计算文件的 MD5 十六进制字符串
+func fileMD5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (j *JobDefaultRepo) SaveExecutionProgress(ctx context.Context, progress *po.JobExecutionProgress) error {
+	if err := j.tx.WithContext(ctx).Create(progress).Error; err != nil {
+		j.l.Error("保存任务执行进度失败", slog.Any("progress", progress), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (j *JobDefaultRepo) SelectExecutionProgresses(ctx context.Context, executionID uint) ([]po.JobExecutionProgress, error) {
+	var progresses []po.JobExecutionProgress
+	if err := j.tx.WithContext(ctx).
+		Where("execution_id = ?", executionID).
+		Order("progress_id ASC").
+		Find(&progresses).Error; err != nil {
+		j.l.Error("获取任务执行进度失败", slog.Any("executionID", executionID), slog.Any("err", err))
+		return nil, err
+	}
+	return progresses, nil
+}
+
+// SelectActiveExecutionByDroneSN 获取无人机正在执行的航线任务
+func (j *JobDefaultRepo) SelectActiveExecutionByDroneSN(ctx context.Context, droneSN string) (*po.JobExecution, error) {
+	var execution po.JobExecution
+	if err := j.tx.WithContext(ctx).
+		Where("state = 0 AND drone_sn = ? AND status IN ?", droneSN,
+			[]int{po.JobExecutionStatusPrepared, po.JobExecutionStatusExecuting}).
+		Order("execution_id DESC").
+		First(&execution).Error; err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (j *JobDefaultRepo) SaveExecutionCommand(ctx context.Context, command *po.JobExecutionCommand) error {
+	if err := j.tx.WithContext(ctx).Create(command).Error; err != nil {
+		j.l.Error("保存航线控制指令失败", slog.Any("command", command), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (j *JobDefaultRepo) SelectExecutionCommands(ctx context.Context, executionID uint) ([]po.JobExecutionCommand, error) {
+	var commands []po.JobExecutionCommand
+	if err := j.tx.WithContext(ctx).
+		Where("execution_id = ?", executionID).
+		Order("command_id ASC").
+		Find(&commands).Error; err != nil {
+		j.l.Error("获取航线控制指令失败", slog.Any("executionID", executionID), slog.Any("err", err))
+		return nil, err
+	}
+	return commands, nil
+}
+
+// SelectScheduledJobs 获取需要调度的任务：周期任务，以及计划时间尚未触发的一次性任务，已取消的任务除外
+func (j *JobDefaultRepo) SelectScheduledJobs(ctx context.Context) ([]po.Job, error) {
+	var jobs []po.Job
+	if err := j.tx.WithContext(ctx).
+		Where("state = 0 AND status <> ?", po.JobStatusCancelled).
+		Where("cron_expr <> '' OR last_run_time IS NULL OR last_run_time < schedule_time").
+		Find(&jobs).Error; err != nil {
+		j.l.Error("查询待调度任务失败", slog.Any("err", err))
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// UpdateJobSchedule 更新任务的 cron 表达式与补偿策略
+func (j *JobDefaultRepo) UpdateJobSchedule(ctx context.Context, id uint, cronExpr, missedRunPolicy string) error {
+	if err := j.tx.WithContext(ctx).Model(&po.Job{}).
+		Where("job_id = ?", id).
+		Updates(map[string]any{"cron_expr": cronExpr, "missed_run_policy": missedRunPolicy}).Error; err != nil {
+		j.l.Error("更新任务调度配置失败", slog.Any("jobID", id), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// UpdateJobLastRunTime 记录任务最近一次已处理的计划执行时间，只会向后推进
+func (j *JobDefaultRepo) UpdateJobLastRunTime(ctx context.Context, id uint, t time.Time) error {
+	if err := j.tx.WithContext(ctx).Model(&po.Job{}).
+		Where("job_id = ?", id).
+		Where("last_run_time IS NULL OR last_run_time < ?", t).
+		Update("last_run_time", t).Error; err != nil {
+		j.l.Error("更新任务执行时间失败", slog.Any("jobID", id), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// UpdateJobStatus 在任务仍处于 history.FromStatus 时切换到 history.ToStatus 并写入状态历史
+// 任务状态已被其他调用修改时返回 false
+func (j *JobDefaultRepo) UpdateJobStatus(ctx context.Context, history *po.JobStatusHistory) (bool, error) {
+	updated := false
+	err := j.tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&po.Job{}).
+			Where("job_id = ? AND status = ?", history.JobID, history.FromStatus).
+			Update("status", history.ToStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		j.l.Error("更新任务状态失败", slog.Any("history", history), slog.Any("err", err))
+		return false, err
+	}
+	return updated, nil
+}
+
+// SelectJobStatusHistories 获取任务的状态历史，按时间先后排序
+func (j *JobDefaultRepo) SelectJobStatusHistories(ctx context.Context, jobID uint) ([]po.JobStatusHistory, error) {
+	var histories []po.JobStatusHistory
+	if err := j.tx.WithContext(ctx).
+		Where("job_id = ?", jobID).
+		Order("history_id ASC").
+		Find(&histories).Error; err != nil {
+		j.l.Error("查询任务状态历史失败", slog.Any("jobID", jobID), slog.Any("err", err))
+		return nil, err
+	}
+	return histories, nil
+}
+
+// SelectLastJobStatusHistory 获取任务最近一次进入指定状态的记录，不存在时返回 nil
+func (j *JobDefaultRepo) SelectLastJobStatusHistory(ctx context.Context, jobID uint, toStatus int) (*po.JobStatusHistory, error) {
+	var history po.JobStatusHistory
+	err := j.tx.WithContext(ctx).
+		Where("job_id = ? AND to_status = ?", jobID, toStatus).
+		Order("history_id DESC").
+		First(&history).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+// mediaURLExpiry 媒体文件下载链接有效期
+const mediaURLExpiry = time.Hour
+
+type MediaDefaultRepo struct {
+	tx     *gorm.DB
+	s3     *minio.Client
+	l      *slog.Logger
+	bucket string
+}
+
+func NewMediaDefaultRepo(db *gorm.DB, s3 *minio.Client, l *slog.Logger) *MediaDefaultRepo {
+	return &MediaDefaultRepo{
+		tx:     db,
+		s3:     s3,
+		l:      l,
+		bucket: "media",
+	}
+}
+
+// Bucket 媒体文件存放的桶
+func (r *MediaDefaultRepo) Bucket() string {
+	return r.bucket
+}
+
+// Save 保存媒体文件，同一对象键重复上报时更新已有记录
+func (r *MediaDefaultRepo) Save(ctx context.Context, media *po.Media) error {
+	var existing po.Media
+	err := r.tx.WithContext(ctx).Where("object_key = ?", media.ObjectKey).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		media.ID = existing.ID
+		media.CreatedTime = existing.CreatedTime
+	}
+	if err := r.tx.WithContext(ctx).Save(media).Error; err != nil {
+		r.l.Error("保存媒体文件失败", slog.Any("key", media.ObjectKey), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// SelectExistingTinyFingerprints 返回已存在的文件指纹
+func (r *MediaDefaultRepo) SelectExistingTinyFingerprints(ctx context.Context, fingerprints []string) ([]string, error) {
+	existing := make([]string, 0)
+	if len(fingerprints) == 0 {
+		return existing, nil
+	}
+	if err := r.tx.WithContext(ctx).Model(&po.Media{}).
+		Where("tiny_fingerprint IN ?", fingerprints).
+		Pluck("tiny_fingerprint", &existing).Error; err != nil {
+		r.l.Error("查询媒体文件指纹失败", slog.Any("err", err))
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *MediaDefaultRepo) SelectByID(ctx context.Context, id uint) (*po.Media, error) {
+	var media po.Media
+	if err := r.tx.WithContext(ctx).Where("media_id = ?", id).First(&media).Error; err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// SelectAll 按任务、航线、无人机或计划 ID 查询媒体文件，按拍摄时间倒序
+func (r *MediaDefaultRepo) SelectAll(ctx context.Context, query dto.MediaQuery) ([]po.Media, int64, error) {
+	var (
+		medias []po.Media
+		total  int64
+	)
+	db := r.tx.WithContext(ctx).Model(&po.Media{})
+	if query.JobID > 0 {
+		db = db.Where("job_id = ?", query.JobID)
+	}
+	if query.WaylineID > 0 {
+		db = db.Where("wayline_id = ?", query.WaylineID)
+	}
+	if query.DroneSN != "" {
+		db = db.Where("drone_sn = ?", query.DroneSN)
+	}
+	if query.FlightID != "" {
+		db = db.Where("flight_id = ?", query.FlightID)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if query.Page > 0 && query.PageSize > 0 {
+		db = db.Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize)
+	}
+	if err := db.Order("shoot_time DESC, media_id DESC").Find(&medias).Error; err != nil {
+		r.l.Error("查询媒体文件失败", slog.Any("query", query), slog.Any("err", err))
+		return nil, 0, err
+	}
+	return medias, total, nil
+}
+
+// PresignDownloadURL 生成媒体文件的临时下载链接
+func (r *MediaDefaultRepo) PresignDownloadURL(ctx context.Context, key string) (string, error) {
+	u, err := r.s3.PresignedGetObject(ctx, r.bucket, key, mediaURLExpiry, nil)
+	if err != nil {
+		r.l.Error("生成媒体文件下载链接失败", slog.Any("key", key), slog.Any("err", err))
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"gorm.io/gorm"
+)
+
+// mqttAuditMaxPageSize 审计记录单次查询的最大条数
+const mqttAuditMaxPageSize = 500
+
+type MQTTAuditDefaultRepo struct {
+	tx *gorm.DB
+	l  *slog.Logger
+}
+
+func NewMQTTAuditDefaultRepo(db *gorm.DB, l *slog.Logger) *MQTTAuditDefaultRepo {
+	return &MQTTAuditDefaultRepo{
+		tx: db,
+		l:  l,
+	}
+}
+
+func (r *MQTTAuditDefaultRepo) BatchCreate(ctx context.Context, audits []po.MQTTAudit) error {
+	if err := r.tx.WithContext(ctx).CreateInBatches(audits, 100).Error; err != nil {
+		r.l.Error("保存 MQTT 审计记录失败", slog.Int("count", len(audits)), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// SelectAll 按设备、方法、方向、tid/bid 与时间范围查询审计记录，按时间正序，便于还原一次交互
+func (r *MQTTAuditDefaultRepo) SelectAll(ctx context.Context, query dto.MQTTAuditQuery) ([]po.MQTTAudit, int64, error) {
+	var (
+		audits []po.MQTTAudit
+		total  int64
+	)
+	db := r.tx.WithContext(ctx).Model(&po.MQTTAudit{})
+	if query.SN != "" {
+		db = db.Where("sn = ?", query.SN)
+	}
+	if query.Method != "" {
+		db = db.Where("method = ?", query.Method)
+	}
+	if query.Direction != "" {
+		db = db.Where("direction = ?", query.Direction)
+	}
+	if query.TID != "" {
+		db = db.Where("tid = ? OR bid = ?", query.TID, query.TID)
+	}
+	if query.Start > 0 {
+		db = db.Where("created_time >= ?", time.UnixMilli(query.Start))
+	}
+	if query.End > 0 {
+		db = db.Where("created_time <= ?", time.UnixMilli(query.End))
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > mqttAuditMaxPageSize {
+		query.PageSize = mqttAuditMaxPageSize
+	}
+	db = db.Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize)
+	if err := db.Order("created_time ASC, audit_id ASC").Find(&audits).Error; err != nil {
+		r.l.Error("查询 MQTT 审计记录失败", slog.Any("query", query), slog.Any("err", err))
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
+
+// DeleteBefore 删除早于指定时间的审计记录
+func (r *MQTTAuditDefaultRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	res := r.tx.WithContext(ctx).Where("created_time < ?", before).Delete(&po.MQTTAudit{})
+	if res.Error != nil {
+		r.l.Error("清理过期 MQTT 审计记录失败", slog.Any("err", res.Error))
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
+
+// DeleteOverflow 只保留最新的 keep 条审计记录
+func (r *MQTTAuditDefaultRepo) DeleteOverflow(ctx context.Context, keep int64) (int64, error) {
+	var boundary po.MQTTAudit
+	err := r.tx.WithContext(ctx).Order("audit_id DESC").Offset(int(keep)).Limit(1).Find(&boundary).Error
+	if err != nil {
+		r.l.Error("查询 MQTT 审计记录边界失败", slog.Any("err", err))
+		return 0, err
+	}
+	if boundary.ID == 0 {
+		return 0, nil
+	}
+	res := r.tx.WithContext(ctx).Where("audit_id <= ?", boundary.ID).Delete(&po.MQTTAudit{})
+	if res.Error != nil {
+		r.l.Error("清理超量 MQTT 审计记录失败", slog.Any("err", res.Error))
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
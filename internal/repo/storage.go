@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// stsDuration 临时凭证有效期
+const stsDuration = time.Hour
+
+// stsPolicy 临时凭证的访问策略，只允许向指定桶上传文件
+const stsPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [{
+		"Effect": "Allow",
+		"Action": ["s3:PutObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"],
+		"Resource": ["arn:aws:s3:::%s/*"]
+	}]
+}`
+
+// StorageDefaultRepo 对象存储临时凭证签发
+//
+// 设备和 Pilot 直传对象存储时不下发长期密钥，而是通过 MinIO AssumeRole 签发限定桶的临时凭证
+type StorageDefaultRepo struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	region    string
+	l         *slog.Logger
+}
+
+func NewStorageDefaultRepo(endpoint, accessKeyID, secretAccessKey string, l *slog.Logger) *StorageDefaultRepo {
+	return &StorageDefaultRepo{
+		endpoint:  endpoint,
+		accessKey: accessKeyID,
+		secretKey: secretAccessKey,
+		region:    "us-east-1",
+		l:         l,
+	}
+}
+
+// IssueCredentials 签发只能写入 bucket 的临时凭证
+func (r *StorageDefaultRepo) IssueCredentials(ctx context.Context, bucket string) (dto.StorageConfig, error) {
+	creds, err := credentials.NewSTSAssumeRole(r.endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey:       r.accessKey,
+		SecretKey:       r.secretKey,
+		Policy:          fmt.Sprintf(stsPolicy, bucket),
+		Location:        r.region,
+		DurationSeconds: int(stsDuration.Seconds()),
+	})
+	if err != nil {
+		return dto.StorageConfig{}, err
+	}
+	v, err := creds.Get()
+	if err != nil {
+		r.l.Error("签发临时凭证失败", slog.Any("bucket", bucket), slog.Any("err", err))
+		return dto.StorageConfig{}, err
+	}
+
+	expire := int64(stsDuration.Seconds())
+	if !v.Expiration.IsZero() {
+		expire = int64(time.Until(v.Expiration).Seconds())
+	}
+	return dto.StorageConfig{
+		Endpoint: r.endpoint,
+		Bucket:   bucket,
+		Region:   r.region,
+		Provider: "minio",
+		Credentials: dto.StorageCredentials{
+			AccessKeyID:     v.AccessKeyID,
+			AccessKeySecret: v.SecretAccessKey,
+			Expire:          expire,
+			SecurityToken:   v.SessionToken,
+		},
+	}, nil
+}
@@ -4,15 +4,23 @@ import "log/slog"
 
 // Container 服务容器，用于依赖注入
 type Container struct {
-	User    UserSvc
-	Drone   DroneSvc
-	Area    AreaSvc
-	Wayline WaylineSvc
-	Job     JobSvc
-	Model   ModelSvc
-	Gateway GatewaySvc
-	Result  ResultSvc // 添加结果服务
-	l       *slog.Logger
+	User     UserSvc
+	Drone    DroneSvc
+	Area     AreaSvc
+	Wayline  WaylineSvc
+	Job      JobSvc
+	Model    ModelSvc
+	Gateway  GatewaySvc
+	Result   ResultSvc        // 添加结果服务
+	HMS      HMSSvc           // 设备健康告警服务
+	Firmware FirmwareSvc      // 固件升级服务
+	Log      DeviceLogSvc     // 设备日志服务
+	Media    MediaSvc         // 媒体文件服务
+	DRC      DRCSvc           // 指令飞行会话
+	Binding  DeviceBindingSvc // 设备绑定
+	Audit    MQTTAuditSvc     // MQTT 消息审计
+	Schedule JobSchedulerSvc  // 任务定时调度
+	l        *slog.Logger
 }
 
 // NewContainer 创建服务容器
@@ -25,17 +33,33 @@ func NewContainer(
 	model ModelSvc,
 	gateway GatewaySvc,
 	result ResultSvc, // 添加结果服务
+	hms HMSSvc,
+	firmware FirmwareSvc,
+	log DeviceLogSvc,
+	media MediaSvc,
+	drc DRCSvc,
+	binding DeviceBindingSvc,
+	audit MQTTAuditSvc,
+	schedule JobSchedulerSvc,
 	l *slog.Logger,
 ) *Container {
 	return &Container{
-		User:    user,
-		Drone:   drone,
-		Area:    area,
-		Wayline: wayline,
-		Job:     job,
-		Model:   model,
-		Gateway: gateway,
-		Result:  result, // 添加结果服务
-		l:       l,
+		User:     user,
+		Drone:    drone,
+		Area:     area,
+		Wayline:  wayline,
+		Job:      job,
+		Model:    model,
+		Gateway:  gateway,
+		Result:   result, // 添加结果服务
+		HMS:      hms,
+		Firmware: firmware,
+		Log:      log,
+		Media:    media,
+		DRC:      drc,
+		Binding:  binding,
+		Audit:    audit,
+		Schedule: schedule,
+		l:        l,
 	}
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+)
+
+// ErrBindingPending 设备已提交绑定，等待管理员审批
+var ErrBindingPending = errors.New("设备等待管理员审批")
+
+type DeviceBindingSvc interface {
+	// IsBound 判断设备是否已绑定到工作空间，遥测处理时调用，只读内存缓存
+	IsBound(sn string) bool
+	// Observe 设备在拓扑中出现时调用，未知设备生成待审批记录，返回设备是否已绑定
+	Observe(ctx context.Context, sn string, topo dto.ProductTopo) bool
+	// Bind 处理 Pilot 发起的绑定，已审批的设备直接绑定，否则进入待审批并返回 ErrBindingPending
+	Bind(ctx context.Context, params dto.DeviceBindingParams) error
+	Unbind(ctx context.Context, sn string) error
+	// Approve 管理员审批通过，设备重新上线后开始接收遥测
+	Approve(ctx context.Context, sn string) error
+	Reject(ctx context.Context, sn string) error
+	List(ctx context.Context, statuses ...int) ([]po.DeviceBinding, error)
+	// ListDevices 按 Pilot 设备列表格式返回已绑定或未绑定的设备
+	ListDevices(ctx context.Context, bound bool) ([]dto.BoundDevice, error)
+}
+
+type DeviceBindingRepo interface {
+	Save(ctx context.Context, binding *po.DeviceBinding) error
+	SelectBySN(ctx context.Context, sn string) (*po.DeviceBinding, error)
+	SelectAll(ctx context.Context, statuses ...int) ([]po.DeviceBinding, error)
+	// SeedBound 将已登记但没有绑定记录的设备标记为已绑定
+	SeedBound(ctx context.Context, workspaceID string) (int, error)
+}
+
+type DeviceBindingImpl struct {
+	repo        DeviceBindingRepo
+	workspaceID string
+	l           *slog.Logger
+
+	mu       sync.RWMutex
+	statuses map[string]int // 设备 SN -> 绑定状态
+}
+
+// NewDeviceBindingImpl 创建设备绑定服务，并从数据库加载已有的绑定状态
+//
+// 已登记在无人机表与网关表中、但还没有绑定记录的设备会先被标记为已绑定
+func NewDeviceBindingImpl(repo DeviceBindingRepo, workspaceID string, l *slog.Logger) DeviceBindingSvc {
+	s := &DeviceBindingImpl{
+		repo:        repo,
+		workspaceID: workspaceID,
+		l:           l,
+		statuses:    make(map[string]int),
+	}
+	if n, err := repo.SeedBound(context.Background(), workspaceID); err != nil {
+		l.Error("初始化已登记设备的绑定关系失败", slog.Any("err", err))
+	} else if n > 0 {
+		l.Info("已登记设备已标记为已绑定", slog.Int("count", n))
+	}
+	bindings, err := repo.SelectAll(context.Background())
+	if err != nil {
+		l.Error("加载设备绑定关系失败", slog.Any("err", err))
+	}
+	for _, b := range bindings {
+		s.statuses[b.SN] = b.Status
+	}
+	return s
+}
+
+func (s *DeviceBindingImpl) IsBound(sn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.statuses[sn] == po.DeviceBindingStatusBound
+}
+
+func (s *DeviceBindingImpl) Observe(ctx context.Context, sn string, topo dto.ProductTopo) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[sn]
+	if ok && status != po.DeviceBindingStatusUnbound {
+		return status == po.DeviceBindingStatusBound
+	}
+
+	binding, err := s.repo.SelectBySN(ctx, sn)
+	if err != nil {
+		return false
+	}
+	if binding == nil {
+		binding = &po.DeviceBinding{SN: sn}
+	}
+	binding.WorkspaceID = s.workspaceID
+	binding.Domain = topo.Domain
+	binding.Type = topo.Type
+	binding.SubType = topo.SubType
+	binding.Status = po.DeviceBindingStatusPending
+	if err := s.repo.Save(ctx, binding); err != nil {
+		return false
+	}
+	s.statuses[sn] = po.DeviceBindingStatusPending
+	s.l.Warn("发现未绑定设备，等待管理员审批", slog.String("sn", sn), slog.String("domain", topo.Domain))
+	return false
+}
+
+func (s *DeviceBindingImpl) Bind(ctx context.Context, params dto.DeviceBindingParams) error {
+	if params.WorkspaceID != "" && params.WorkspaceID != s.workspaceID {
+		return fmt.Errorf("工作空间不存在: %s", params.WorkspaceID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	binding, err := s.repo.SelectBySN(ctx, params.DeviceSN)
+	if err != nil {
+		return err
+	}
+	if binding == nil {
+		binding = &po.DeviceBinding{SN: params.DeviceSN, WorkspaceID: s.workspaceID}
+	}
+	if binding.Status == po.DeviceBindingStatusRejected {
+		return errors.New("设备已被管理员拒绝")
+	}
+	binding.UserID = params.UserID
+	if params.Nickname != "" {
+		binding.Nickname = params.Nickname
+	}
+	if binding.Status != po.DeviceBindingStatusBound {
+		binding.Status = po.DeviceBindingStatusPending
+	}
+	if err := s.repo.Save(ctx, binding); err != nil {
+		return err
+	}
+	s.statuses[binding.SN] = binding.Status
+	if binding.Status != po.DeviceBindingStatusBound {
+		return ErrBindingPending
+	}
+	return nil
+}
+
+func (s *DeviceBindingImpl) Unbind(ctx context.Context, sn string) error {
+	return s.setStatus(ctx, sn, po.DeviceBindingStatusUnbound)
+}
+
+func (s *DeviceBindingImpl) Approve(ctx context.Context, sn string) error {
+	return s.setStatus(ctx, sn, po.DeviceBindingStatusBound)
+}
+
+func (s *DeviceBindingImpl) Reject(ctx context.Context, sn string) error {
+	return s.setStatus(ctx, sn, po.DeviceBindingStatusRejected)
+}
+
+// setStatus 更新已有设备的绑定状态，同时刷新内存缓存
+func (s *DeviceBindingImpl) setStatus(ctx context.Context, sn string, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	binding, err := s.repo.SelectBySN(ctx, sn)
+	if err != nil {
+		return err
+	}
+	if binding == nil {
+		return fmt.Errorf("设备 %s 没有绑定记录", sn)
+	}
+	binding.Status = status
+	if status == po.DeviceBindingStatusBound {
+		now := time.Now()
+		binding.BoundAt = &now
+	} else {
+		binding.BoundAt = nil
+	}
+	if err := s.repo.Save(ctx, binding); err != nil {
+		return err
+	}
+	s.statuses[sn] = status
+	s.l.Info("设备绑定状态已更新", slog.String("sn", sn), slog.Int("status", status))
+	return nil
+}
+
+func (s *DeviceBindingImpl) List(ctx context.Context, statuses ...int) ([]po.DeviceBinding, error) {
+	return s.repo.SelectAll(ctx, statuses...)
+}
+
+func (s *DeviceBindingImpl) ListDevices(ctx context.Context, bound bool) ([]dto.BoundDevice, error) {
+	statuses := []int{po.DeviceBindingStatusPending, po.DeviceBindingStatusUnbound}
+	if bound {
+		statuses = []int{po.DeviceBindingStatusBound}
+	}
+	bindings, err := s.repo.SelectAll(ctx, statuses...)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]dto.BoundDevice, 0, len(bindings))
+	for _, b := range bindings {
+		d := dto.BoundDevice{
+			DeviceSN:    b.SN,
+			Nickname:    b.Nickname,
+			WorkspaceID: b.WorkspaceID,
+			Domain:      b.Domain,
+			Type:        b.Type,
+			SubType:     b.SubType,
+			BoundStatus: bound,
+		}
+		if b.BoundAt != nil {
+			d.BoundTime = b.BoundAt.Format(time.DateTime)
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/pkg/servicecall"
+	"github.com/google/uuid"
+)
+
+type DeviceLogSvc interface {
+	// ListDeviceFiles 通过 fileupload_list 获取无人机及其遥控器上的日志列表
+	ListDeviceFiles(ctx context.Context, droneSN string, modules []string) ([]dto.FileuploadDeviceFiles, error)
+	// StartUpload 通过 fileupload_start 让设备将日志上传到对象存储
+	StartUpload(ctx context.Context, droneSN string, params dto.DeviceLogUploadParams) ([]po.DeviceLog, error)
+	// HandleFileuploadProgress 处理 fileupload_progress 事件，返回更新后的上传记录
+	HandleFileuploadProgress(ctx context.Context, gatewaySN string, data dto.FileuploadProgressData) ([]po.DeviceLog, error)
+	// ListUploaded 获取无人机的日志上传记录，已上传完成的附带下载链接
+	ListUploaded(ctx context.Context, droneSN string) ([]DeviceLogItem, error)
+}
+
+// StorageRepo 签发设备直传对象存储使用的临时凭证
+type StorageRepo interface {
+	IssueCredentials(ctx context.Context, bucket string) (dto.StorageConfig, error)
+}
+
+type DeviceLogRepo interface {
+	Bucket() string
+	Save(ctx context.Context, log *po.DeviceLog) error
+	SelectByS3Key(ctx context.Context, key string) (*po.DeviceLog, error)
+	SelectByDroneSN(ctx context.Context, droneSN string) ([]po.DeviceLog, error)
+	PresignDownloadURL(ctx context.Context, key string) (string, error)
+}
+
+// DeviceLogItem 日志上传记录及下载链接
+type DeviceLogItem struct {
+	po.DeviceLog
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type DeviceLogImpl struct {
+	repo      DeviceLogRepo
+	droneRepo DroneRepo
+	storage   StorageRepo
+	caller    *servicecall.Client
+	l         *slog.Logger
+}
+
+func NewDeviceLogImpl(repo DeviceLogRepo, droneRepo DroneRepo, storage StorageRepo, caller *servicecall.Client, l *slog.Logger) DeviceLogSvc {
+	return &DeviceLogImpl{
+		repo:      repo,
+		droneRepo: droneRepo,
+		storage:   storage,
+		caller:    caller,
+		l:         l,
+	}
+}
+
+func (s *DeviceLogImpl) gatewaySN(ctx context.Context, droneSN string) (string, error) {
+	gatewaySN, err := s.droneRepo.FetchGatewaySNByDroneSN(ctx, droneSN)
+	if err != nil || gatewaySN == "" {
+		return "", errors.New("无人机未连接网关")
+	}
+	return gatewaySN, nil
+}
+
+func (s *DeviceLogImpl) ListDeviceFiles(ctx context.Context, droneSN string, modules []string) ([]dto.FileuploadDeviceFiles, error) {
+	gatewaySN, err := s.gatewaySN(ctx, droneSN)
+	if err != nil {
+		return nil, err
+	}
+	if len(modules) == 0 {
+		modules = []string{dto.FileuploadModuleAircraft, dto.FileuploadModuleRC}
+	}
+
+	reply, err := s.caller.Call(ctx, gatewaySN, dto.MethodFileuploadList, dto.FileuploadListData{ModuleList: modules})
+	if err != nil {
+		return nil, err
+	}
+	var output dto.FileuploadListOutput
+	if err := json.Unmarshal(reply.Data.Output, &output); err != nil {
+		return nil, fmt.Errorf("解析日志列表失败: %w", err)
+	}
+	return output.Files, nil
+}
+
+func (s *DeviceLogImpl) StartUpload(ctx context.Context, droneSN string, params dto.DeviceLogUploadParams) ([]po.DeviceLog, error) {
+	if len(params.Files) == 0 {
+		return nil, errors.New("未指定需要上传的日志")
+	}
+	gatewaySN, err := s.gatewaySN(ctx, droneSN)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := s.storage.IssueCredentials(ctx, s.repo.Bucket())
+	if err != nil {
+		return nil, fmt.Errorf("签发上传凭证失败: %w", err)
+	}
+
+	bid := uuid.New().String()
+	now := time.Now()
+	data := dto.FileuploadStartData{StorageConfig: storage}
+	logs := make([]po.DeviceLog, 0, len(params.Files))
+	for _, f := range params.Files {
+		if len(f.BootIndexes) == 0 {
+			return nil, fmt.Errorf("模块 %s 未指定开机序号", f.Module)
+		}
+		file := dto.FileuploadStartFile{
+			ObjectKey: fmt.Sprintf("%s/%s/%s_%s.zip", droneSN, now.Format("20060102"), f.Module, now.Format("150405")),
+			Module:    f.Module,
+		}
+		indexes := make([]string, 0, len(f.BootIndexes))
+		for _, i := range f.BootIndexes {
+			file.List = append(file.List, dto.FileuploadBootIndex{BootIndex: i})
+			indexes = append(indexes, strconv.Itoa(i))
+		}
+		data.Params.Files = append(data.Params.Files, file)
+		logs = append(logs, po.DeviceLog{
+			DroneSN:     droneSN,
+			GatewaySN:   gatewaySN,
+			BID:         bid,
+			Module:      f.Module,
+			BootIndexes: strings.Join(indexes, ","),
+			S3Key:       file.ObjectKey,
+			Status:      dto.FileuploadStatusSent,
+		})
+	}
+
+	_, callErr := s.caller.CallWithBID(ctx, gatewaySN, bid, dto.MethodFileuploadStart, data)
+	for i := range logs {
+		if callErr != nil {
+			logs[i].Status = dto.FileuploadStatusFailed
+			logs[i].Message = callErr.Error()
+		}
+		if err := s.repo.Save(ctx, &logs[i]); err != nil {
+			return nil, err
+		}
+	}
+	if callErr != nil {
+		return nil, callErr
+	}
+	s.l.Info("已下发日志上传", slog.String("droneSN", droneSN), slog.String("bid", bid), slog.Int("count", len(logs)))
+	return logs, nil
+}
+
+func (s *DeviceLogImpl) HandleFileuploadProgress(ctx context.Context, gatewaySN string, data dto.FileuploadProgressData) ([]po.DeviceLog, error) {
+	var updated []po.DeviceLog
+	for _, f := range data.Output.Ext.Files {
+		log, err := s.repo.SelectByS3Key(ctx, f.Key)
+		if err != nil {
+			return nil, err
+		}
+		log.DeviceSN = f.DeviceSN
+		log.Fingerprint = f.Fingerprint
+		log.Size = f.Size
+		log.Status = f.Progress.Status
+		log.Progress = f.Progress.Progress
+		log.Result = f.Progress.Result
+		if f.Progress.Status == dto.FileuploadStatusOK {
+			finishedAt := time.Now()
+			if f.Progress.FinishTime > 0 {
+				finishedAt = time.UnixMilli(f.Progress.FinishTime)
+			}
+			log.FinishedAt = &finishedAt
+		}
+		if err := s.repo.Save(ctx, log); err != nil {
+			return nil, err
+		}
+		updated = append(updated, *log)
+	}
+	s.l.Info("日志上传进度", slog.String("gatewaySN", gatewaySN), slog.String("status", data.Output.Status))
+	return updated, nil
+}
+
+func (s *DeviceLogImpl) ListUploaded(ctx context.Context, droneSN string) ([]DeviceLogItem, error) {
+	logs, err := s.repo.SelectByDroneSN(ctx, droneSN)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]DeviceLogItem, 0, len(logs))
+	for _, log := range logs {
+		item := DeviceLogItem{DeviceLog: log}
+		if log.Status == dto.FileuploadStatusOK {
+			// 生成链接失败不影响列表返回，前端可稍后重试
+			item.DownloadURL, _ = s.repo.PresignDownloadURL(ctx, log.S3Key)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
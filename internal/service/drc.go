@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/pkg/servicecall"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gofiber/contrib/websocket"
+)
+
+// DRC 链路参数
+const (
+	drcHeartbeatInterval = time.Second      // 心跳间隔，设备超过 3 秒未收到心跳会退出 DRC
+	drcCallTimeout       = 10 * time.Second // 进入、退出 DRC 的超时时间
+	drcBrokerTTL         = 2 * time.Hour    // 下发给设备的中继凭证有效期
+	drcOSDFrequency      = 10
+	drcHSIFrequency      = 1
+)
+
+// DRCUpTopic 设备上行的 DRC 主题
+const DRCUpTopic = "thing/product/+/drc/up"
+
+type DRCSvc interface {
+	// Join 控制连接建立时调用，无人机的首个控制连接会让网关进入 DRC 模式
+	Join(ctx context.Context, sn string, conn *websocket.Conn) error
+	// Leave 控制连接关闭时调用，最后一个控制连接关闭后退出 DRC 模式
+	Leave(ctx context.Context, sn string, conn *websocket.Conn)
+	// Send 将杆量、紧急停桨等 DRC 专用消息下发到飞行器
+	// 返回 true 表示该消息只由 DRC 处理，无需再交给控制会话；相机、云台、返航等由控制会话调用 services 处理
+	Send(ctx context.Context, sn string, msg dto.WSbaseModel) (bool, error)
+	// Subscribe 订阅设备上行的 drc/up 主题，遥测转发给对应的控制连接
+	Subscribe() error
+}
+
+// drcSession 一台无人机的 DRC 会话
+type drcSession struct {
+	sn        string
+	gatewaySN string
+	ready     chan struct{} // 进入 DRC 完成后关闭
+	err       error         // 进入 DRC 的结果，ready 关闭后可读
+	cancel    context.CancelFunc
+
+	mu    sync.Mutex // 保护 conns 与 seq，同时串行化对连接的写入
+	conns []*websocket.Conn
+	seq   int64
+}
+
+type DRCImpl struct {
+	droneRepo DroneRepo
+	mqtt      mqtt.Client
+	caller    *servicecall.Client
+	broker    dto.DRCBroker
+	l         *slog.Logger
+
+	mu       sync.Mutex
+	sessions map[string]*drcSession // 无人机 SN -> 会话
+}
+
+// NewDRCImpl 创建 DRC 会话管理，broker 为设备连接的 MQTT 中继，ClientID 与 ExpireTime 在进入 DRC 时生成
+func NewDRCImpl(droneRepo DroneRepo, mqtt mqtt.Client, caller *servicecall.Client, broker dto.DRCBroker, l *slog.Logger) DRCSvc {
+	// 设备只接受 host:port 形式的地址
+	if i := strings.Index(broker.Address, "://"); i >= 0 {
+		broker.Address = broker.Address[i+3:]
+	}
+	return &DRCImpl{
+		droneRepo: droneRepo,
+		mqtt:      mqtt,
+		caller:    caller,
+		broker:    broker,
+		l:         l,
+		sessions:  make(map[string]*drcSession),
+	}
+}
+
+func (s *DRCImpl) Subscribe() error {
+	token := s.mqtt.Subscribe(DRCUpTopic, 0, func(_ mqtt.Client, m mqtt.Message) {
+		parts := strings.Split(m.Topic(), "/")
+		if len(parts) != 5 {
+			return
+		}
+		s.handleUp(parts[2], m.Payload())
+	})
+	if token.Wait() && token.Error() != nil {
+		s.l.Error("订阅 DRC 上行主题失败", slog.Any("err", token.Error()))
+		return token.Error()
+	}
+	return nil
+}
+
+func (s *DRCImpl) Join(ctx context.Context, sn string, conn *websocket.Conn) error {
+	s.mu.Lock()
+	session, ok := s.sessions[sn]
+	if !ok {
+		session = &drcSession{sn: sn, ready: make(chan struct{})}
+		s.sessions[sn] = session
+	}
+	session.mu.Lock()
+	session.conns = append(session.conns, conn)
+	session.mu.Unlock()
+	s.mu.Unlock()
+
+	if ok {
+		// 其他连接正在进入或已进入 DRC，等待其结果
+		<-session.ready
+		return session.err
+	}
+
+	session.err = s.enter(ctx, session)
+	close(session.ready)
+	if session.err != nil {
+		s.l.Error("进入 DRC 模式失败", slog.String("sn", sn), slog.Any("err", session.err))
+		s.mu.Lock()
+		if s.sessions[sn] == session {
+			delete(s.sessions, sn)
+		}
+		s.mu.Unlock()
+		return session.err
+	}
+	s.writeSession(session, dto.WSMethodDRCSessionEntered, map[string]string{"gateway_sn": session.gatewaySN})
+	return nil
+}
+
+// enter 让网关进入 DRC 模式、抢夺飞行控制权并开始发送心跳
+func (s *DRCImpl) enter(ctx context.Context, session *drcSession) error {
+	gatewaySN, err := s.droneRepo.FetchGatewaySNByDroneSN(ctx, session.sn)
+	if err != nil || gatewaySN == "" {
+		return errors.New("无人机未连接网关")
+	}
+	// handleUp 按网关查找会话，需在锁内写入
+	s.mu.Lock()
+	session.gatewaySN = gatewaySN
+	s.mu.Unlock()
+
+	broker := s.broker
+	broker.ClientID = "drc-" + gatewaySN
+	broker.ExpireTime = time.Now().Add(drcBrokerTTL).Unix()
+	callCtx, cancel := context.WithTimeout(ctx, drcCallTimeout)
+	defer cancel()
+	if _, err := s.caller.Call(callCtx, gatewaySN, dto.MethodDRCModeEnter, dto.DRCModeEnterData{
+		MQTTBroker:   broker,
+		OSDFrequency: drcOSDFrequency,
+		HSIFrequency: drcHSIFrequency,
+	}); err != nil {
+		return fmt.Errorf("drc_mode_enter 失败: %w", err)
+	}
+	// 遥控器在 Pilot 中手动操作时无法抢夺控制权，不影响相机等载荷控制
+	if _, err := s.caller.Call(callCtx, gatewaySN, dto.MethodFlightAuthorityGrab, struct{}{}); err != nil {
+		s.l.Warn("抢夺飞行控制权失败", slog.String("gatewaySN", gatewaySN), slog.Any("err", err))
+	}
+
+	hbCtx, hbCancel := context.WithCancel(context.Background())
+	session.cancel = hbCancel
+	go s.heartbeat(hbCtx, session)
+	s.l.Info("已进入 DRC 模式", slog.String("sn", session.sn), slog.String("gatewaySN", gatewaySN))
+	return nil
+}
+
+func (s *DRCImpl) heartbeat(ctx context.Context, session *drcSession) {
+	ticker := time.NewTicker(drcHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.publishDown(session, dto.MethodDRCHeartBeat, map[string]int64{"timestamp": now.UnixMilli()})
+		}
+	}
+}
+
+func (s *DRCImpl) Leave(ctx context.Context, sn string, conn *websocket.Conn) {
+	s.mu.Lock()
+	session, ok := s.sessions[sn]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	session.mu.Lock()
+	for i, c := range session.conns {
+		if c == conn {
+			session.conns = append(session.conns[:i], session.conns[i+1:]...)
+			break
+		}
+	}
+	remaining := len(session.conns)
+	session.mu.Unlock()
+	if remaining > 0 {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.sessions, sn)
+	s.mu.Unlock()
+
+	<-session.ready
+	if session.err != nil {
+		return
+	}
+	session.cancel()
+	callCtx, cancel := context.WithTimeout(context.Background(), drcCallTimeout)
+	defer cancel()
+	if _, err := s.caller.Call(callCtx, session.gatewaySN, dto.MethodDRCModeExit, struct{}{}); err != nil {
+		s.l.Error("退出 DRC 模式失败", slog.String("sn", sn), slog.Any("err", err))
+		return
+	}
+	s.l.Info("已退出 DRC 模式", slog.String("sn", sn), slog.String("gatewaySN", session.gatewaySN))
+}
+
+func (s *DRCImpl) Send(ctx context.Context, sn string, msg dto.WSbaseModel) (bool, error) {
+	switch msg.Method {
+	case dto.MethodDRCStickControl:
+		session, err := s.activeSession(sn)
+		if err != nil {
+			return true, err
+		}
+		var data dto.StickControlData
+		if err := remarshal(msg.Data, &data); err != nil {
+			return true, err
+		}
+		for _, v := range []int{data.Roll, data.Pitch, data.Throttle, data.Yaw, data.GimbalPitch} {
+			if v < dto.StickMin || v > dto.StickMax {
+				return true, fmt.Errorf("杆量超出范围 [%d, %d]: %d", dto.StickMin, dto.StickMax, v)
+			}
+		}
+		return true, s.publishDown(session, dto.MethodDRCStickControl, data)
+	case dto.MethodDRCEmergencyStop:
+		session, err := s.activeSession(sn)
+		if err != nil {
+			return true, err
+		}
+		return true, s.publishDown(session, dto.MethodDRCEmergencyStop, struct{}{})
+	}
+	return false, nil
+}
+
+// activeSession 获取已进入 DRC 的会话
+func (s *DRCImpl) activeSession(sn string) (*drcSession, error) {
+	s.mu.Lock()
+	session, ok := s.sessions[sn]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("无人机未进入 DRC 模式")
+	}
+	select {
+	case <-session.ready:
+	default:
+		return nil, errors.New("正在进入 DRC 模式")
+	}
+	if session.err != nil {
+		return nil, session.err
+	}
+	return session, nil
+}
+
+// publishDown 向网关的 drc/down 发布消息，seq 在会话内递增
+func (s *DRCImpl) publishDown(session *drcSession, method string, data any) error {
+	session.mu.Lock()
+	session.seq++
+	msg := dto.DRCMessage{Method: method, Seq: session.seq, Data: data}
+	session.mu.Unlock()
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	topic := fmt.Sprintf("thing/product/%s/drc/down", session.gatewaySN)
+	token := s.mqtt.Publish(topic, 0, false, payload)
+	if token.Wait() && token.Error() != nil {
+		s.l.Error("DRC 下行消息发送失败", slog.String("topic", topic), slog.String("method", method), slog.Any("err", token.Error()))
+		return token.Error()
+	}
+	return nil
+}
+
+// handleUp 将网关的 drc/up 消息转发给对应无人机的控制连接
+func (s *DRCImpl) handleUp(gatewaySN string, payload []byte) {
+	var msg struct {
+		Method string          `json:"method"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		s.l.Error("解析 DRC 上行消息失败", slog.String("gatewaySN", gatewaySN), slog.Any("err", err))
+		return
+	}
+	if msg.Method == dto.MethodDRCHeartBeat {
+		return
+	}
+
+	s.mu.Lock()
+	var target *drcSession
+	for _, session := range s.sessions {
+		if session.gatewaySN == gatewaySN {
+			target = session
+			break
+		}
+	}
+	s.mu.Unlock()
+	if target == nil {
+		return
+	}
+	s.writeSession(target, msg.Method, msg.Data)
+}
+
+// writeSession 向会话的所有控制连接推送消息
+func (s *DRCImpl) writeSession(session *drcSession, method string, data any) {
+	payload, err := json.Marshal(dto.WSbaseModel{
+		Timestamp: time.Now().Unix(),
+		Method:    method,
+		Data:      data,
+	})
+	if err != nil {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, conn := range session.conns {
+		if err := WriteConn(conn, websocket.TextMessage, payload); err != nil {
+			s.l.Debug("DRC 消息推送失败", slog.String("sn", session.sn), slog.Any("err", err))
+		}
+	}
+}
+
+// remarshal 将 WebSocket 消息中的 data 转换为具体结构
+func remarshal(src, dst any) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
@@ -3,22 +3,26 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"reflect"
 	"slices"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/dronesphere/internal/event"
 	"github.com/dronesphere/internal/model/dto"
 	"github.com/dronesphere/internal/model/entity"
 	"github.com/dronesphere/internal/model/po"
 	"github.com/dronesphere/internal/model/ro"
+	"github.com/dronesphere/internal/pkg/servicecall"
 	"github.com/dronesphere/internal/repo"
 	"github.com/dronesphere/pkg/txlive"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/gofiber/contrib/websocket"
-	"github.com/google/uuid"
 )
 
 func init() {
@@ -32,7 +36,9 @@ type (
 		Repo() DroneRepo
 		SaveDroneTopo(ctx context.Context, update dto.UpdateTopoPayload) error
 		FetchDeviceTopo(ctx context.Context, workspace string) ([]entity.Drone, []entity.RC, error)
-		UpdateStateBySN(ctx context.Context, sn string, msg dto.DroneMessageProperty) error
+		UpdateStateBySN(ctx context.Context, sn string, data json.RawMessage) error
+		// UpdatePropertiesBySN 合并 state 消息中变化的属性，返回实际发生变化的属性
+		UpdatePropertiesBySN(ctx context.Context, sn string, data json.RawMessage) ([]event.DroneStateChangedPayload, error)
 		// 新增：从消息创建无人机实体
 		CreateDroneFromMsg(ctx context.Context, sn string, msg dto.ProductTopo, modelRepo *repo.ModelDefaultRepo) (*entity.Drone, error)
 		// 新增：根据无人机SN启动直播
@@ -41,6 +47,10 @@ type (
 		StopLiveBySN(ctx context.Context, sn string) error
 		CheckControlConnection(ctx context.Context, conn *websocket.Conn, sn string) error
 		HandleControlSession(ctx context.Context, conn *websocket.Conn, sn string, mt int, msg string) error
+		// SetPropertiesBySN 校验并通过 property/set 设置无人机属性，返回每个属性的设置结果
+		SetPropertiesBySN(ctx context.Context, sn string, props map[string]json.RawMessage) (map[string]int, error)
+		// BroadcastToSN 向订阅指定无人机的前端连接推送消息
+		BroadcastToSN(sn string, messageType int, data string)
 	}
 
 	DroneRepo interface {
@@ -51,27 +61,36 @@ type (
 		SelectByIDV2(ctx context.Context, id uint) (*po.Drone, error)
 		FetchStateBySN(ctx context.Context, sn string) (ro.Drone, error)
 		SaveState(ctx context.Context, state ro.Drone) error
+		FetchRawStateBySN(ctx context.Context, sn string) (map[string]json.RawMessage, error) // 获取实时状态原始字段
+		MergeState(ctx context.Context, sn string, fields map[string]json.RawMessage) error   // 按字段合并实时状态
+		SaveOffline(ctx context.Context, sn string, lastOnlineAt time.Time) error             // 标记离线并记录最后在线时间
 		SelectAllByID(ctx context.Context, ids []uint) ([]entity.Drone, error)
 		UpdateDroneInfo(ctx context.Context, sn string, updates map[string]interface{}) error
 		FetchDroneModelOptions(ctx context.Context) ([]dto.DroneModelOption, error)                 // 获取无人机型号选项列表
 		UpdateLiveInfoBySN(ctx context.Context, sn, pushRTMPUrl, pullRTMPUrl, videoID string) error // 修改签名以包含 videoID
 		FetchGatewaySNByDroneSN(ctx context.Context, droneSN string) (string, error)                // 新增获取网关SN的方法
+		SaveGatewaySNByDroneSN(ctx context.Context, droneSN, gatewaySN string) error                // 记录无人机挂载的网关SN
+		DeleteGatewaySNByDroneSN(ctx context.Context, droneSN string) error                         // 删除无人机挂载的网关SN
 	}
 )
 
 type DroneImpl struct {
 	r         DroneRepo
 	modelRepo ModelRepo
+	jobSvc    JobSvc
 	l         *slog.Logger
 	mqtt      mqtt.Client
+	caller    *servicecall.Client
 }
 
-func NewDroneImpl(r DroneRepo, modelRepo ModelRepo, l *slog.Logger, mqtt mqtt.Client) DroneSvc {
+func NewDroneImpl(r DroneRepo, modelRepo ModelRepo, jobSvc JobSvc, l *slog.Logger, mqtt mqtt.Client, caller *servicecall.Client) DroneSvc {
 	return &DroneImpl{
 		r:         r,
 		modelRepo: modelRepo,
+		jobSvc:    jobSvc,
 		l:         l,
 		mqtt:      mqtt,
+		caller:    caller,
 	}
 }
 
@@ -146,47 +165,20 @@ func (s *DroneImpl) StartLiveBySN(ctx context.Context, sn string) (string, error
 		return "", fmt.Errorf("未能获取无人机关联的GatewaySN，无法发送MQTT指令")
 	}
 
-	if gatewaySN != "" {
-		// 构造 MQTT 消息
-		liveStartData := dto.LiveStartPushData{
-			URL:          pushRTMPUrl, // 设备端使用推流地址
-			URLType:      1,           // 1 代表 RTMP
-			VideoID:      videoID,
-			VideoQuality: 0, // 0 代表自适应
-		}
-		mqttRequest := dto.LiveStartPushRequest{
-			BID:       uuid.New().String(),
-			Data:      liveStartData,
-			TID:       uuid.New().String(),
-			Timestamp: time.Now().UnixMilli(),
-			Method:    "live_start_push",
-		}
-		payload, marshalErr := json.Marshal(mqttRequest)
-		if marshalErr != nil {
-			s.l.Error("序列化MQTT live_start_push 请求失败", slog.String("sn", sn), slog.Any("error", marshalErr))
-			return "", fmt.Errorf("序列化MQTT live_start_push请求失败: %w", marshalErr)
-		}
-
-		topic := fmt.Sprintf("thing/product/%s/services", gatewaySN)
-		s.l.Info("准备发送MQTT live_start_push 消息", slog.String("topic", topic), slog.String("payload", string(payload)))
-
-		token := s.mqtt.Publish(topic, 1, false, payload)
-		if token.WaitTimeout(5*time.Second) && token.Error() != nil {
-			s.l.Error("发送MQTT live_start_push 消息失败", slog.String("sn", sn), slog.String("topic", topic), slog.Any("error", token.Error()))
-			return "", fmt.Errorf("发送MQTT live_start_push消息失败: %w", token.Error())
-		} else if !token.WaitTimeout(5 * time.Second) {
-			s.l.Error("发送MQTT live_start_push 消息超时", slog.String("sn", sn), slog.String("topic", topic))
-			return "", fmt.Errorf("发送MQTT live_start_push消息超时")
-		}
-		s.l.Info("MQTT live_start_push 消息发送成功", slog.String("sn", sn), slog.String("topic", topic))
-
-		s.l.Info("启动无人机直播流程成功", slog.String("sn", sn))
-		return pullRTMPUrl, nil
-	} else {
-		s.l.Warn("GatewaySN为空，跳过发送MQTT live_start_push消息", slog.String("sn", sn))
-		return "", fmt.Errorf("GatewaySN为空，无法发送MQTT指令")
+	// 调用设备 live_start_push 并等待应答
+	liveStartData := dto.LiveStartPushData{
+		URL:          pushRTMPUrl, // 设备端使用推流地址
+		URLType:      1,           // 1 代表 RTMP
+		VideoID:      videoID,
+		VideoQuality: 0, // 0 代表自适应
+	}
+	if _, err := s.caller.Call(ctx, gatewaySN, "live_start_push", liveStartData); err != nil {
+		s.l.Error("设备启动直播失败", slog.String("sn", sn), slog.String("gatewaySN", gatewaySN), slog.Any("error", err))
+		return "", fmt.Errorf("设备启动直播失败: %w", err)
 	}
 
+	s.l.Info("启动无人机直播流程成功", slog.String("sn", sn))
+	return pullRTMPUrl, nil
 }
 
 // StopLiveBySN 根据无人机SN停止直播
@@ -219,38 +211,13 @@ func (s *DroneImpl) StopLiveBySN(ctx context.Context, sn string) error {
 		return nil
 	}
 
-	if gatewaySN != "" {
-		// 构造 MQTT 消息
-		liveStopData := dto.LiveStopPushData{
-			VideoID: drone.CurrentVideoID,
-		}
-		mqttRequest := dto.LiveStopPushRequest{
-			BID:       uuid.New().String(),
-			Data:      liveStopData,
-			TID:       uuid.New().String(),
-			Timestamp: time.Now().UnixMilli(),
-			Method:    "live_stop_push",
-		}
-		payload, marshalErr := json.Marshal(mqttRequest)
-		if marshalErr != nil {
-			s.l.Error("序列化MQTT live_stop_push 请求失败", slog.String("sn", sn), slog.Any("error", marshalErr))
-			return fmt.Errorf("序列化MQTT live_stop_push请求失败: %w", marshalErr)
-		}
-
-		topic := fmt.Sprintf("thing/product/%s/services", gatewaySN)
-		s.l.Info("准备发送MQTT live_stop_push 消息", slog.String("topic", topic), slog.String("payload", string(payload)))
-
-		token := s.mqtt.Publish(topic, 1, false, payload)
-		if token.WaitTimeout(5*time.Second) && token.Error() != nil {
-			s.l.Error("发送MQTT live_stop_push 消息失败", slog.String("sn", sn), slog.String("topic", topic), slog.Any("error", token.Error()))
-			return fmt.Errorf("发送MQTT live_stop_push消息失败: %w", token.Error())
-		} else if !token.WaitTimeout(5 * time.Second) {
-			s.l.Error("发送MQTT live_stop_push 消息超时", slog.String("sn", sn), slog.String("topic", topic))
-			return fmt.Errorf("发送MQTT live_stop_push消息超时")
-		}
-		s.l.Info("MQTT live_stop_push 消息发送成功", slog.String("sn", sn), slog.String("topic", topic))
-	} else {
-		s.l.Warn("GatewaySN为空，跳过发送MQTT live_stop_push消息", slog.String("sn", sn))
+	// 调用设备 live_stop_push 并等待应答
+	liveStopData := dto.LiveStopPushData{
+		VideoID: drone.CurrentVideoID,
+	}
+	if _, err := s.caller.Call(ctx, gatewaySN, "live_stop_push", liveStopData); err != nil {
+		s.l.Error("设备停止直播失败", slog.String("sn", sn), slog.String("gatewaySN", gatewaySN), slog.Any("error", err))
+		return fmt.Errorf("设备停止直播失败: %w", err)
 	}
 
 	// 清空数据库中的直播信息
@@ -266,7 +233,7 @@ func (s *DroneImpl) StopLiveBySN(ctx context.Context, sn string) error {
 func (s *DroneImpl) SaveDroneTopo(ctx context.Context, data dto.UpdateTopoPayload) error {
 	rc := ctx.Value(dto.SNKey).(string)
 	s.l.Info("SaveDroneTopo", slog.Any("data", data), slog.Any("rc", rc))
-	// 如果没有子设备，按照遥控器SN删除无人机
+	// 没有子设备说明无人机已脱离拓扑，关联关系与离线状态由拓扑事件处理器维护
 	if len(data.SubDevices) == 0 {
 		s.l.Info("SubDevices is empty, remove drone", slog.Any("rc", rc))
 		return nil
@@ -316,19 +283,84 @@ func (s *DroneImpl) FetchDeviceTopo(ctx context.Context, workspace string) ([]en
 }
 
 // UpdateStateBySN 更新无人机实时数据状态
-func (s *DroneImpl) UpdateStateBySN(ctx context.Context, sn string, msg dto.DroneMessageProperty) error {
-	var state = ro.Drone{
-		SN:                   sn,
-		Status:               ro.DroneStatusOnline,
-		DroneMessageProperty: msg,
+//
+// data 为 OSD 消息的 data 字段，只合并其中出现的属性，不会清空 state 消息写入的低频属性
+func (s *DroneImpl) UpdateStateBySN(ctx context.Context, sn string, data json.RawMessage) error {
+	fields, err := parseStateFields(sn, data)
+	if err != nil {
+		return err
 	}
-	if err := s.r.SaveState(ctx, state); err != nil {
+	if err := s.r.MergeState(ctx, sn, fields); err != nil {
 		s.l.Error("Save realtime drone failed", slog.Any("err", err))
 		return err
 	}
 	return nil
 }
 
+// UpdatePropertiesBySN 合并 state 消息中的属性
+//
+// state 消息只携带发生变化的属性，逐个与已保存的值比较，仅写入并返回确实变化的属性
+func (s *DroneImpl) UpdatePropertiesBySN(ctx context.Context, sn string, data json.RawMessage) ([]event.DroneStateChangedPayload, error) {
+	fields, err := parseStateFields(sn, data)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.r.FetchRawStateBySN(ctx, sn)
+	if err != nil {
+		// 尚无实时数据时视为全部属性发生变化
+		s.l.Warn("获取无人机实时数据失败", slog.Any("sn", sn), slog.Any("err", err))
+		current = map[string]json.RawMessage{}
+	}
+
+	changed := make(map[string]json.RawMessage)
+	var changes []event.DroneStateChangedPayload
+	now := time.Now().UnixMilli()
+	for k, v := range fields {
+		if old, ok := current[k]; ok && jsonEqual(old, v) {
+			continue
+		}
+		changed[k] = v
+		if k == "sn" || k == "online_status" {
+			continue
+		}
+		changes = append(changes, event.DroneStateChangedPayload{
+			SN:        sn,
+			Property:  k,
+			Old:       current[k],
+			New:       v,
+			Timestamp: now,
+		})
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if err := s.r.MergeState(ctx, sn, changed); err != nil {
+		s.l.Error("合并无人机属性失败", slog.Any("sn", sn), slog.Any("err", err))
+		return nil, err
+	}
+	return changes, nil
+}
+
+// parseStateFields 拆分消息 data 的顶层属性，并附加 SN 与在线状态
+func parseStateFields(sn string, data json.RawMessage) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("解析无人机属性失败: %w", err)
+	}
+	fields["sn"], _ = json.Marshal(sn)
+	fields["online_status"], _ = json.Marshal(ro.DroneStatusOnline)
+	return fields, nil
+}
+
+// jsonEqual 判断两段 JSON 是否语义相等，忽略空白与键顺序
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
 // CreateDroneFromMsg 从消息创建无人机实体
 // 该方法将原本位于 entity.Drone 中的 NewDroneFromMsg 功能提升到服务层
 // 避免了 entity 包对 repo 包的循环引用问题
@@ -471,7 +503,7 @@ func (dcm *DroneImpl) BroadcastToSN(sn string, messageType int, data string) {
 	conns := dcm.GetConnections(sn)
 
 	for _, conn := range conns {
-		if err := conn.WriteMessage(messageType, []byte(data)); err != nil {
+		if err := WriteConn(conn, messageType, []byte(data)); err != nil {
 			// 发送失败，移除该连接
 			dcm.RemoveConnection(sn, conn)
 		}
@@ -479,7 +511,7 @@ func (dcm *DroneImpl) BroadcastToSN(sn string, messageType int, data string) {
 }
 
 func (dcm *DroneImpl) ReplyToConn(sn string, conn *websocket.Conn, msg string) {
-	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+	if err := WriteConn(conn, websocket.TextMessage, []byte(msg)); err != nil {
 		dcm.l.Error("回应消息发送失败", slog.String("sn", sn), slog.Any("error", err))
 		// 发送失败，移除该连接
 		dcm.RemoveConnection(sn, conn)
@@ -524,17 +556,25 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 	var msg dto.WSbaseModel
 	if err := json.Unmarshal([]byte(msgStr), &msg); err != nil {
 		s.l.Error("反序列化无人机控制消息失败", slog.String("sn", sn), slog.String("message", msgStr), slog.Any("error", err))
-		return fmt.Errorf("反序列化无人机控制消息失败: %w", err)
+		err = fmt.Errorf("反序列化无人机控制消息失败: %w", err)
+		s.replyControl(sn, conn, msg, err)
+		return err
 	}
 	s.l.Info("处理无人机控制消息", slog.String("sn", sn), slog.Any("message", msg))
 
+	err := s.handleControl(ctx, conn, sn, msg)
+	s.replyControl(sn, conn, msg, err)
+	return err
+}
+
+// handleControl 校验控制消息并调用对应的设备服务，成功后广播给该无人机的所有控制连接
+func (s *DroneImpl) handleControl(ctx context.Context, conn *websocket.Conn, sn string, msg dto.WSbaseModel) error {
 	// 根据 Method 调用不同的处理逻辑
 	switch msg.Method {
 	case "init":
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化初始化控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化初始化控制数据失败: %w", err)
 		}
 
@@ -547,7 +587,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		}
 		if err := json.Unmarshal(dataBytes, &InitData); err != nil {
 			s.l.Error("反序列化初始化控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化初始化控制数据失败: %w", err)
 		}
 		s.l.Info("处理初始化控制数据", slog.String("sn", sn), slog.Any("data", InitData))
@@ -555,7 +594,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化初始化控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化初始化控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -565,7 +603,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化自动模式控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化自动模式控制数据失败: %w", err)
 		}
 
@@ -575,20 +612,17 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		}
 		if err := json.Unmarshal(dataBytes, &switchCameraData); err != nil {
 			s.l.Error("反序列化自动模式控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化自动模式控制数据失败: %w", err)
 		}
 		s.l.Info("处理自动模式控制", slog.String("sn", sn), slog.String("action", switchCameraData.Action))
 		if switchCameraData.Action != "start" && switchCameraData.Action != "stop" {
 			s.l.Error("无效的自动模式控制动作", slog.String("sn", sn), slog.String("action", switchCameraData.Action))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("无效的自动模式控制动作: %s", switchCameraData.Action)
 		}
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化自动模式控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化自动模式控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -598,24 +632,26 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化相机切换控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化相机切换控制数据失败: %w", err)
 		}
 
 		var switchCameraData struct {
-			Camera string `json:"camera"` // 相机类型 (wide/zoom)
+			Camera string `json:"camera"`         // 相机类型 (wide/zoom/ir)
+			Mode   string `json:"mode,omitempty"` // 相机模式 (photo/video)，为空时不切换
 		}
 		if err := json.Unmarshal(dataBytes, &switchCameraData); err != nil {
 			s.l.Error("反序列化相机切换控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化相机切换控制数据失败: %w", err)
 		}
 		s.l.Info("处理相机切换控制", slog.String("sn", sn), slog.String("camera", switchCameraData.Camera))
+		if err := s.switchCamera(ctx, sn, switchCameraData.Camera, switchCameraData.Mode); err != nil {
+			s.l.Error("切换相机失败", slog.String("sn", sn), slog.Any("error", err))
+			return err
+		}
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化相机切换控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化相机切换控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -625,7 +661,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化缩放控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化缩放控制数据失败: %w", err)
 		}
 
@@ -636,15 +671,25 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		}
 		if err := json.Unmarshal(dataBytes, &GimbalAngleData); err != nil {
 			s.l.Error("反序列化云台角度控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化云台角度控制数据失败: %w", err)
 		}
 		s.l.Info("处理云台角度控制", slog.String("sn", sn), slog.Float64("pitch", GimbalAngleData.Pitch), slog.Float64("roll", GimbalAngleData.Roll), slog.Float64("yaw", GimbalAngleData.Yaw))
+		// 云台只支持俯仰与偏航控制
+		if math.Abs(GimbalAngleData.Roll) >= gimbalAngleEpsilon {
+			return fmt.Errorf("云台不支持横滚控制: %v", GimbalAngleData.Roll)
+		}
+		pitch, yaw, err := s.setGimbalAngle(ctx, sn, GimbalAngleData.Pitch, GimbalAngleData.Yaw)
+		if err != nil {
+			s.l.Error("控制云台失败", slog.String("sn", sn), slog.Any("error", err))
+			return err
+		}
+		// 广播云台实际到达的角度
+		GimbalAngleData.Pitch, GimbalAngleData.Roll, GimbalAngleData.Yaw = pitch, 0, yaw
+		msg.Data = GimbalAngleData
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化云台角度控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化云台角度控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -655,7 +700,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化缩放控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化缩放控制数据失败: %w", err)
 		}
 
@@ -664,17 +708,19 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		}
 		if err := json.Unmarshal(dataBytes, &zoomData); err != nil {
 			s.l.Error("反序列化缩放控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化缩放控制数据失败: %w", err)
 		}
 
 		s.l.Info("处理缩放控制", slog.String("sn", sn), slog.Float64("factor", zoomData.Factor))
+		if err := s.setZoom(ctx, sn, zoomData.Factor); err != nil {
+			s.l.Error("设置变焦倍数失败", slog.String("sn", sn), slog.Any("error", err))
+			return err
+		}
 
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化缩放控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化缩放控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -682,11 +728,14 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 	case "go_home":
 		// 处理返航控制
 		s.l.Info("处理返航控制", slog.String("sn", sn))
+		if err := s.jobSvc.ControlFlighttask(ctx, sn, dto.FlighttaskActionReturn); err != nil {
+			s.l.Error("一键返航失败", slog.String("sn", sn), slog.Any("error", err))
+			return err
+		}
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化返航控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化返航控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -696,33 +745,39 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化航线控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化航线控制数据失败: %w", err)
 		}
 
 		var waylineData struct {
-			Action string `json:"action"` // 航线动作 (takeoff/pause/resume/finish)
+			Action string `json:"action"` // 航线动作 (pause/resume/finish/cancel_return)
 		}
 		if err := json.Unmarshal(dataBytes, &waylineData); err != nil {
 			s.l.Error("反序列化航线控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化航线控制数据失败: %w", err)
 		}
 
+		// 起飞只能通过任务下发完成，不能当作成功同步给其他控制连接
+		if waylineData.Action == dto.FlighttaskActionTakeoff {
+			s.l.Warn("控制连接不支持起飞", slog.String("sn", sn))
+			return errors.New("控制连接不支持起飞，请通过任务下发起飞")
+		}
+
 		// 验证action参数
-		validActions := []string{"takeoff", "pause", "resume", "finish"}
+		validActions := []string{dto.FlighttaskActionPause, dto.FlighttaskActionResume,
+			dto.FlighttaskActionFinish, dto.FlighttaskActionCancelReturn}
 		if !slices.Contains(validActions, waylineData.Action) {
 			s.l.Error("无效的航线控制动作", slog.String("sn", sn), slog.String("action", waylineData.Action))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("无效的航线控制动作: %s", waylineData.Action)
 		}
 
 		s.l.Info("处理航线控制", slog.String("sn", sn), slog.String("action", waylineData.Action))
+		if err := s.jobSvc.ControlFlighttask(ctx, sn, waylineData.Action); err != nil {
+			return err
+		}
 		msg.Timestamp = time.Now().Unix() // 更新时间戳
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化航线控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化航线控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
@@ -732,7 +787,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		dataBytes, err := json.Marshal(msg.Data)
 		if err != nil {
 			s.l.Error("序列化检测控制数据失败", slog.String("sn", sn), slog.Any("data", msg.Data), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化检测控制数据失败: %w", err)
 		}
 
@@ -742,7 +796,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		}
 		if err := json.Unmarshal(dataBytes, &detectData); err != nil {
 			s.l.Error("反序列化检测控制数据失败", slog.String("sn", sn), slog.String("data", string(dataBytes)), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("反序列化检测控制数据失败: %w", err)
 		}
 
@@ -750,7 +803,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		validModes := []string{"manual", "auto"}
 		if !slices.Contains(validModes, detectData.Mode) {
 			s.l.Error("无效的检测模式", slog.String("sn", sn), slog.String("mode", detectData.Mode))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("无效的检测模式: %s", detectData.Mode)
 		}
 
@@ -758,7 +810,6 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		validActions := []string{"start", "finish"}
 		if !slices.Contains(validActions, detectData.Action) {
 			s.l.Error("无效的检测动作", slog.String("sn", sn), slog.String("action", detectData.Action))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("无效的检测动作: %s", detectData.Action)
 		}
 
@@ -767,15 +818,12 @@ func (s *DroneImpl) HandleControlSession(ctx context.Context, conn *websocket.Co
 		resJson, err := json.Marshal(msg)
 		if err != nil {
 			s.l.Error("序列化检测控制响应失败", slog.String("sn", sn), slog.Any("message", msg), slog.Any("error", err))
-			s.ReplyToConn(sn, conn, "error")
 			return fmt.Errorf("序列化检测控制响应失败: %w", err)
 		}
 		s.BroadcastToSN(sn, websocket.TextMessage, string(resJson))
 		return nil
 	default:
 		s.l.Warn("未知的无人机控制方法", slog.String("sn", sn), slog.String("method", msg.Method))
-		msg.Timestamp = time.Now().Unix() // 更新时间戳
-		s.ReplyToConn(sn, conn, "error")  // 这里可以根据实际情况选择是否回应连接
 		return fmt.Errorf("未知的无人机控制方法: %s", msg.Method)
 	}
 }
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/entity"
+	"github.com/dronesphere/internal/pkg/servicecall"
+	"github.com/gofiber/contrib/websocket"
+)
+
+// 云台与变焦的控制参数
+const (
+	zoomFactorMin      = 2
+	zoomFactorMax      = 200
+	gimbalAngleEpsilon = 0.5 // 角度差小于该值视为已到达，单位：度
+	gimbalMaxDragSpeed = 1.0 // camera_screen_drag 的最大转速，单位：rad/s
+
+	gimbalDragInterval = 500 * time.Millisecond // 两次 camera_screen_drag 之间等待姿态上报的时间
+	gimbalMoveTimeout  = 5 * time.Second        // 转动到目标角度的最长时间
+)
+
+// controlTarget 控制消息下发的目标
+type controlTarget struct {
+	drone        entity.Drone
+	gatewaySN    string
+	payloadIndex string // 主相机的 payload_index，格式为 {type}-{sub_type}-{gimbalindex}
+}
+
+// replyControl 将控制结果回复给发起控制的连接
+func (s *DroneImpl) replyControl(sn string, conn *websocket.Conn, msg dto.WSbaseModel, err error) {
+	reply := dto.ControlReply{}
+	if err != nil {
+		reply.Result = -1
+		reply.Message = err.Error()
+		var re *servicecall.ResultError
+		if errors.As(err, &re) {
+			reply.Result = re.Result
+		}
+	}
+	payload, mErr := json.Marshal(dto.WSbaseModel{
+		TID:       msg.TID,
+		Timestamp: time.Now().Unix(),
+		Method:    msg.Method + "_reply",
+		Data:      reply,
+	})
+	if mErr != nil {
+		s.l.Error("序列化控制结果失败", slog.String("sn", sn), slog.Any("error", mErr))
+		return
+	}
+	s.ReplyToConn(sn, conn, string(payload))
+}
+
+// resolveControlTarget 获取无人机所在网关与主相机的 payload_index
+//
+// payload_index 优先取无人机变体上的第一个云台，变体未配置云台时退回到型号可搭载的第一个云台
+func (s *DroneImpl) resolveControlTarget(ctx context.Context, sn string) (*controlTarget, error) {
+	drone, err := s.r.SelectBySN(ctx, sn)
+	if err != nil {
+		return nil, fmt.Errorf("获取无人机信息失败: %w", err)
+	}
+	gatewaySN, err := s.r.FetchGatewaySNByDroneSN(ctx, sn)
+	if err != nil || gatewaySN == "" {
+		return nil, errors.New("无人机未连接网关")
+	}
+
+	variation := drone.Variation
+	if variation.ID == 0 || len(variation.Gimbals) == 0 {
+		if v, err := s.modelRepo.FindDefaultDroneVariation(ctx, drone.DroneModelID); err == nil && v != nil {
+			variation = *v
+		}
+	}
+	gimbals := variation.Gimbals
+	if len(gimbals) == 0 {
+		droneModel, err := s.modelRepo.SelectDroneModelByID(ctx, drone.DroneModelID)
+		if err != nil {
+			return nil, fmt.Errorf("获取无人机型号失败: %w", err)
+		}
+		gimbals = droneModel.Gimbals
+	}
+	if len(gimbals) == 0 {
+		return nil, fmt.Errorf("无人机 %s 没有云台信息", sn)
+	}
+	gimbal := gimbals[0]
+	return &controlTarget{
+		drone:        drone,
+		gatewaySN:    gatewaySN,
+		payloadIndex: strconv.Itoa(gimbal.Type) + "-" + strconv.Itoa(gimbal.SubType) + "-" + strconv.Itoa(gimbal.Gimbalindex),
+	}, nil
+}
+
+// switchCamera 切换直播镜头与相机模式
+func (s *DroneImpl) switchCamera(ctx context.Context, sn, camera, mode string) error {
+	target, err := s.resolveControlTarget(ctx, sn)
+	if err != nil {
+		return err
+	}
+	if camera != "" {
+		if camera != "wide" && camera != "zoom" && camera != "ir" {
+			return fmt.Errorf("无效的相机类型: %s", camera)
+		}
+		// 镜头切换作用于正在推送的视频流
+		if target.drone.CurrentVideoID == "" {
+			return errors.New("无人机未在直播，无法切换镜头")
+		}
+		if _, err := s.caller.Call(ctx, target.gatewaySN, dto.MethodLiveLensChange, dto.LiveLensChangeData{
+			VideoID:   target.drone.CurrentVideoID,
+			VideoType: camera,
+		}); err != nil {
+			return err
+		}
+	}
+	if mode == "" {
+		return nil
+	}
+	data := dto.CameraModeSwitchData{PayloadIndex: target.payloadIndex}
+	switch mode {
+	case "photo":
+		data.CameraMode = dto.CameraModePhoto
+	case "video":
+		data.CameraMode = dto.CameraModeVideo
+	default:
+		return fmt.Errorf("无效的相机模式: %s", mode)
+	}
+	_, err = s.caller.Call(ctx, target.gatewaySN, dto.MethodCameraModeSwitch, data)
+	return err
+}
+
+// setZoom 设置变焦镜头的倍数
+func (s *DroneImpl) setZoom(ctx context.Context, sn string, factor float64) error {
+	if factor < zoomFactorMin || factor > zoomFactorMax {
+		return fmt.Errorf("变焦倍数超出范围 [%d, %d]: %v", zoomFactorMin, zoomFactorMax, factor)
+	}
+	target, err := s.resolveControlTarget(ctx, sn)
+	if err != nil {
+		return err
+	}
+	_, err = s.caller.Call(ctx, target.gatewaySN, dto.MethodCameraFocalLenSet, dto.CameraFocalLengthSetData{
+		PayloadIndex: target.payloadIndex,
+		CameraType:   "zoom",
+		ZoomFactor:   factor,
+	})
+	return err
+}
+
+// setGimbalAngle 将云台转到指定角度，返回云台实际到达的俯仰角与偏航角
+//
+// 回中与朝下使用 gimbal_reset；其余角度按最新上报的姿态反复下发 camera_screen_drag，
+// 设备每次只按该速度转动一个控制周期，直到与目标的差值小于 gimbalAngleEpsilon，超时返回错误
+func (s *DroneImpl) setGimbalAngle(ctx context.Context, sn string, pitch, yaw float64) (float64, float64, error) {
+	target, err := s.resolveControlTarget(ctx, sn)
+	if err != nil {
+		return 0, 0, err
+	}
+	if resetMode, ok := gimbalResetMode(pitch, yaw); ok {
+		_, err := s.caller.Call(ctx, target.gatewaySN, dto.MethodGimbalReset, dto.GimbalResetData{
+			PayloadIndex: target.payloadIndex,
+			ResetMode:    resetMode,
+		})
+		return pitch, yaw, err
+	}
+
+	deadline := time.Now().Add(gimbalMoveTimeout)
+	for {
+		currentPitch, currentYaw, err := s.gimbalAttitude(ctx, sn, target.payloadIndex)
+		if err != nil {
+			return 0, 0, err
+		}
+		pitchSpeed, yawSpeed := dragSpeed(pitch-currentPitch), dragSpeed(angleDiff(yaw, currentYaw))
+		if pitchSpeed == 0 && yawSpeed == 0 {
+			return currentPitch, currentYaw, nil
+		}
+		if time.Now().After(deadline) {
+			return currentPitch, currentYaw, fmt.Errorf("云台未能在 %s 内到达目标角度，当前俯仰 %.1f°，偏航 %.1f°",
+				gimbalMoveTimeout, currentPitch, currentYaw)
+		}
+		if _, err := s.caller.Call(ctx, target.gatewaySN, dto.MethodCameraScreenDrag, dto.CameraScreenDragData{
+			PayloadIndex: target.payloadIndex,
+			Locked:       true,
+			PitchSpeed:   pitchSpeed,
+			YawSpeed:     yawSpeed,
+		}); err != nil {
+			return currentPitch, currentYaw, err
+		}
+		select {
+		case <-ctx.Done():
+			return currentPitch, currentYaw, ctx.Err()
+		case <-time.After(gimbalDragInterval):
+		}
+	}
+}
+
+// gimbalResetMode 目标角度为回中或朝下时返回对应的 gimbal_reset 模式
+func gimbalResetMode(pitch, yaw float64) (int, bool) {
+	if math.Abs(yaw) >= gimbalAngleEpsilon {
+		return 0, false
+	}
+	switch {
+	case math.Abs(pitch) < gimbalAngleEpsilon:
+		return dto.GimbalResetModeRecenter, true
+	case math.Abs(pitch+90) < gimbalAngleEpsilon:
+		return dto.GimbalResetModeDown, true
+	}
+	return 0, false
+}
+
+// gimbalAttitude 从无人机实时状态中读取云台当前的俯仰角与偏航角
+func (s *DroneImpl) gimbalAttitude(ctx context.Context, sn, payloadIndex string) (float64, float64, error) {
+	fields, err := s.r.FetchRawStateBySN(ctx, sn)
+	if err != nil {
+		return 0, 0, fmt.Errorf("获取无人机实时状态失败: %w", err)
+	}
+	raw, ok := fields[payloadIndex]
+	if !ok {
+		return 0, 0, fmt.Errorf("未找到云台 %s 的实时姿态", payloadIndex)
+	}
+	var attitude struct {
+		GimbalPitch float64 `json:"gimbal_pitch"`
+		GimbalYaw   float64 `json:"gimbal_yaw"`
+	}
+	if err := json.Unmarshal(raw, &attitude); err != nil {
+		return 0, 0, fmt.Errorf("解析云台姿态失败: %w", err)
+	}
+	return attitude.GimbalPitch, attitude.GimbalYaw, nil
+}
+
+// angleDiff 目标角度与当前角度的差值，换算到 [-180, 180)，偏航角跨越 ±180° 时取较短的方向
+func angleDiff(target, current float64) float64 {
+	return math.Mod(math.Mod(target-current+180, 360)+360, 360) - 180
+}
+
+// dragSpeed 将角度差换算为转速，差值小于阈值时不转动
+func dragSpeed(diff float64) float64 {
+	if math.Abs(diff) < gimbalAngleEpsilon {
+		return 0
+	}
+	speed := diff * math.Pi / 180
+	return math.Max(-gimbalMaxDragSpeed, math.Min(gimbalMaxDragSpeed, speed))
+}
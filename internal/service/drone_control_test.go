@@ -0,0 +1,72 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGimbalResetMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		pitch, yaw float64
+		wantMode   int
+		wantReset  bool
+	}{
+		{"回中", 0, 0, dto.GimbalResetModeRecenter, true},
+		{"接近回中", 0.3, -0.2, dto.GimbalResetModeRecenter, true},
+		{"朝下", -90, 0, dto.GimbalResetModeDown, true},
+		{"接近朝下", -89.7, 0.4, dto.GimbalResetModeDown, true},
+		{"朝下但偏航不为零", -90, 30, 0, false},
+		{"俯仰向上", 90, 0, 0, false},
+		{"任意角度", -45, 0, 0, false},
+		{"仅偏航", 0, 45, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, ok := gimbalResetMode(tt.pitch, tt.yaw)
+			assert.Equal(t, tt.wantReset, ok)
+			if tt.wantReset {
+				assert.Equal(t, tt.wantMode, mode)
+			}
+		})
+	}
+}
+
+func TestDragSpeed(t *testing.T) {
+	tests := []struct {
+		name string
+		diff float64
+		want float64
+	}{
+		{"已到达", 0.4, 0},
+		{"反向已到达", -0.4, 0},
+		{"小角度按比例", 10, 10 * math.Pi / 180},
+		{"反向小角度", -30, -30 * math.Pi / 180},
+		{"大角度限速", 90, gimbalMaxDragSpeed},
+		{"反向大角度限速", -120, -gimbalMaxDragSpeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, dragSpeed(tt.diff), 1e-9)
+		})
+	}
+}
+
+func TestAngleDiff(t *testing.T) {
+	tests := []struct {
+		target, current float64
+		want            float64
+	}{
+		{30, 10, 20},
+		{10, 30, -20},
+		{170, -170, -20},
+		{-170, 170, 20},
+		{0, 0, 0},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, angleDiff(tt.target, tt.current), 1e-9, "angleDiff(%v, %v)", tt.target, tt.current)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/dronesphere/internal/repo"
+)
+
+// propertySpec 可写属性的校验规则
+type propertySpec struct {
+	// validate 校验属性值，返回归一化后的值用于下发
+	validate func(raw json.RawMessage) (any, error)
+	// unsupportedTypes 不支持该属性的机型主类型，为空表示所有机型均支持
+	unsupportedTypes []int
+}
+
+// writableProperties 可通过 property/set 修改的无人机属性
+var writableProperties = map[string]propertySpec{
+	"height_limit": {validate: intRange(20, 1500)}, // 限高，单位：米
+	"night_lights_state": {
+		validate:         intEnum(0, 1),
+		unsupportedTypes: []int{60, 89}, // M300 RTK、M350 RTK 不支持夜航灯
+	},
+	"commander_mode_lost_action": {validate: intEnum(0, 1)}, // 指点飞行失控动作，0: 继续，1: 退出
+	"distance_limit_status": {validate: structOf(map[string]func(json.RawMessage) (any, error){
+		"state":          intEnum(0, 1),
+		"distance_limit": intRange(15, 8000),
+	})},
+	"obstacle_avoidance": {validate: structOf(map[string]func(json.RawMessage) (any, error){
+		"horizon":  intEnum(0, 1),
+		"upside":   intEnum(0, 1),
+		"downside": intEnum(0, 1),
+	})},
+}
+
+func intRange(minValue, maxValue int) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var v int
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("应为整数")
+		}
+		if v < minValue || v > maxValue {
+			return nil, fmt.Errorf("超出范围 [%d, %d]", minValue, maxValue)
+		}
+		return v, nil
+	}
+}
+
+func intEnum(values ...int) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var v int
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("应为整数")
+		}
+		if !slices.Contains(values, v) {
+			return nil, fmt.Errorf("取值应为 %v", values)
+		}
+		return v, nil
+	}
+}
+
+// structOf 校验结构体属性，只允许出现已知字段，可以只设置部分字段
+func structOf(fields map[string]func(json.RawMessage) (any, error)) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("应为对象")
+		}
+		if len(m) == 0 {
+			return nil, fmt.Errorf("至少需要一个字段")
+		}
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			validate, ok := fields[k]
+			if !ok {
+				return nil, fmt.Errorf("不支持的字段 %s", k)
+			}
+			val, err := validate(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s %w", k, err)
+			}
+			out[k] = val
+		}
+		return out, nil
+	}
+}
+
+// SetPropertiesBySN 校验并设置无人机属性，返回每个属性的设备应答结果，0 表示成功
+func (s *DroneImpl) SetPropertiesBySN(ctx context.Context, sn string, props map[string]json.RawMessage) (map[string]int, error) {
+	if len(props) == 0 {
+		return nil, fmt.Errorf("没有需要设置的属性")
+	}
+	drone, err := s.r.SelectBySN(ctx, sn)
+	if err != nil && err.Error() != repo.ErrNoRTData {
+		return nil, fmt.Errorf("获取无人机信息失败: %w", err)
+	}
+	if drone.DroneModelID == 0 {
+		return nil, fmt.Errorf("无人机 %s 型号未知，无法校验属性", sn)
+	}
+	droneModel, err := s.modelRepo.SelectDroneModelByID(ctx, drone.DroneModelID)
+	if err != nil {
+		return nil, fmt.Errorf("获取无人机型号失败: %w", err)
+	}
+
+	data := make(map[string]any, len(props))
+	for key, raw := range props {
+		spec, ok := writableProperties[key]
+		if !ok {
+			return nil, fmt.Errorf("属性 %s 不可写", key)
+		}
+		if slices.Contains(spec.unsupportedTypes, droneModel.Type) {
+			return nil, fmt.Errorf("%s 不支持属性 %s", droneModel.Name, key)
+		}
+		val, err := spec.validate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("属性 %s %w", key, err)
+		}
+		data[key] = val
+	}
+
+	results, err := s.caller.SetProperty(ctx, sn, data)
+	if err != nil {
+		s.l.Error("设置无人机属性失败", slog.String("sn", sn), slog.Any("error", err))
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/pkg/servicecall"
+	"github.com/dronesphere/internal/repo"
+	"github.com/google/uuid"
+)
+
+type FirmwareSvc interface {
+	// Upload 上传并登记固件包
+	Upload(ctx context.Context, firmware *po.Firmware, file io.Reader, size int64) error
+	List(ctx context.Context, modelKey string) ([]po.Firmware, error)
+	Delete(ctx context.Context, id uint) error
+	// CreateUpgrade 向设备下发 ota_create，按设备所在网关分组下发，返回升级任务
+	CreateUpgrade(ctx context.Context, firmwareID uint, params dto.FirmwareUpgradeParams) (*po.FirmwareUpgradeJob, error)
+	// HandleOTAProgress 处理网关上报的 ota_progress 事件，更新同一 bid 下的设备升级状态
+	HandleOTAProgress(ctx context.Context, gatewaySN, bid string, data dto.OTAProgressData) error
+	ListUpgradeJobs(ctx context.Context) ([]po.FirmwareUpgradeJob, error)
+	FetchUpgradeJob(ctx context.Context, id uint) (*po.FirmwareUpgradeJob, error)
+}
+
+type FirmwareRepo interface {
+	SaveFirmware(ctx context.Context, firmware *po.Firmware, file io.Reader, size int64) error
+	SelectFirmwares(ctx context.Context, modelKey string) ([]po.Firmware, error)
+	SelectFirmwareByID(ctx context.Context, id uint) (*po.Firmware, error)
+	DeleteFirmwareByID(ctx context.Context, id uint) error
+	SaveUpgradeJob(ctx context.Context, job *po.FirmwareUpgradeJob) error
+	SaveUpgradeDevice(ctx context.Context, device *po.FirmwareUpgradeDevice) error
+	SelectUpgradeJobs(ctx context.Context) ([]po.FirmwareUpgradeJob, error)
+	SelectUpgradeJobByID(ctx context.Context, id uint) (*po.FirmwareUpgradeJob, error)
+	SelectUpgradeDevicesByBID(ctx context.Context, bid string) ([]po.FirmwareUpgradeDevice, error)
+	UpdateUpgradeJobStatus(ctx context.Context, id uint, status int) error
+	// PresignDownloadURL 生成固件文件的临时下载链接，设备通过该链接下载固件
+	PresignDownloadURL(ctx context.Context, key string) (string, error)
+}
+
+type FirmwareImpl struct {
+	repo        FirmwareRepo
+	droneRepo   DroneRepo
+	gatewayRepo repo.GatewayRepo
+	caller      *servicecall.Client
+	l           *slog.Logger
+}
+
+func NewFirmwareImpl(repo FirmwareRepo, droneRepo DroneRepo, gatewayRepo repo.GatewayRepo, caller *servicecall.Client, l *slog.Logger) FirmwareSvc {
+	return &FirmwareImpl{
+		repo:        repo,
+		droneRepo:   droneRepo,
+		gatewayRepo: gatewayRepo,
+		caller:      caller,
+		l:           l,
+	}
+}
+
+// modelKey 设备型号标识，格式与 DJI 文档中的 device_type 一致
+func modelKey(domain, ttype, subType int) string {
+	return fmt.Sprintf("%d-%d-%d", domain, ttype, subType)
+}
+
+func (s *FirmwareImpl) Upload(ctx context.Context, firmware *po.Firmware, file io.Reader, size int64) error {
+	return s.repo.SaveFirmware(ctx, firmware, file, size)
+}
+
+func (s *FirmwareImpl) List(ctx context.Context, modelKey string) ([]po.Firmware, error) {
+	return s.repo.SelectFirmwares(ctx, modelKey)
+}
+
+func (s *FirmwareImpl) Delete(ctx context.Context, id uint) error {
+	return s.repo.DeleteFirmwareByID(ctx, id)
+}
+
+// resolveDevice 解析设备的型号、所在网关和当前固件版本
+// 无人机通过拓扑关系找到挂载的网关，网关设备由自身下发
+func (s *FirmwareImpl) resolveDevice(ctx context.Context, sn string) (key, gatewaySN, version string, err error) {
+	// 无人机离线时实时状态可能不存在，只要持久化记录存在即视为无人机
+	if drone, _ := s.droneRepo.SelectBySN(ctx, sn); drone.ID > 0 {
+		gatewaySN, err := s.droneRepo.FetchGatewaySNByDroneSN(ctx, sn)
+		if err != nil || gatewaySN == "" {
+			return "", "", "", errors.New("无人机未连接网关")
+		}
+		m := drone.DroneModel
+		return modelKey(m.Domain, m.Type, m.SubType), gatewaySN, drone.FirmwareVersion, nil
+	}
+
+	gateway, err := s.gatewayRepo.SelectBySN(ctx, sn)
+	if err != nil {
+		return "", "", "", errors.New("设备不存在")
+	}
+	m := gateway.GatewayModel
+	state, _ := s.gatewayRepo.FetchStateBySN(ctx, sn)
+	return modelKey(m.Domain, m.Type, m.SubType), sn, state.FirmwareVersion, nil
+}
+
+func (s *FirmwareImpl) CreateUpgrade(ctx context.Context, firmwareID uint, params dto.FirmwareUpgradeParams) (*po.FirmwareUpgradeJob, error) {
+	if len(params.SNs) == 0 {
+		return nil, errors.New("未指定升级设备")
+	}
+	firmware, err := s.repo.SelectFirmwareByID(ctx, firmwareID)
+	if err != nil {
+		return nil, errors.New("固件不存在")
+	}
+	upgradeType := params.FirmwareUpgradeType
+	if upgradeType == 0 {
+		upgradeType = dto.FirmwareUpgradeTypeNormal
+	}
+	fileURL, err := s.repo.PresignDownloadURL(ctx, firmware.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("生成固件下载链接失败: %w", err)
+	}
+
+	job := &po.FirmwareUpgradeJob{
+		FirmwareID:  firmware.ID,
+		Version:     firmware.Version,
+		UpgradeType: upgradeType,
+		Status:      po.FirmwareUpgradeJobStatusRunning,
+	}
+	// 按网关分组，同一网关下的设备合并为一次 ota_create
+	groups := make(map[string][]int)
+	for _, sn := range params.SNs {
+		device := po.FirmwareUpgradeDevice{SN: sn, Status: dto.OTAStatusSent}
+		key, gatewaySN, version, err := s.resolveDevice(ctx, sn)
+		switch {
+		case err != nil:
+			device.Status = dto.OTAStatusFailed
+			device.Message = err.Error()
+		case key != firmware.ModelKey:
+			device.Status = dto.OTAStatusRejected
+			device.Message = fmt.Sprintf("设备型号 %s 与固件型号 %s 不匹配", key, firmware.ModelKey)
+		default:
+			device.GatewaySN = gatewaySN
+			device.FromVersion = version
+			groups[gatewaySN] = append(groups[gatewaySN], len(job.Devices))
+		}
+		job.Devices = append(job.Devices, device)
+	}
+	if len(groups) == 0 {
+		job.Status = po.FirmwareUpgradeJobStatusFinished
+	}
+	if err := s.repo.SaveUpgradeJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	for gatewaySN, indexes := range groups {
+		bid := uuid.New().String()
+		data := dto.OTACreateData{}
+		for _, i := range indexes {
+			data.Devices = append(data.Devices, dto.OTADevice{
+				SN:                  job.Devices[i].SN,
+				ProductVersion:      firmware.Version,
+				FileURL:             fileURL,
+				MD5:                 firmware.MD5,
+				FileSize:            firmware.FileSize,
+				FileName:            firmware.FileName,
+				FirmwareUpgradeType: upgradeType,
+			})
+		}
+
+		_, err := s.caller.CallWithBID(ctx, gatewaySN, bid, dto.MethodOTACreate, data)
+		for _, i := range indexes {
+			device := &job.Devices[i]
+			device.BID = bid
+			if err != nil {
+				device.Status = dto.OTAStatusFailed
+				device.Message = err.Error()
+				var re *servicecall.ResultError
+				if errors.As(err, &re) {
+					device.Result = re.Result
+				}
+			}
+			if err := s.repo.SaveUpgradeDevice(ctx, device); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			s.l.Error("下发固件升级失败", slog.String("gatewaySN", gatewaySN), slog.Any("err", err))
+			continue
+		}
+		s.l.Info("已下发固件升级", slog.String("gatewaySN", gatewaySN), slog.String("bid", bid), slog.Int("count", len(indexes)))
+	}
+	s.refreshJobStatus(ctx, job.ID)
+
+	return s.repo.SelectUpgradeJobByID(ctx, job.ID)
+}
+
+// otaFinished 升级状态是否为终态
+func otaFinished(status string) bool {
+	switch status {
+	case dto.OTAStatusOK, dto.OTAStatusRejected, dto.OTAStatusFailed, dto.OTAStatusCanceled, dto.OTAStatusTimeout:
+		return true
+	}
+	return false
+}
+
+func (s *FirmwareImpl) HandleOTAProgress(ctx context.Context, gatewaySN, bid string, data dto.OTAProgressData) error {
+	devices, err := s.repo.SelectUpgradeDevicesByBID(ctx, bid)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return errors.New("未找到对应的固件升级记录: " + bid)
+	}
+
+	for i := range devices {
+		device := &devices[i]
+		if otaFinished(device.Status) {
+			continue
+		}
+		device.Status = data.Output.Status
+		device.Percent = data.Output.Progress.Percent
+		device.CurrentStep = data.Output.Progress.CurrentStep
+		device.Result = data.Result
+		if err := s.repo.SaveUpgradeDevice(ctx, device); err != nil {
+			return err
+		}
+	}
+	s.l.Info("固件升级进度", slog.String("gatewaySN", gatewaySN), slog.String("bid", bid),
+		slog.String("status", data.Output.Status), slog.Int("percent", data.Output.Progress.Percent))
+
+	s.refreshJobStatus(ctx, devices[0].UpgradeJobID)
+	return nil
+}
+
+// refreshJobStatus 所有设备均进入终态后将升级任务标记为已结束
+func (s *FirmwareImpl) refreshJobStatus(ctx context.Context, jobID uint) {
+	job, err := s.repo.SelectUpgradeJobByID(ctx, jobID)
+	if err != nil || job.Status == po.FirmwareUpgradeJobStatusFinished {
+		return
+	}
+	for _, device := range job.Devices {
+		if !otaFinished(device.Status) {
+			return
+		}
+	}
+	if err := s.repo.UpdateUpgradeJobStatus(ctx, jobID, po.FirmwareUpgradeJobStatusFinished); err != nil {
+		s.l.Error("更新固件升级任务状态失败", slog.Any("jobID", jobID), slog.Any("err", err))
+	}
+}
+
+func (s *FirmwareImpl) ListUpgradeJobs(ctx context.Context) ([]po.FirmwareUpgradeJob, error) {
+	return s.repo.SelectUpgradeJobs(ctx)
+}
+
+func (s *FirmwareImpl) FetchUpgradeJob(ctx context.Context, id uint) (*po.FirmwareUpgradeJob, error) {
+	return s.repo.SelectUpgradeJobByID(ctx, id)
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"log/slog"
 
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/ro"
 	"github.com/dronesphere/internal/repo"
 )
 
@@ -10,6 +13,8 @@ type (
 	// GatewaySvc 网关设备服务接口
 	GatewaySvc interface {
 		Repo() repo.GatewayRepo
+		// UpdateStateBySN 更新网关实时数据状态
+		UpdateStateBySN(ctx context.Context, sn string, msg dto.GatewayOSDData) error
 	}
 
 	// GatewayImpl 网关设备服务实现
@@ -31,3 +36,17 @@ func NewGatewayImpl(repo repo.GatewayRepo, l *slog.Logger) GatewaySvc {
 func (s *GatewayImpl) Repo() repo.GatewayRepo {
 	return s.repo
 }
+
+// UpdateStateBySN 更新网关实时数据状态
+func (s *GatewayImpl) UpdateStateBySN(ctx context.Context, sn string, msg dto.GatewayOSDData) error {
+	state := ro.Gateway{
+		SN:             sn,
+		Status:         ro.DroneStatusOnline,
+		GatewayOSDData: msg,
+	}
+	if err := s.repo.SaveState(ctx, state); err != nil {
+		s.l.Error("保存网关实时数据失败", slog.Any("sn", sn), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
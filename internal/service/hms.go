@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/repo"
+)
+
+type HMSSvc interface {
+	// HandleHMS 处理网关上报的 hms 事件，按设备保存告警
+	HandleHMS(ctx context.Context, gatewaySN string, data dto.HMSData) error
+	// ListAlerts 获取设备告警，activeOnly 为 true 时只返回未确认且未恢复的告警
+	ListAlerts(ctx context.Context, sn string, activeOnly bool) ([]po.HMSAlert, error)
+	// Acknowledge 确认设备告警，ids 为空时确认全部未确认告警
+	Acknowledge(ctx context.Context, sn string, ids []uint) (int64, error)
+	// CountActive 统计多个设备未确认且未恢复的告警数量
+	CountActive(ctx context.Context, sns []string) (map[string]int64, error)
+}
+
+type HMSRepo interface {
+	SaveAlert(ctx context.Context, alert *po.HMSAlert) error
+	SelectAlertsBySN(ctx context.Context, sn string, activeOnly bool) ([]po.HMSAlert, error)
+	// SelectCurrentAlertsBySN 获取设备当前仍在上报、尚未恢复的告警
+	SelectCurrentAlertsBySN(ctx context.Context, sn string) ([]po.HMSAlert, error)
+	// ResolveAlerts 将网关上报的、不在 keep 中的告警标记为已恢复
+	ResolveAlerts(ctx context.Context, gatewaySN string, keep []uint) (int64, error)
+	AcknowledgeAlerts(ctx context.Context, sn string, ids []uint) (int64, error)
+	CountActiveBySNs(ctx context.Context, sns []string) (map[string]int64, error)
+}
+
+type HMSImpl struct {
+	repo        HMSRepo
+	gatewayRepo repo.GatewayRepo
+	l           *slog.Logger
+}
+
+func NewHMSImpl(repo HMSRepo, gatewayRepo repo.GatewayRepo, l *slog.Logger) HMSSvc {
+	return &HMSImpl{
+		repo:        repo,
+		gatewayRepo: gatewayRepo,
+		l:           l,
+	}
+}
+
+// HandleHMS 处理网关上报的 hms 事件
+// device_type 的 domain 为 0 时告警属于网关当前挂载的无人机，否则属于网关本身。
+// 每次上报包含网关及其无人机当前的全部告警，未出现在本次上报中的告警标记为已恢复
+func (s *HMSImpl) HandleHMS(ctx context.Context, gatewaySN string, data dto.HMSData) error {
+	now := time.Now()
+	droneSN := ""
+	var current []uint
+	for _, item := range data.List {
+		sn := gatewaySN
+		if strings.HasPrefix(item.DeviceType, "0-") {
+			if droneSN == "" {
+				drones, err := s.gatewayRepo.GetConnectedDrones(ctx, gatewaySN)
+				if err != nil {
+					return err
+				}
+				if len(drones) == 0 {
+					return errors.New("网关未挂载无人机: " + gatewaySN)
+				}
+				droneSN = drones[0].SN
+			}
+			sn = droneSN
+		}
+
+		alert := &po.HMSAlert{
+			SN:             sn,
+			GatewaySN:      gatewaySN,
+			Code:           item.Code,
+			Level:          item.Level,
+			Module:         item.Module,
+			DeviceType:     item.DeviceType,
+			ComponentIndex: item.Args.ComponentIndex,
+			SensorIndex:    item.Args.SensorIndex,
+			InTheSky:       item.InTheSky == 1,
+			Imminent:       item.Imminent == 1,
+			LastSeenAt:     now,
+		}
+		if err := s.repo.SaveAlert(ctx, alert); err != nil {
+			return err
+		}
+		current = append(current, alert.ID)
+	}
+	resolved, err := s.repo.ResolveAlerts(ctx, gatewaySN, current)
+	if err != nil {
+		return err
+	}
+	s.l.Info("HMS 告警已保存", slog.String("gatewaySN", gatewaySN), slog.Int("count", len(data.List)),
+		slog.Int64("resolved", resolved))
+	return nil
+}
+
+func (s *HMSImpl) ListAlerts(ctx context.Context, sn string, activeOnly bool) ([]po.HMSAlert, error) {
+	return s.repo.SelectAlertsBySN(ctx, sn, activeOnly)
+}
+
+func (s *HMSImpl) Acknowledge(ctx context.Context, sn string, ids []uint) (int64, error) {
+	return s.repo.AcknowledgeAlerts(ctx, sn, ids)
+}
+
+func (s *HMSImpl) CountActive(ctx context.Context, sns []string) (map[string]int64, error) {
+	return s.repo.CountActiveBySNs(ctx, sns)
+}
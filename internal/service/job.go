@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
@@ -12,6 +14,7 @@ import (
 	"github.com/dronesphere/internal/model/entity"
 	"github.com/dronesphere/internal/model/po"
 	"github.com/dronesphere/internal/model/vo"
+	"github.com/dronesphere/internal/pkg/servicecall"
 	"github.com/dronesphere/pkg/coordinate"
 	"github.com/dronesphere/pkg/wpml"
 	"github.com/jinzhu/copier"
@@ -24,9 +27,28 @@ type (
 		FetchByID(ctx context.Context, id uint) (*entity.Job, error)
 		FetchAvailableAreas(ctx context.Context) ([]*entity.Area, error)
 		FetchAvailableDrones(ctx context.Context) ([]entity.Drone, error)
-		FetchAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string) ([]entity.Job, error)
+		// FetchAll 查询任务，statuses 不为空时只返回处于这些状态的任务
+		FetchAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string, statuses []int) ([]entity.Job, error)
 		CreateJob(ctx context.Context, name, description string, areaID uint, scheduleTime time.Time, drones []po.JobDronePO, waylines []po.JobWaylinePO, command_drones []po.JobCommandDronePO, waylineGenerationParams po.JobWaylineGenerationParams) (uint, error)
 		ModifyJob(ctx context.Context, id uint, name, description string, areaID uint, scheduleTime time.Time, drones []po.JobDronePO, waylines []po.JobWaylinePO, command_drones []po.JobCommandDronePO, waylineGenerationParams po.JobWaylineGenerationParams) (*entity.Job, error)
+		// DispatchJob 将任务的航线下发到各无人机并开始执行
+		DispatchJob(ctx context.Context, id uint, params dto.JobDispatchParams) ([]po.JobExecution, error)
+		FetchExecutions(ctx context.Context, id uint) ([]po.JobExecution, error)
+		// HandleFlighttaskProgress 记录设备上报的航线执行进度，返回更新后的执行记录
+		HandleFlighttaskProgress(ctx context.Context, gatewaySN string, data dto.FlighttaskProgressData) (*po.JobExecution, error)
+		FetchExecutionProgresses(ctx context.Context, executionID uint) ([]po.JobExecutionProgress, error)
+		// ControlFlighttask 对无人机正在执行的航线下发暂停、恢复、终止、返航等控制指令
+		ControlFlighttask(ctx context.Context, droneSN, action string) error
+		FetchExecutionCommands(ctx context.Context, executionID uint) ([]po.JobExecutionCommand, error)
+		// TransitionJob 校验并切换任务的生命周期状态，记录状态历史
+		TransitionJob(ctx context.Context, id uint, to int, reason string) error
+		// CancelJob 取消任务，正在执行的航线会被终止
+		CancelJob(ctx context.Context, id uint) error
+		// RecoverDispatchingJobs 将服务重启前中断在下发中的任务标记为失败
+		RecoverDispatchingJobs(ctx context.Context) error
+		FetchStatusHistories(ctx context.Context, id uint) ([]po.JobStatusHistory, error)
+		// CheckReadiness 下发前检查任务中每架无人机的状态，返回逐机的检查报告
+		CheckReadiness(ctx context.Context, id uint) (*dto.JobReadinessReport, error)
 	}
 
 	JobRepo interface {
@@ -34,10 +56,25 @@ type (
 		DeleteByID(ctx context.Context, id uint) error
 		FetchPOByID(ctx context.Context, id uint) (*po.Job, error)
 		SelectByID(ctx context.Context, id uint) (*po.Job, error)
-		SelectAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string) ([]po.Job, error)
+		SelectAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string, statuses []int) ([]po.Job, error)
 		SelectPhysicalDrones(ctx context.Context) ([]dto.PhysicalDrone, error)
 		SaveWayline(ctx context.Context, wayline po.Wayline, kmzFile string) (*po.Wayline, error)
 		SaveWaylineAndKmzKey(ctx context.Context, wayline po.Wayline, kmzKey string) (*po.Wayline, error)
+		FetchWaylineFingerprint(ctx context.Context, wayline po.Wayline) (string, error)
+		SaveExecution(ctx context.Context, execution *po.JobExecution) error
+		SelectExecutionByFlightID(ctx context.Context, flightID string) (*po.JobExecution, error)
+		SelectExecutionsByJobID(ctx context.Context, jobID uint) ([]po.JobExecution, error)
+		SaveExecutionProgress(ctx context.Context, progress *po.JobExecutionProgress) error
+		SelectExecutionProgresses(ctx context.Context, executionID uint) ([]po.JobExecutionProgress, error)
+		SelectActiveExecutionByDroneSN(ctx context.Context, droneSN string) (*po.JobExecution, error)
+		SaveExecutionCommand(ctx context.Context, command *po.JobExecutionCommand) error
+		SelectExecutionCommands(ctx context.Context, executionID uint) ([]po.JobExecutionCommand, error)
+		SelectScheduledJobs(ctx context.Context) ([]po.Job, error)
+		UpdateJobSchedule(ctx context.Context, id uint, cronExpr, missedRunPolicy string) error
+		UpdateJobLastRunTime(ctx context.Context, id uint, t time.Time) error
+		UpdateJobStatus(ctx context.Context, history *po.JobStatusHistory) (bool, error)
+		SelectJobStatusHistories(ctx context.Context, jobID uint) ([]po.JobStatusHistory, error)
+		SelectLastJobStatusHistory(ctx context.Context, jobID uint, toStatus int) (*po.JobStatusHistory, error)
 	}
 )
 
@@ -48,10 +85,12 @@ type JobImpl struct {
 	modelRepo   ModelRepo
 	waylineRepo WaylineRepo
 	waylineSvc  WaylineSvc
+	hmsRepo     HMSRepo
 	l           *slog.Logger
+	caller      *servicecall.Client
 }
 
-func NewJobImpl(jobRepo JobRepo, areaRepo AreaRepo, droneRepo DroneRepo, modelRepo ModelRepo, waylineRepo WaylineRepo, waylineSvc WaylineSvc, l *slog.Logger) *JobImpl {
+func NewJobImpl(jobRepo JobRepo, areaRepo AreaRepo, droneRepo DroneRepo, modelRepo ModelRepo, waylineRepo WaylineRepo, waylineSvc WaylineSvc, hmsRepo HMSRepo, l *slog.Logger, caller *servicecall.Client) *JobImpl {
 	return &JobImpl{
 		jobRepo:     jobRepo,
 		areaRepo:    areaRepo,
@@ -59,7 +98,9 @@ func NewJobImpl(jobRepo JobRepo, areaRepo AreaRepo, droneRepo DroneRepo, modelRe
 		modelRepo:   modelRepo,
 		waylineRepo: waylineRepo,
 		waylineSvc:  waylineSvc,
+		hmsRepo:     hmsRepo,
 		l:           l,
+		caller:      caller,
 	}
 }
 
@@ -134,8 +175,12 @@ func (j *JobImpl) FetchByID(ctx context.Context, id uint) (*entity.Job, error) {
 	entity.Area = *areaEntity
 	entity.ID = job.ID
 	entity.Name = job.Name
+	entity.Status = job.Status
 	entity.Description = job.Description
 	entity.ScheduleTime = job.ScheduleTime
+	entity.CronExpr = job.CronExpr
+	entity.MissedRunPolicy = job.MissedRunPolicy
+	entity.LastRunTime = job.LastRunTime
 	var (
 		wg      sync.WaitGroup
 		mu      sync.Mutex
@@ -287,6 +332,12 @@ func (j *JobImpl) FetchDroneEntity(ctx context.Context, jobID uint, dronePO po.J
 }
 
 func (j *JobImpl) CreateJob(ctx context.Context, name, description string, areaID uint, scheduleTime time.Time, drones []po.JobDronePO, waylines []po.JobWaylinePO, commandDrones []po.JobCommandDronePO, waylineGenerationParams po.JobWaylineGenerationParams) (uint, error) {
+	// 未指定航点的航线由服务端在任务区域内规划
+	waylines, err := j.planCoverageWaylines(ctx, areaID, drones, waylines, waylineGenerationParams)
+	if err != nil {
+		j.l.Error("规划覆盖航线失败", slog.Any("error", err))
+		return 0, err
+	}
 	job := &po.Job{
 		Name:                    name,
 		Description:             description,
@@ -350,6 +401,21 @@ func (j *JobImpl) ModifyJob(ctx context.Context, id uint, name, description stri
 	if err != nil {
 		return nil, err
 	}
+	switch p.Status {
+	case po.JobStatusDispatching, po.JobStatusExecuting, po.JobStatusPaused:
+		return nil, fmt.Errorf("任务%s，不能修改", po.JobStatusNames[p.Status])
+	case po.JobStatusCompleted, po.JobStatusFailed, po.JobStatusCancelled:
+		// 修改后的任务回到草稿，由调度重新决定是否等待执行
+		if err := j.transitionJob(ctx, p, po.JobStatusDraft, "修改任务"); err != nil {
+			return nil, err
+		}
+	}
+
+	waylines, err = j.planCoverageWaylines(ctx, areaID, drones, waylines, waylineGenerationParams)
+	if err != nil {
+		j.l.Error("规划覆盖航线失败", slog.Any("error", err))
+		return nil, err
+	}
 
 	oldWaylines := p.Waylines
 	// 更新任务信息
@@ -474,9 +540,9 @@ func (j *JobImpl) ModifyJob(ctx context.Context, id uint, name, description stri
 	return j.FetchByID(ctx, id)
 }
 
-func (j *JobImpl) FetchAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string) ([]entity.Job, error) {
+func (j *JobImpl) FetchAll(ctx context.Context, jobName, areaName string, scheduleTimeStart, scheduleTimeEnd string, statuses []int) ([]entity.Job, error) {
 	// 调用时传递空字符串作为时间参数，表示不按时间筛选
-	jobs, err := j.jobRepo.SelectAll(ctx, jobName, areaName, scheduleTimeStart, scheduleTimeEnd)
+	jobs, err := j.jobRepo.SelectAll(ctx, jobName, areaName, scheduleTimeStart, scheduleTimeEnd, statuses)
 	if err != nil {
 		return nil, err
 	}
@@ -832,7 +898,6 @@ func (j *JobImpl) generateWorkerWayline(ctx context.Context, droneID uint, drone
 			WaypointTurnParam:     nil,
 			UseStraightLine:       &trueBool,
 			GimbalPitchAngle:      -90,
-			ActionGroup:           nil,
 		}
 		if idx == 0 {
 			var actions []wpml.Action
@@ -884,13 +949,26 @@ func (j *JobImpl) generateWorkerWayline(ctx context.Context, droneID uint, drone
 					ActionTrigger:         wpml.ActionTrigger{TriggerType: wpml.TriggerReachPoint},
 				}
 				actionGroup.Actions = actions
-				placemark.ActionGroup = actionGroup
+				placemark.ActionGroups = append(placemark.ActionGroups, *actionGroup)
 			}
 		}
 
 		folder.Placemarks = append(folder.Placemarks, placemark)
 	}
 
+	// 设置了重叠率时按相机视场计算拍照间距，在整条航线上等距拍照
+	if params.OverlapRate > 0 && len(folder.Placemarks) > 1 {
+		camera, _, cameraErr := coverageCamera(droneVariation)
+		_, distance, err := coverageSpacing(droneVariation, params)
+		if cameraErr != nil || err != nil {
+			j.l.Warn("无法计算拍照间距，航线不包含拍照动作", slog.Any("error", errors.Join(cameraErr, err)))
+		} else {
+			first := &folder.Placemarks[0]
+			group := photoActionGroup(len(first.ActionGroups), 0, len(folder.Placemarks)-1, gimbals[0], camera, distance)
+			first.ActionGroups = append(first.ActionGroups, group)
+		}
+	}
+
 	doc.Folders = append(doc.Folders, folder)
 
 	return doc, nil
@@ -1024,7 +1102,6 @@ func (j *JobImpl) generateCommandWayline(ctx context.Context, droneID uint, dron
 		WaypointTurnParam:     nil,
 		UseStraightLine:       &trueBool,
 		GimbalPitchAngle:      -90,
-		ActionGroup:           nil,
 	}
 	folder.Placemarks = append(folder.Placemarks, takeOffPlacemark)
 
@@ -1043,7 +1120,6 @@ func (j *JobImpl) generateCommandWayline(ctx context.Context, droneID uint, dron
 		WaypointTurnParam:     nil,
 		UseStraightLine:       &trueBool,
 		GimbalPitchAngle:      -90,
-		ActionGroup:           nil,
 	}
 	hoverAction := wpml.Action{
 		ActionType: wpml.ActionHover,
@@ -1060,7 +1136,7 @@ func (j *JobImpl) generateCommandWayline(ctx context.Context, droneID uint, dron
 		ActionTrigger:         wpml.ActionTrigger{TriggerType: wpml.TriggerReachPoint},
 		Actions:               actions,
 	}
-	commandPlacemark.ActionGroup = ag
+	commandPlacemark.ActionGroups = append(commandPlacemark.ActionGroups, *ag)
 	folder.Placemarks = append(folder.Placemarks, commandPlacemark)
 
 	doc.Folders = append(doc.Folders, folder)
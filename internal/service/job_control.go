@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/pkg/servicecall"
+)
+
+// flighttaskCommand 航线控制动作对应的设备方法与允许下发的飞行模式
+type flighttaskCommand struct {
+	method string
+	modes  []int
+	// needExecution 为 true 时要求无人机有正在执行的航线任务
+	needExecution bool
+}
+
+// airborneModes 空中可返航的飞行模式，不含已在返航、降落中的模式
+var airborneModes = []int{
+	dto.ModeCodeManualFlight, dto.ModeCodeAutoTakeoff, dto.ModeCodeRouteFlight, dto.ModeCodePanoramaPhoto,
+	dto.ModeCodeIntelligentFollow, dto.ModeCodeADSBEvasion, dto.ModeCodeAPAS, dto.ModeCodeVirtualJoystick,
+	dto.ModeCodeCommandFlight,
+}
+
+var flighttaskCommands = map[string]flighttaskCommand{
+	dto.FlighttaskActionPause: {
+		method:        dto.MethodFlighttaskPause,
+		modes:         []int{dto.ModeCodeAutoTakeoff, dto.ModeCodeRouteFlight},
+		needExecution: true,
+	},
+	// 航线暂停后飞行器悬停，飞行模式可能为航线飞行或手动飞行
+	dto.FlighttaskActionResume: {
+		method:        dto.MethodFlighttaskRecovery,
+		modes:         []int{dto.ModeCodeRouteFlight, dto.ModeCodeManualFlight},
+		needExecution: true,
+	},
+	dto.FlighttaskActionFinish: {
+		method:        dto.MethodFlighttaskStop,
+		modes:         []int{dto.ModeCodeAutoTakeoff, dto.ModeCodeRouteFlight, dto.ModeCodeManualFlight, dto.ModeCodePanoramaPhoto},
+		needExecution: true,
+	},
+	dto.FlighttaskActionReturn: {
+		method: dto.MethodReturnHome,
+		modes:  airborneModes,
+	},
+	dto.FlighttaskActionCancelReturn: {
+		method: dto.MethodReturnHomeCancel,
+		modes:  []int{dto.ModeCodeAutoReturn},
+	},
+}
+
+// ControlFlighttask 校验无人机当前飞行模式后下发航线控制指令
+// 无人机有正在执行的航线任务时，指令无论成功与否都会记录到该次执行的指令历史中
+func (j *JobImpl) ControlFlighttask(ctx context.Context, droneSN, action string) error {
+	command, ok := flighttaskCommands[action]
+	if !ok {
+		return fmt.Errorf("无效的航线控制动作: %s", action)
+	}
+
+	execution, err := j.jobRepo.SelectActiveExecutionByDroneSN(ctx, droneSN)
+	if err != nil && command.needExecution {
+		return errors.New("无人机没有正在执行的航线任务")
+	}
+
+	record := po.JobExecutionCommand{
+		DroneSN: droneSN,
+		Action:  action,
+		Method:  command.method,
+		Result:  -1,
+	}
+	err = j.sendFlighttaskCommand(ctx, droneSN, action, command, execution, &record)
+	if err != nil {
+		record.Message = err.Error()
+		var re *servicecall.ResultError
+		if errors.As(err, &re) {
+			record.Result = re.Result
+		}
+	} else {
+		record.Result = 0
+	}
+	if execution != nil {
+		record.ExecutionID = execution.ID
+		record.JobID = execution.JobID
+		record.FlightID = execution.FlightID
+		if sErr := j.jobRepo.SaveExecutionCommand(ctx, &record); sErr != nil {
+			j.l.Error("保存航线控制指令失败", slog.String("droneSN", droneSN), slog.Any("error", sErr))
+		}
+	}
+	if err != nil {
+		j.l.Error("航线控制指令失败", slog.String("droneSN", droneSN), slog.String("action", action), slog.Any("error", err))
+		return err
+	}
+	j.l.Info("航线控制指令已执行", slog.String("droneSN", droneSN), slog.String("action", action))
+	if execution != nil {
+		j.applyFlighttaskCommand(ctx, execution, action)
+	}
+	return nil
+}
+
+// applyFlighttaskCommand 设备接受暂停、恢复指令后更新执行记录并同步任务状态
+// 终止、返航的结果以设备后续的进度上报为准
+func (j *JobImpl) applyFlighttaskCommand(ctx context.Context, execution *po.JobExecution, action string) {
+	switch action {
+	case dto.FlighttaskActionPause:
+		execution.ProgressStatus = dto.FlighttaskStatusPaused
+	case dto.FlighttaskActionResume:
+		execution.ProgressStatus = dto.FlighttaskStatusInProgress
+	default:
+		return
+	}
+	if err := j.jobRepo.SaveExecution(ctx, execution); err != nil {
+		j.l.Error("保存任务执行记录失败", slog.Any("error", err))
+		return
+	}
+	j.syncJobStatus(ctx, execution.JobID, "航线控制: "+action)
+}
+
+// sendFlighttaskCommand 校验飞行模式并调用设备方法，record 中填入下发时的飞行模式
+func (j *JobImpl) sendFlighttaskCommand(ctx context.Context, droneSN, action string, command flighttaskCommand, execution *po.JobExecution, record *po.JobExecutionCommand) error {
+	modeCode, err := j.droneModeCode(ctx, droneSN)
+	if err != nil {
+		return err
+	}
+	record.ModeCode = modeCode
+	if !slices.Contains(command.modes, modeCode) {
+		return fmt.Errorf("无人机当前处于%s模式，无法执行 %s", dto.ModeCodeMap[modeCode], action)
+	}
+	if action == dto.FlighttaskActionResume && execution.ProgressStatus != dto.FlighttaskStatusPaused {
+		return errors.New("航线未暂停，无需恢复")
+	}
+
+	gatewaySN, err := j.droneRepo.FetchGatewaySNByDroneSN(ctx, droneSN)
+	if err != nil || gatewaySN == "" {
+		return errors.New("无人机未连接网关")
+	}
+	_, err = j.caller.Call(ctx, gatewaySN, command.method, struct{}{})
+	return err
+}
+
+// droneModeCode 从无人机实时状态中读取飞行模式
+func (j *JobImpl) droneModeCode(ctx context.Context, droneSN string) (int, error) {
+	fields, err := j.droneRepo.FetchRawStateBySN(ctx, droneSN)
+	if err != nil {
+		return 0, fmt.Errorf("获取无人机实时状态失败: %w", err)
+	}
+	raw, ok := fields["mode_code"]
+	if !ok {
+		return 0, errors.New("无人机未上报飞行模式")
+	}
+	var modeCode int
+	if err := json.Unmarshal(raw, &modeCode); err != nil {
+		return 0, fmt.Errorf("解析飞行模式失败: %w", err)
+	}
+	return modeCode, nil
+}
+
+// FetchExecutionCommands 获取单次执行的控制指令历史
+func (j *JobImpl) FetchExecutionCommands(ctx context.Context, executionID uint) ([]po.JobExecutionCommand, error) {
+	return j.jobRepo.SelectExecutionCommands(ctx, executionID)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/model/vo"
+	"github.com/dronesphere/pkg/coverage"
+	"github.com/dronesphere/pkg/wpml"
+)
+
+// coverageCamera 选取无人机主云台上用于测绘的相机，优先广角相机
+func coverageCamera(variation po.DroneVariation) (po.CameraModel, coverage.Camera, error) {
+	if len(variation.Gimbals) == 0 {
+		return po.CameraModel{}, coverage.Camera{}, fmt.Errorf("无人机变体 %d 未配置云台", variation.ID)
+	}
+	cameras := variation.Gimbals[0].Cameras
+	for _, t := range []po.CameraType{po.CameraTypeWide, po.CameraTypeZoom} {
+		for _, c := range cameras {
+			if c.Type != t {
+				continue
+			}
+			if camera, err := coverage.CameraFromEquivalent(c.FocalLength, c.EquivalentFocalLength); err == nil {
+				return c, camera, nil
+			}
+		}
+	}
+	return po.CameraModel{}, coverage.Camera{}, fmt.Errorf("云台 %s 缺少可用于测绘的相机焦距信息", variation.Gimbals[0].Name)
+}
+
+// coverageSpacing 根据飞行高度与重叠率计算航线间距与拍照间距，单位：米
+// OverlapRate 可以是 [0, 1) 的比例，也可以是百分比
+func coverageSpacing(variation po.DroneVariation, params po.JobWaylineGenerationParams) (float64, float64, error) {
+	_, camera, err := coverageCamera(variation)
+	if err != nil {
+		return 0, 0, err
+	}
+	overlap := float64(params.OverlapRate)
+	if overlap >= 1 {
+		overlap /= 100
+	}
+	return camera.Spacing(float64(params.FlyingHeight), overlap)
+}
+
+// planCoverageWaylines 为未指定航点的无人机在任务区域内生成往返式覆盖航线
+// 多架无人机按扫描线顺序划分相邻的子区域，航线间距取各机型中最小的间距以保证重叠率
+func (j *JobImpl) planCoverageWaylines(ctx context.Context, areaID uint, drones []po.JobDronePO, waylines []po.JobWaylinePO, params po.JobWaylineGenerationParams) ([]po.JobWaylinePO, error) {
+	var pending []int
+	for i, w := range waylines {
+		if len(w.Waypoints) == 0 {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return waylines, nil
+	}
+
+	variations, err := j.modelRepo.SelectAllDroneVariation(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	spacing := math.Inf(1)
+	for _, i := range pending {
+		var variation *po.DroneVariation
+		for _, d := range drones {
+			if d.Key != waylines[i].DroneKey {
+				continue
+			}
+			for k := range variations {
+				if variations[k].ID == d.VariationID {
+					variation = &variations[k]
+				}
+			}
+		}
+		if variation == nil {
+			return nil, fmt.Errorf("未找到航线 %s 对应的无人机型号", waylines[i].DroneKey)
+		}
+		lineSpacing, _, err := coverageSpacing(*variation, params)
+		if err != nil {
+			return nil, fmt.Errorf("无法计算航线 %s 的航线间距: %w", waylines[i].DroneKey, err)
+		}
+		spacing = math.Min(spacing, lineSpacing)
+	}
+
+	area, err := j.areaRepo.SelectByID(ctx, areaID)
+	if err != nil {
+		return nil, err
+	}
+	if area == nil {
+		return nil, errors.New("任务区域不存在")
+	}
+	polygon := make([]coverage.Point, len(area.Points))
+	for i, p := range area.Points {
+		polygon[i] = coverage.Point{Lat: p.Lat, Lng: p.Lng}
+	}
+	lines, err := coverage.Lines(polygon, spacing)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]po.JobWaylinePO, len(waylines))
+	copy(result, waylines)
+	for n, group := range coverage.Split(lines, len(pending)) {
+		w := &result[pending[n]]
+		if len(group) == 0 {
+			return nil, fmt.Errorf("区域过小，无法为航线 %s 分配作业范围", w.DroneKey)
+		}
+		if w.Altitude == 0 {
+			w.Altitude = float64(params.FlyingHeight)
+		}
+		w.Waypoints = nil
+		for idx, p := range coverage.Route(group) {
+			w.Waypoints = append(w.Waypoints, vo.GeoPoint{Index: idx, Lat: p.Lat, Lng: p.Lng, Altitude: w.Altitude})
+		}
+		w.Path = w.Waypoints
+	}
+	j.l.Info("已生成覆盖航线", slog.Any("areaID", areaID), slog.Float64("spacing", spacing),
+		slog.Int("lines", len(lines)), slog.Int("drones", len(pending)))
+	return result, nil
+}
+
+// photoActionGroup 沿航线等距拍照的动作组，覆盖 start 到 end 的全部航点
+func photoActionGroup(id, start, end int, gimbal po.GimbalModel, camera po.CameraModel, distance float64) wpml.ActionGroup {
+	group := wpml.DefaultActionGroup(id, start, end)
+	group.ActionTrigger = wpml.ActionTrigger{TriggerType: wpml.TriggerMultipleDistance, TriggerParam: math.Round(distance*10) / 10}
+	group.Actions = []wpml.Action{{
+		ActionId:   0,
+		ActionType: wpml.ActionTakePhoto,
+		ActionParams: &wpml.TakePhotoParams{
+			PayloadPositionIndex:      gimbal.Gimbalindex,
+			PayloadLensIndex:          []string{string(camera.Type)},
+			UseGlobalPayloadLensIndex: wpml.BoolAsInt(false),
+		},
+	}}
+	return group
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"gorm.io/datatypes"
+)
+
+const (
+	defaultRTHAltitude = 100  // 默认返航高度，单位：米
+	minRTHAltitude     = 20   // 返航高度下限
+	maxRTHAltitude     = 1500 // 返航高度上限
+)
+
+// DispatchJob 将任务下发到各无人机
+// 对任务中的每一架无人机依次调用 flighttask_prepare 与 flighttask_execute，并记录设备应答
+// 单架无人机下发失败不会中断其他无人机，失败原因记录在对应的执行记录中
+// 下发前先做起飞前检查，任一无人机未通过时拒绝下发，除非 params.IgnoreReadiness 为 true
+func (j *JobImpl) DispatchJob(ctx context.Context, id uint, params dto.JobDispatchParams) ([]po.JobExecution, error) {
+	if params.RTHAltitude == 0 {
+		params.RTHAltitude = defaultRTHAltitude
+	}
+	if params.RTHAltitude < minRTHAltitude || params.RTHAltitude > maxRTHAltitude {
+		return nil, fmt.Errorf("返航高度超出范围 [%d, %d]: %d", minRTHAltitude, maxRTHAltitude, params.RTHAltitude)
+	}
+	if params.OutOfControlAction < dto.OutOfControlActionGoHome || params.OutOfControlAction > dto.OutOfControlActionLanding {
+		return nil, fmt.Errorf("无效的失控动作: %d", params.OutOfControlAction)
+	}
+	if params.ExitWaylineWhenRCLost != 0 && params.ExitWaylineWhenRCLost != 1 {
+		return nil, fmt.Errorf("无效的航线失控动作: %d", params.ExitWaylineWhenRCLost)
+	}
+
+	job, err := j.jobRepo.FetchPOByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != 0 {
+		return nil, fmt.Errorf("任务 %d 已删除", id)
+	}
+	// 任务正在下发或执行时不能重复下发
+	if !slices.Contains(dispatchableStatuses, job.Status) {
+		return nil, fmt.Errorf("任务%s，不能重复下发", po.JobStatusNames[job.Status])
+	}
+
+	// 起飞前检查未通过时拒绝下发，操作员确认后可忽略
+	report, err := j.CheckReadiness(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	reason := "开始下发"
+	if report.Level == dto.ReadinessFail {
+		failures := readinessFailures(report)
+		if !params.IgnoreReadiness {
+			return nil, fmt.Errorf("起飞前检查未通过: %s", failures)
+		}
+		j.l.Warn("忽略未通过的起飞前检查，强制下发", slog.Any("jobID", id), slog.String("failures", failures))
+		reason = "忽略起飞前检查，强制下发"
+	}
+	// 并发下发时只有一个请求能从原状态切换到下发中
+	if err := j.transitionJob(ctx, job, po.JobStatusDispatching, reason); err != nil {
+		return nil, err
+	}
+
+	// 各无人机并发下发，互不等待设备应答
+	executions := make([]po.JobExecution, len(job.Drones))
+	var wg sync.WaitGroup
+	for i, drone := range job.Drones {
+		wg.Add(1)
+		go func(index int, drone po.JobDronePO) {
+			defer wg.Done()
+			executions[index] = j.dispatchToDrone(ctx, job.ID, drone, params)
+		}(i, drone)
+	}
+	wg.Wait()
+	j.l.Info("任务下发完成", slog.Any("jobID", id), slog.Int("count", len(executions)))
+
+	// 下发期间可能已收到进度上报，以数据库中的执行记录为准
+	round, err := j.roundExecutions(ctx, id)
+	if err != nil {
+		round = executions
+	}
+	if err := j.transitionJob(ctx, job, deriveJobStatus(round), "下发完成"); err != nil {
+		j.l.Error("更新任务状态失败", slog.Any("jobID", id), slog.Any("error", err))
+	}
+	return executions, nil
+}
+
+// dispatchToDrone 向单架无人机下发航线任务并返回执行记录
+func (j *JobImpl) dispatchToDrone(ctx context.Context, jobID uint, drone po.JobDronePO, params dto.JobDispatchParams) po.JobExecution {
+	execution := po.JobExecution{
+		JobID:       jobID,
+		JobDroneKey: drone.Key,
+		FlightID:    uuid.New().String(),
+		Status:      po.JobExecutionStatusPreparing,
+	}
+	fail := func(msg string, err error) po.JobExecution {
+		j.l.Error(msg, slog.Any("jobID", jobID), slog.String("droneKey", drone.Key), slog.Any("error", err))
+		execution.Status = po.JobExecutionStatusFailed
+		execution.Message = msg
+		if err != nil {
+			execution.Message = msg + ": " + err.Error()
+		}
+		if err := j.jobRepo.SaveExecution(ctx, &execution); err != nil {
+			j.l.Error("保存任务执行记录失败", slog.Any("error", err))
+		}
+		return execution
+	}
+
+	physicalDrone, err := j.droneRepo.SelectByIDV2(ctx, drone.PhysicalDroneID)
+	if err != nil {
+		return fail("获取无人机信息失败", err)
+	}
+	execution.DroneSN = physicalDrone.SN
+
+	gatewaySN, err := j.droneRepo.FetchGatewaySNByDroneSN(ctx, physicalDrone.SN)
+	if err != nil || gatewaySN == "" {
+		return fail("未能获取无人机关联的网关SN", err)
+	}
+	execution.GatewaySN = gatewaySN
+
+	wayline, err := j.waylineSvc.FetchWaylineByJobIDAndDroneKey(ctx, jobID, drone.Key)
+	if err != nil {
+		return fail("获取航线失败", err)
+	}
+	execution.WaylineID = wayline.ID
+
+	fingerprint, err := j.jobRepo.FetchWaylineFingerprint(ctx, wayline.Wayline)
+	if err != nil {
+		return fail("获取航线文件签名失败", err)
+	}
+	if err := j.jobRepo.SaveExecution(ctx, &execution); err != nil {
+		return fail("保存任务执行记录失败", err)
+	}
+
+	// 1. flighttask_prepare，bid 使用 flight_id，便于与后续进度上报关联
+	prepare := dto.FlighttaskPrepareData{
+		FlightID:    execution.FlightID,
+		ExecuteTime: time.Now().UnixMilli(),
+		TaskType:    dto.FlighttaskTypeImmediate,
+		File: dto.FlighttaskFile{
+			URL:         wayline.Url,
+			Fingerprint: fingerprint,
+		},
+		RTHAltitude:           params.RTHAltitude,
+		OutOfControlAction:    params.OutOfControlAction,
+		ExitWaylineWhenRCLost: params.ExitWaylineWhenRCLost,
+	}
+	reply, err := j.caller.CallWithBID(ctx, gatewaySN, execution.FlightID, dto.MethodFlighttaskPrepare, prepare)
+	if reply != nil {
+		execution.PrepareResult = &reply.Data.Result
+	}
+	if err != nil {
+		return fail("flighttask_prepare 失败", err)
+	}
+	execution.Status = po.JobExecutionStatusPrepared
+	if err := j.jobRepo.SaveExecution(ctx, &execution); err != nil {
+		j.l.Error("保存任务执行记录失败", slog.Any("error", err))
+	}
+
+	// 2. flighttask_execute，下发期间任务被取消时不再开始执行
+	if current, err := j.jobRepo.FetchPOByID(ctx, jobID); err == nil && current.Status != po.JobStatusDispatching {
+		return fail("任务状态为"+po.JobStatusNames[current.Status]+"，取消执行", nil)
+	}
+	execute := dto.FlighttaskExecuteData{FlightID: execution.FlightID}
+	reply, err = j.caller.CallWithBID(ctx, gatewaySN, execution.FlightID, dto.MethodFlighttaskExecute, execute)
+	if reply != nil {
+		execution.ExecuteResult = &reply.Data.Result
+	}
+	if err != nil {
+		return fail("flighttask_execute 失败", err)
+	}
+	execution.Status = po.JobExecutionStatusExecuting
+	if err := j.jobRepo.SaveExecution(ctx, &execution); err != nil {
+		j.l.Error("保存任务执行记录失败", slog.Any("error", err))
+	}
+	j.l.Info("航线任务已开始执行", slog.String("droneSN", execution.DroneSN), slog.String("flightID", execution.FlightID))
+	return execution
+}
+
+// FetchExecutions 获取任务的下发执行记录
+func (j *JobImpl) FetchExecutions(ctx context.Context, id uint) ([]po.JobExecution, error) {
+	return j.jobRepo.SelectExecutionsByJobID(ctx, id)
+}
+
+// HandleFlighttaskProgress 处理 flighttask_progress 事件
+// 每次上报都会写入一条进度历史，并把最新进度同步到执行记录上
+func (j *JobImpl) HandleFlighttaskProgress(ctx context.Context, gatewaySN string, data dto.FlighttaskProgressData) (*po.JobExecution, error) {
+	ext := data.Output.Ext
+	execution, err := j.jobRepo.SelectExecutionByFlightID(ctx, ext.FlightID)
+	if err != nil {
+		return nil, err
+	}
+
+	var breakPoint *po.JobBreakPointPO
+	if ext.BreakPoint != nil {
+		breakPoint = &po.JobBreakPointPO{}
+		if err := copier.Copy(breakPoint, ext.BreakPoint); err != nil {
+			j.l.Error("复制航线断点数据失败", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	progress := po.JobExecutionProgress{
+		ExecutionID:          execution.ID,
+		JobID:                execution.JobID,
+		WaylineID:            execution.WaylineID,
+		FlightID:             execution.FlightID,
+		Result:               data.Result,
+		Status:               data.Output.Status,
+		CurrentStep:          data.Output.Progress.CurrentStep,
+		Percent:              data.Output.Progress.Percent,
+		CurrentWaypointIndex: ext.CurrentWaypointIndex,
+		WaylineMissionState:  ext.WaylineMissionState,
+		MediaCount:           ext.MediaCount,
+		BreakPoint:           datatypes.NewJSONType(breakPoint),
+	}
+	if err := j.jobRepo.SaveExecutionProgress(ctx, &progress); err != nil {
+		return nil, err
+	}
+
+	execution.ProgressStatus = data.Output.Status
+	execution.Percent = data.Output.Progress.Percent
+	execution.CurrentWaypointIndex = ext.CurrentWaypointIndex
+	if breakPoint != nil {
+		execution.BreakPoint = datatypes.NewJSONType(breakPoint)
+	}
+	switch data.Output.Status {
+	case dto.FlighttaskStatusOK, dto.FlighttaskStatusPartiallyDone:
+		execution.Status = po.JobExecutionStatusFinished
+	case dto.FlighttaskStatusFailed, dto.FlighttaskStatusCanceled, dto.FlighttaskStatusRejected, dto.FlighttaskStatusTimeout:
+		execution.Status = po.JobExecutionStatusFailed
+		execution.Message = fmt.Sprintf("航线任务状态 %s，返回码 %d", data.Output.Status, data.Result)
+	default:
+		execution.Status = po.JobExecutionStatusExecuting
+	}
+	if err := j.jobRepo.SaveExecution(ctx, execution); err != nil {
+		return nil, err
+	}
+
+	j.l.Info("航线执行进度已更新", slog.String("gatewaySN", gatewaySN), slog.String("flightID", execution.FlightID),
+		slog.String("status", execution.ProgressStatus), slog.Int("percent", execution.Percent))
+	j.syncJobStatus(ctx, execution.JobID, "航线进度: "+data.Output.Status)
+	return execution, nil
+}
+
+// FetchExecutionProgresses 获取单次执行的进度历史
+func (j *JobImpl) FetchExecutionProgresses(ctx context.Context, executionID uint) ([]po.JobExecutionProgress, error) {
+	return j.jobRepo.SelectExecutionProgresses(ctx, executionID)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/model/ro"
+	"github.com/dronesphere/pkg/coordinate"
+)
+
+const (
+	readinessBatteryPerKm  = 4.0             // 每公里航程需要预留的电量，单位：%
+	readinessBatteryBuffer = 10.0            // 电量高于所需电量但不足该余量时提示风险，单位：%
+	readinessMaxWindSpeed  = 12.0            // 风速上限，单位：m/s
+	readinessWarnWindSpeed = 8.0             // 风速超过该值时提示风险，单位：m/s
+	readinessMinStorage    = 512 * 1024      // 剩余存储空间下限，单位：KB
+	readinessWarnStorage   = 2 * 1024 * 1024 // 剩余存储空间低于该值时提示风险，单位：KB
+)
+
+// readinessSeverity 检查结果等级的严重程度，用于汇总
+var readinessSeverity = map[string]int{
+	dto.ReadinessPass: 0,
+	dto.ReadinessWarn: 1,
+	dto.ReadinessFail: 2,
+}
+
+// CheckReadiness 对任务中的每一架无人机做起飞前检查
+// 检查在线状态、电量、风速、存储空间、当前的 HMS 告警与固件一致性，检查本身不会修改任务
+func (j *JobImpl) CheckReadiness(ctx context.Context, id uint) (*dto.JobReadinessReport, error) {
+	job, err := j.jobRepo.FetchPOByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != 0 {
+		return nil, fmt.Errorf("任务 %d 已删除", id)
+	}
+	report := &dto.JobReadinessReport{
+		JobID:       job.ID,
+		Level:       dto.ReadinessPass,
+		CheckedTime: time.Now(),
+	}
+	for _, drone := range job.Drones {
+		readiness := j.checkDroneReadiness(ctx, job, drone)
+		report.Level = worseReadiness(report.Level, readiness.Level)
+		report.Drones = append(report.Drones, readiness)
+	}
+	return report, nil
+}
+
+// checkDroneReadiness 检查单架无人机，离线时不再检查依赖实时数据的项目
+func (j *JobImpl) checkDroneReadiness(ctx context.Context, job *po.Job, drone po.JobDronePO) dto.DroneReadiness {
+	readiness := dto.DroneReadiness{DroneKey: drone.Key}
+	add := func(name, level, msg string) {
+		readiness.Checks = append(readiness.Checks, dto.ReadinessCheck{Name: name, Level: level, Message: msg})
+	}
+	done := func() dto.DroneReadiness {
+		readiness.Level = dto.ReadinessPass
+		for _, c := range readiness.Checks {
+			readiness.Level = worseReadiness(readiness.Level, c.Level)
+		}
+		return readiness
+	}
+
+	physicalDrone, err := j.droneRepo.SelectByIDV2(ctx, drone.PhysicalDroneID)
+	if err != nil || physicalDrone.SN == "" {
+		add(dto.ReadinessCheckOnline, dto.ReadinessFail, "未找到任务分配的无人机")
+		return done()
+	}
+	readiness.DroneSN = physicalDrone.SN
+
+	state, err := j.droneRepo.FetchStateBySN(ctx, physicalDrone.SN)
+	if err != nil || state.Status != ro.DroneStatusOnline {
+		add(dto.ReadinessCheckOnline, dto.ReadinessFail, "无人机不在线")
+		return done()
+	}
+	add(dto.ReadinessCheckOnline, dto.ReadinessPass, "无人机在线")
+	readiness.Checks = append(readiness.Checks, evaluateDroneState(state, routeLength(job, drone))...)
+
+	alerts, err := j.hmsRepo.SelectCurrentAlertsBySN(ctx, physicalDrone.SN)
+	if err != nil {
+		j.l.Error("查询 HMS 告警失败", slog.String("sn", physicalDrone.SN), slog.Any("error", err))
+		add(dto.ReadinessCheckHMS, dto.ReadinessWarn, "无法获取健康告警")
+		return done()
+	}
+	level, msg := evaluateHMSAlerts(alerts)
+	add(dto.ReadinessCheckHMS, level, msg)
+	return done()
+}
+
+// evaluateDroneState 根据实时数据检查电量、风速、存储空间与固件一致性，routeLength 为航程，单位：米
+func evaluateDroneState(state ro.Drone, routeLength float64) []dto.ReadinessCheck {
+	var checks []dto.ReadinessCheck
+	add := func(name, level, msg string) {
+		checks = append(checks, dto.ReadinessCheck{Name: name, Level: level, Message: msg})
+	}
+
+	// 降落时仍需保留低电量告警阈值以上的电量
+	capacity := float64(state.Battery.CapacityPercent)
+	required := state.LowBatteryWarningThreshold + math.Ceil(routeLength/1000*readinessBatteryPerKm)
+	msg := fmt.Sprintf("电量 %.0f%%，航程 %.1f km 需要 %.0f%%", capacity, routeLength/1000, required)
+	switch {
+	case capacity < required:
+		add(dto.ReadinessCheckBattery, dto.ReadinessFail, msg)
+	case capacity < required+readinessBatteryBuffer:
+		add(dto.ReadinessCheckBattery, dto.ReadinessWarn, msg)
+	default:
+		add(dto.ReadinessCheckBattery, dto.ReadinessPass, msg)
+	}
+
+	msg = fmt.Sprintf("风速 %.1f m/s", state.WindSpeed)
+	switch {
+	case state.WindSpeed >= readinessMaxWindSpeed:
+		add(dto.ReadinessCheckWind, dto.ReadinessFail, msg)
+	case state.WindSpeed >= readinessWarnWindSpeed:
+		add(dto.ReadinessCheckWind, dto.ReadinessWarn, msg)
+	default:
+		add(dto.ReadinessCheckWind, dto.ReadinessPass, msg)
+	}
+
+	free := state.Storage.Total - state.Storage.Used
+	msg = fmt.Sprintf("剩余存储空间 %.1f GB", float64(free)/1024/1024)
+	switch {
+	case state.Storage.Total == 0:
+		add(dto.ReadinessCheckStorage, dto.ReadinessWarn, "未上报存储空间")
+	case free < readinessMinStorage:
+		add(dto.ReadinessCheckStorage, dto.ReadinessFail, msg)
+	case free < readinessWarnStorage:
+		add(dto.ReadinessCheckStorage, dto.ReadinessWarn, msg)
+	default:
+		add(dto.ReadinessCheckStorage, dto.ReadinessPass, msg)
+	}
+
+	// compatible_status 0: 不需要一致性升级，1: 需要一致性升级
+	if state.CompatibleStatus != 0 {
+		add(dto.ReadinessCheckCompatible, dto.ReadinessFail, "固件需要一致性升级")
+	} else {
+		add(dto.ReadinessCheckCompatible, dto.ReadinessPass, "固件版本一致")
+	}
+	return checks
+}
+
+// evaluateHMSAlerts 尚未恢复的警告级告警视为故障，提醒级告警提示风险，是否已确认不影响结果
+func evaluateHMSAlerts(alerts []po.HMSAlert) (string, string) {
+	var warnings, cautions []string
+	for _, a := range alerts {
+		switch a.Level {
+		case dto.HMSLevelWarning:
+			warnings = append(warnings, a.Code)
+		case dto.HMSLevelCaution:
+			cautions = append(cautions, a.Code)
+		}
+	}
+	switch {
+	case len(warnings) > 0:
+		return dto.ReadinessFail, "存在故障告警: " + strings.Join(warnings, ", ")
+	case len(cautions) > 0:
+		return dto.ReadinessWarn, "存在提醒告警: " + strings.Join(cautions, ", ")
+	}
+	return dto.ReadinessPass, "无告警"
+}
+
+// routeLength 估算无人机的航程，包括从起飞点到航线起点以及从航线终点返回起飞点，单位：米
+func routeLength(job *po.Job, drone po.JobDronePO) float64 {
+	var path []po.JobTakeoffPointPO
+	takeoff := drone.TakeoffPoint
+	if takeoff.Lat != 0 || takeoff.Lng != 0 {
+		path = append(path, takeoff)
+	}
+	for _, w := range job.Waylines {
+		if w.DroneKey != drone.Key {
+			continue
+		}
+		points := w.Path
+		if len(points) == 0 {
+			points = w.Waypoints
+		}
+		for _, p := range points {
+			path = append(path, po.JobTakeoffPointPO{Lat: p.Lat, Lng: p.Lng})
+		}
+	}
+	if takeoff.Lat != 0 || takeoff.Lng != 0 {
+		path = append(path, takeoff)
+	}
+
+	length := 0.0
+	for i := 1; i < len(path); i++ {
+		length += coordinate.HaversineDistance(path[i-1].Lat, path[i-1].Lng, path[i].Lat, path[i].Lng)
+	}
+	return length
+}
+
+func worseReadiness(a, b string) string {
+	if readinessSeverity[b] > readinessSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// readinessFailures 汇总未通过检查的无人机与原因
+func readinessFailures(report *dto.JobReadinessReport) string {
+	var failures []string
+	for _, d := range report.Drones {
+		if d.Level != dto.ReadinessFail {
+			continue
+		}
+		var reasons []string
+		for _, c := range d.Checks {
+			if c.Level == dto.ReadinessFail {
+				reasons = append(reasons, c.Message)
+			}
+		}
+		name := d.DroneSN
+		if name == "" {
+			name = d.DroneKey
+		}
+		failures = append(failures, name+"("+strings.Join(reasons, "；")+")")
+	}
+	return strings.Join(failures, "，")
+}
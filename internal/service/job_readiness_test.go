@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/model/ro"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateDroneState(t *testing.T) {
+	const gb = 1024 * 1024 // 单位：KB
+	// 默认状态：航程 5 km 需要 20% + 20% 电量，各项均通过
+	state := func(modify func(s *ro.Drone)) ro.Drone {
+		s := ro.Drone{Status: ro.DroneStatusOnline}
+		s.Battery.CapacityPercent = 90
+		s.LowBatteryWarningThreshold = 20
+		s.WindSpeed = 3
+		s.Storage = dto.Storage{Total: 64 * gb, Used: gb}
+		if modify != nil {
+			modify(&s)
+		}
+		return s
+	}
+	tests := []struct {
+		name  string
+		state ro.Drone
+		check string
+		want  string
+	}{
+		{"电量充足", state(nil), dto.ReadinessCheckBattery, dto.ReadinessPass},
+		{"电量余量不足", state(func(s *ro.Drone) { s.Battery.CapacityPercent = 45 }), dto.ReadinessCheckBattery, dto.ReadinessWarn},
+		{"电量不足", state(func(s *ro.Drone) { s.Battery.CapacityPercent = 35 }), dto.ReadinessCheckBattery, dto.ReadinessFail},
+		{"风速正常", state(nil), dto.ReadinessCheckWind, dto.ReadinessPass},
+		{"风速偏大", state(func(s *ro.Drone) { s.WindSpeed = 9 }), dto.ReadinessCheckWind, dto.ReadinessWarn},
+		{"风速超限", state(func(s *ro.Drone) { s.WindSpeed = 12 }), dto.ReadinessCheckWind, dto.ReadinessFail},
+		{"存储充足", state(nil), dto.ReadinessCheckStorage, dto.ReadinessPass},
+		{"未上报存储", state(func(s *ro.Drone) { s.Storage = dto.Storage{} }), dto.ReadinessCheckStorage, dto.ReadinessWarn},
+		{"存储偏少", state(func(s *ro.Drone) { s.Storage.Used = 63 * gb }), dto.ReadinessCheckStorage, dto.ReadinessWarn},
+		{"存储不足", state(func(s *ro.Drone) { s.Storage.Used = 64*gb - 100*1024 }), dto.ReadinessCheckStorage, dto.ReadinessFail},
+		{"固件一致", state(nil), dto.ReadinessCheckCompatible, dto.ReadinessPass},
+		{"固件需要一致性升级", state(func(s *ro.Drone) { s.CompatibleStatus = 1 }), dto.ReadinessCheckCompatible, dto.ReadinessFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := evaluateDroneState(tt.state, 5000)
+			assert.Len(t, checks, 4)
+			for _, c := range checks {
+				if c.Name == tt.check {
+					assert.Equal(t, tt.want, c.Level, c.Message)
+					return
+				}
+			}
+			t.Errorf("缺少检查项 %s", tt.check)
+		})
+	}
+}
+
+func TestEvaluateHMSAlerts(t *testing.T) {
+	alert := func(code string, level int, acknowledged bool) po.HMSAlert {
+		return po.HMSAlert{Code: code, Level: level, Acknowledged: acknowledged}
+	}
+	tests := []struct {
+		name   string
+		alerts []po.HMSAlert
+		want   string
+		codes  []string
+	}{
+		{"无告警", nil, dto.ReadinessPass, nil},
+		{"仅通知", []po.HMSAlert{alert("0x16100083", dto.HMSLevelNotice, false)}, dto.ReadinessPass, nil},
+		{"提醒", []po.HMSAlert{
+			alert("0x16100083", dto.HMSLevelNotice, false),
+			alert("0x1B030019", dto.HMSLevelCaution, false),
+		}, dto.ReadinessWarn, []string{"0x1B030019"}},
+		{"警告优先于提醒", []po.HMSAlert{
+			alert("0x1B030019", dto.HMSLevelCaution, false),
+			alert("0x16050001", dto.HMSLevelWarning, false),
+		}, dto.ReadinessFail, []string{"0x16050001"}},
+		{"已确认但未恢复的警告", []po.HMSAlert{alert("0x16050001", dto.HMSLevelWarning, true)}, dto.ReadinessFail, []string{"0x16050001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, msg := evaluateHMSAlerts(tt.alerts)
+			assert.Equal(t, tt.want, level)
+			for _, code := range tt.codes {
+				assert.Contains(t, msg, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/robfig/cron/v3"
+)
+
+// maxCatchUpRuns all 策略下单个任务最多补执行的次数，超出的更早的执行会被跳过
+const maxCatchUpRuns = 10
+
+// cronParser 同时支持标准 5 段与带秒的 6 段 cron 表达式，以及 @daily 等描述符
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+type JobSchedulerSvc interface {
+	// Start 加载所有待调度的任务并开始调度，停机期间错过的执行按任务的补偿策略处理
+	Start(ctx context.Context) error
+	// Stop 停止调度，等待正在触发的下发完成
+	Stop()
+	// Sync 任务创建、修改或删除后重新加载其调度
+	Sync(ctx context.Context, jobID uint) error
+	// UpdateSchedule 设置任务的 cron 表达式与补偿策略
+	UpdateSchedule(ctx context.Context, jobID uint, params dto.JobScheduleParams) (*dto.JobScheduleResult, error)
+	// FetchSchedule 获取任务的调度状态
+	FetchSchedule(ctx context.Context, jobID uint) (*dto.JobScheduleResult, error)
+}
+
+type JobSchedulerImpl struct {
+	jobRepo JobRepo
+	jobSvc  JobSvc
+	cron    *cron.Cron
+	l       *slog.Logger
+
+	mu      sync.Mutex
+	entries map[uint]cron.EntryID // 任务 ID -> 调度条目
+	locks   map[uint]*sync.Mutex  // 任务 ID -> 触发锁，同一任务的定时触发与补执行串行进行
+}
+
+func NewJobSchedulerImpl(jobRepo JobRepo, jobSvc JobSvc, l *slog.Logger) JobSchedulerSvc {
+	logger := cron.PrintfLogger(slog.NewLogLogger(l.Handler(), slog.LevelInfo))
+	return &JobSchedulerImpl{
+		jobRepo: jobRepo,
+		jobSvc:  jobSvc,
+		// 同一任务上一次下发尚未结束时跳过本次触发
+		cron:    cron.New(cron.WithParser(cronParser), cron.WithChain(cron.SkipIfStillRunning(logger))),
+		l:       l,
+		entries: make(map[uint]cron.EntryID),
+		locks:   make(map[uint]*sync.Mutex),
+	}
+}
+
+// onceSchedule 一次性任务的调度，只在 at 触发一次
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+func (s *JobSchedulerImpl) Start(ctx context.Context) error {
+	// 先处理重启前中断的下发，之后这些任务按失败状态重新调度
+	if err := s.jobSvc.RecoverDispatchingJobs(ctx); err != nil {
+		return err
+	}
+	jobs, err := s.jobRepo.SelectScheduledJobs(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, job := range jobs {
+		if err := s.load(ctx, job, now, true); err != nil {
+			s.l.Error("加载任务调度失败", slog.Any("jobID", job.ID), slog.Any("err", err))
+		}
+	}
+	s.cron.Start()
+	s.l.Info("任务调度已启动", slog.Int("count", len(s.entries)))
+	return nil
+}
+
+func (s *JobSchedulerImpl) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+func (s *JobSchedulerImpl) Sync(ctx context.Context, jobID uint) error {
+	job, err := s.jobRepo.FetchPOByID(ctx, jobID)
+	if err != nil || job.State != 0 {
+		s.remove(jobID)
+		return nil
+	}
+	// 修改任务时不补偿已过去的计划时间，只调度之后的执行
+	return s.load(ctx, *job, time.Now(), false)
+}
+
+func (s *JobSchedulerImpl) UpdateSchedule(ctx context.Context, jobID uint, params dto.JobScheduleParams) (*dto.JobScheduleResult, error) {
+	if params.MissedRunPolicy == "" {
+		params.MissedRunPolicy = po.JobMissedRunSkip
+	}
+	switch params.MissedRunPolicy {
+	case po.JobMissedRunSkip, po.JobMissedRunOnce, po.JobMissedRunAll:
+	default:
+		return nil, fmt.Errorf("无效的补偿策略: %s", params.MissedRunPolicy)
+	}
+	if params.CronExpr != "" {
+		if _, err := cronParser.Parse(params.CronExpr); err != nil {
+			return nil, fmt.Errorf("无效的 cron 表达式: %w", err)
+		}
+	}
+
+	job, err := s.jobRepo.FetchPOByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != 0 {
+		return nil, fmt.Errorf("任务 %d 已删除", jobID)
+	}
+	// 重新设置调度即恢复已取消的任务
+	if job.Status == po.JobStatusCancelled {
+		if err := s.jobSvc.TransitionJob(ctx, jobID, po.JobStatusDraft, "修改调度"); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.jobRepo.UpdateJobSchedule(ctx, jobID, params.CronExpr, params.MissedRunPolicy); err != nil {
+		return nil, err
+	}
+	if err := s.Sync(ctx, jobID); err != nil {
+		return nil, err
+	}
+	return s.FetchSchedule(ctx, jobID)
+}
+
+func (s *JobSchedulerImpl) FetchSchedule(ctx context.Context, jobID uint) (*dto.JobScheduleResult, error) {
+	job, err := s.jobRepo.FetchPOByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.JobScheduleResult{
+		JobID:           job.ID,
+		ScheduleTime:    job.ScheduleTime,
+		CronExpr:        job.CronExpr,
+		MissedRunPolicy: job.MissedRunPolicy,
+		LastRunTime:     job.LastRunTime,
+	}
+	s.mu.Lock()
+	id, ok := s.entries[jobID]
+	s.mu.Unlock()
+	if ok {
+		if next := s.cron.Entry(id).Next; !next.IsZero() {
+			result.NextRunTime = &next
+		}
+	}
+	return result, nil
+}
+
+// load 按任务当前的配置重新调度，catchUp 为 true 时处理停机期间错过的执行
+func (s *JobSchedulerImpl) load(ctx context.Context, job po.Job, now time.Time, catchUp bool) error {
+	s.remove(job.ID)
+	if job.Status == po.JobStatusCancelled {
+		return nil
+	}
+
+	schedule, err := jobSchedule(job)
+	if err != nil {
+		return err
+	}
+	// 有待执行的计划时任务进入待执行，否则回到草稿；下发中或执行中的任务保持不变
+	next := schedule.Next(now)
+	switch {
+	case !next.IsZero() && (job.Status == po.JobStatusDraft || job.Status == po.JobStatusCompleted || job.Status == po.JobStatusFailed):
+		if err := s.jobSvc.TransitionJob(ctx, job.ID, po.JobStatusScheduled, "等待计划时间"); err != nil {
+			s.l.Warn("更新任务状态失败", slog.Any("jobID", job.ID), slog.Any("err", err))
+		}
+	case next.IsZero() && job.Status == po.JobStatusScheduled:
+		if err := s.jobSvc.TransitionJob(ctx, job.ID, po.JobStatusDraft, "没有待执行的计划"); err != nil {
+			s.l.Warn("更新任务状态失败", slog.Any("jobID", job.ID), slog.Any("err", err))
+		}
+	}
+	if catchUp {
+		if runs, total := missedRuns(schedule, catchUpFrom(job), now, maxCatchUpRuns); total > 0 {
+			go s.catchUp(job, runs, total)
+		}
+	}
+	if next.IsZero() {
+		return nil
+	}
+
+	jobID := job.ID
+	id := s.cron.Schedule(schedule, cron.FuncJob(func() {
+		planned := time.Now().Truncate(time.Second)
+		// 补执行尚未结束时跳过本次触发，与 SkipIfStillRunning 的行为一致
+		lock := s.jobLock(jobID)
+		if !lock.TryLock() {
+			s.l.Warn("任务上一次触发尚未结束，跳过本次执行", slog.Any("jobID", jobID), slog.Time("planned", planned))
+			return
+		}
+		defer lock.Unlock()
+		s.fire(jobID, planned)
+	}))
+	s.mu.Lock()
+	s.entries[jobID] = id
+	s.mu.Unlock()
+	s.l.Info("任务已加入调度", slog.Any("jobID", jobID), slog.String("cron", job.CronExpr), slog.Time("next", next))
+	return nil
+}
+
+func (s *JobSchedulerImpl) remove(jobID uint) {
+	s.mu.Lock()
+	id, ok := s.entries[jobID]
+	delete(s.entries, jobID)
+	s.mu.Unlock()
+	if ok {
+		s.cron.Remove(id)
+	}
+}
+
+// jobLock 返回任务的触发锁
+func (s *JobSchedulerImpl) jobLock(jobID uint) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.locks[jobID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[jobID] = lock
+	}
+	return lock
+}
+
+// catchUp 按补偿策略处理错过的执行，runs 为最近的若干次计划时间，total 为错过的总次数
+// 补执行期间持有任务的触发锁，到点的定时触发会被跳过
+func (s *JobSchedulerImpl) catchUp(job po.Job, runs []time.Time, total int) {
+	lock := s.jobLock(job.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	last := runs[len(runs)-1]
+	fires := missedRunsToFire(job.MissedRunPolicy, runs)
+	if len(fires) == 0 {
+		s.l.Warn("跳过错过的任务执行", slog.Any("jobID", job.ID), slog.Int("missed", total), slog.Time("last", last))
+		if err := s.jobRepo.UpdateJobLastRunTime(context.Background(), job.ID, last); err != nil {
+			s.l.Error("记录任务执行时间失败", slog.Any("jobID", job.ID), slog.Any("err", err))
+		}
+		return
+	}
+	if job.MissedRunPolicy == po.JobMissedRunAll && total > len(fires) {
+		s.l.Warn("错过的执行过多，只补执行最近的部分", slog.Any("jobID", job.ID), slog.Int("missed", total), slog.Int("runs", len(fires)))
+	}
+	for _, planned := range fires {
+		s.l.Warn("补执行错过的任务", slog.Any("jobID", job.ID), slog.Int("missed", total), slog.Time("planned", planned))
+		s.fire(job.ID, planned)
+	}
+}
+
+// fire 触发一次计划执行，先记录执行时间再下发，避免重启后重复下发
+func (s *JobSchedulerImpl) fire(jobID uint, planned time.Time) {
+	ctx := context.Background()
+	if err := s.jobRepo.UpdateJobLastRunTime(ctx, jobID, planned); err != nil {
+		s.l.Error("记录任务执行时间失败，放弃本次执行", slog.Any("jobID", jobID), slog.Any("err", err))
+		return
+	}
+	// 定时触发使用默认的下发参数
+	executions, err := s.jobSvc.DispatchJob(ctx, jobID, dto.JobDispatchParams{})
+	if err != nil {
+		s.l.Error("定时下发任务失败", slog.Any("jobID", jobID), slog.Time("planned", planned), slog.Any("err", err))
+		return
+	}
+	failed := 0
+	for _, e := range executions {
+		if e.Status == po.JobExecutionStatusFailed {
+			failed++
+		}
+	}
+	s.l.Info("定时下发任务完成", slog.Any("jobID", jobID), slog.Time("planned", planned),
+		slog.Int("count", len(executions)), slog.Int("failed", failed))
+}
+
+// jobSchedule 任务的调度计划，周期任务从 ScheduleTime 开始生效
+func jobSchedule(job po.Job) (cron.Schedule, error) {
+	if job.CronExpr == "" {
+		if job.LastRunTime != nil && !job.LastRunTime.Before(job.ScheduleTime) {
+			return onceSchedule{}, nil
+		}
+		return onceSchedule{at: job.ScheduleTime}, nil
+	}
+	schedule, err := cronParser.Parse(job.CronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 cron 表达式 %q: %w", job.CronExpr, err)
+	}
+	return startAfter{schedule: schedule, start: job.ScheduleTime}, nil
+}
+
+// startAfter 在 start 之前不触发的周期调度
+type startAfter struct {
+	schedule cron.Schedule
+	start    time.Time
+}
+
+func (s startAfter) Next(t time.Time) time.Time {
+	if t.Before(s.start) {
+		t = s.start.Add(-time.Second)
+	}
+	return s.schedule.Next(t)
+}
+
+// catchUpFrom 统计错过的执行的起点，起点之前的计划视为已处理
+// 从未执行过的任务从 ScheduleTime 开始统计，ScheduleTime 本身也计入
+func catchUpFrom(job po.Job) time.Time {
+	from := job.ScheduleTime.Add(-time.Second)
+	if job.LastRunTime != nil && job.LastRunTime.After(from) {
+		from = *job.LastRunTime
+	}
+	return from
+}
+
+// missedRunsToFire 按补偿策略选出需要补执行的计划时间，skip 策略不补执行
+func missedRunsToFire(policy string, runs []time.Time) []time.Time {
+	if len(runs) == 0 {
+		return nil
+	}
+	switch policy {
+	case po.JobMissedRunOnce:
+		return runs[len(runs)-1:]
+	case po.JobMissedRunAll:
+		return runs
+	}
+	return nil
+}
+
+// missedRuns 统计 (from, now] 内的计划执行，返回最近的至多 limit 次与总次数
+func missedRuns(schedule cron.Schedule, from, now time.Time, limit int) ([]time.Time, int) {
+	var runs []time.Time
+	total := 0
+	for t := schedule.Next(from); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		total++
+		runs = append(runs, t)
+		if len(runs) > limit {
+			runs = runs[1:]
+		}
+	}
+	return runs, total
+}
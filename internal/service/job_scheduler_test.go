@@ -0,0 +1,116 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dronesphere/internal/model/po"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobSchedule(t *testing.T) {
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	at := func(d time.Duration) *time.Time {
+		v := base.Add(d)
+		return &v
+	}
+	tests := []struct {
+		name       string
+		job        po.Job
+		now        time.Time
+		wantNext   time.Time // 零值表示没有待执行的计划
+		wantMissed []time.Time
+	}{
+		{
+			name:       "一次性任务已过计划时间",
+			job:        po.Job{ScheduleTime: base},
+			now:        base.Add(time.Hour),
+			wantMissed: []time.Time{base},
+		},
+		{
+			name: "一次性任务已执行",
+			job:  po.Job{ScheduleTime: base, LastRunTime: at(0)},
+			now:  base.Add(time.Hour),
+		},
+		{
+			name:     "一次性任务在未来",
+			job:      po.Job{ScheduleTime: base.Add(time.Hour)},
+			now:      base,
+			wantNext: base.Add(time.Hour),
+		},
+		{
+			name:     "周期任务未到开始时间",
+			job:      po.Job{ScheduleTime: base.Add(90 * time.Minute), CronExpr: "0 * * * *"},
+			now:      base,
+			wantNext: base.Add(2 * time.Hour),
+		},
+		{
+			name:       "周期任务从开始时间起计算错过的执行",
+			job:        po.Job{ScheduleTime: base, CronExpr: "0 * * * *"},
+			now:        base.Add(150 * time.Minute),
+			wantNext:   base.Add(3 * time.Hour),
+			wantMissed: []time.Time{base, base.Add(time.Hour), base.Add(2 * time.Hour)},
+		},
+		{
+			name:     "周期任务停机期间错过的执行",
+			job:      po.Job{ScheduleTime: base, CronExpr: "0 * * * *", LastRunTime: at(time.Hour)},
+			now:      base.Add(330 * time.Minute),
+			wantNext: base.Add(6 * time.Hour),
+			wantMissed: []time.Time{
+				base.Add(2 * time.Hour), base.Add(3 * time.Hour), base.Add(4 * time.Hour), base.Add(5 * time.Hour),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := jobSchedule(tt.job)
+			require.NoError(t, err)
+			assert.True(t, tt.wantNext.Equal(schedule.Next(tt.now)), "next = %v", schedule.Next(tt.now))
+
+			runs, total := missedRuns(schedule, catchUpFrom(tt.job), tt.now, maxCatchUpRuns)
+			assert.Equal(t, len(tt.wantMissed), total)
+			require.Len(t, runs, len(tt.wantMissed))
+			for i := range runs {
+				assert.True(t, tt.wantMissed[i].Equal(runs[i]), "run %d = %v, want %v", i, runs[i], tt.wantMissed[i])
+			}
+		})
+	}
+
+	_, err := jobSchedule(po.Job{ScheduleTime: base, CronExpr: "invalid"})
+	assert.Error(t, err)
+}
+
+func TestMissedRunsLimit(t *testing.T) {
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	schedule, err := jobSchedule(po.Job{ScheduleTime: base, CronExpr: "* * * * *"})
+	require.NoError(t, err)
+
+	// 停机 30 分钟错过 30 次，只保留最近的 maxCatchUpRuns 次
+	runs, total := missedRuns(schedule, base, base.Add(30*time.Minute), maxCatchUpRuns)
+	assert.Equal(t, 30, total)
+	require.Len(t, runs, maxCatchUpRuns)
+	assert.True(t, base.Add(21*time.Minute).Equal(runs[0]), "first = %v", runs[0])
+	assert.True(t, base.Add(30*time.Minute).Equal(runs[len(runs)-1]), "last = %v", runs[len(runs)-1])
+}
+
+func TestMissedRunsToFire(t *testing.T) {
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	runs := []time.Time{base, base.Add(time.Hour), base.Add(2 * time.Hour)}
+	tests := []struct {
+		policy string
+		runs   []time.Time
+		want   []time.Time
+	}{
+		{po.JobMissedRunSkip, runs, nil},
+		{"", runs, nil},
+		{po.JobMissedRunOnce, runs, runs[2:]},
+		{po.JobMissedRunAll, runs, runs},
+		{po.JobMissedRunAll, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			assert.Equal(t, tt.want, missedRunsToFire(tt.policy, tt.runs))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+)
+
+// dispatchableStatuses 允许开始下发的任务状态
+var dispatchableStatuses = []int{po.JobStatusDraft, po.JobStatusScheduled, po.JobStatusCompleted, po.JobStatusFailed, po.JobStatusCancelled}
+
+// jobTransitions 任务状态允许切换到的状态
+var jobTransitions = map[int][]int{
+	po.JobStatusDraft:       {po.JobStatusScheduled, po.JobStatusDispatching, po.JobStatusCancelled},
+	po.JobStatusScheduled:   {po.JobStatusDraft, po.JobStatusDispatching, po.JobStatusCancelled},
+	po.JobStatusDispatching: {po.JobStatusExecuting, po.JobStatusCompleted, po.JobStatusFailed, po.JobStatusCancelled},
+	po.JobStatusExecuting:   {po.JobStatusPaused, po.JobStatusCompleted, po.JobStatusFailed, po.JobStatusCancelled},
+	po.JobStatusPaused:      {po.JobStatusExecuting, po.JobStatusCompleted, po.JobStatusFailed, po.JobStatusCancelled},
+	// 结束后可再次下发，周期任务等待下一次触发
+	po.JobStatusCompleted: {po.JobStatusDraft, po.JobStatusScheduled, po.JobStatusDispatching},
+	po.JobStatusFailed:    {po.JobStatusDraft, po.JobStatusScheduled, po.JobStatusDispatching},
+	// 取消后不再自动调度，修改任务或手动下发后重新生效
+	po.JobStatusCancelled: {po.JobStatusDraft, po.JobStatusDispatching},
+}
+
+func (j *JobImpl) TransitionJob(ctx context.Context, id uint, to int, reason string) error {
+	job, err := j.jobRepo.FetchPOByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return j.transitionJob(ctx, job, to, reason)
+}
+
+// transitionJob 校验并切换任务状态，成功后更新 job.Status
+// 状态不变时直接返回，但进入下发中必须由其他状态切换而来，避免同一任务被重复下发
+func (j *JobImpl) transitionJob(ctx context.Context, job *po.Job, to int, reason string) error {
+	from := job.Status
+	if from == to && to != po.JobStatusDispatching {
+		return nil
+	}
+	if !canTransition(from, to) {
+		return fmt.Errorf("任务状态不能从%s变为%s", po.JobStatusNames[from], po.JobStatusNames[to])
+	}
+	updated, err := j.jobRepo.UpdateJobStatus(ctx, &po.JobStatusHistory{
+		CreatedTime: time.Now(),
+		JobID:       job.ID,
+		FromStatus:  from,
+		ToStatus:    to,
+		Reason:      reason,
+	})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New("任务状态已被修改，请刷新后重试")
+	}
+	job.Status = to
+	j.l.Info("任务状态已变化", slog.Any("jobID", job.ID), slog.String("from", po.JobStatusNames[from]),
+		slog.String("to", po.JobStatusNames[to]), slog.String("reason", reason))
+	return nil
+}
+
+func canTransition(from, to int) bool {
+	return slices.Contains(jobTransitions[from], to)
+}
+
+// RecoverDispatchingJobs 服务启动时将仍处于下发中的任务标记为失败
+// 下发在服务重启或最终状态更新失败时中断，任务会一直停留在下发中
+func (j *JobImpl) RecoverDispatchingJobs(ctx context.Context) error {
+	jobs, err := j.jobRepo.SelectAll(ctx, "", "", "", "", []int{po.JobStatusDispatching})
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if err := j.transitionJob(ctx, &jobs[i], po.JobStatusFailed, "下发中断"); err != nil {
+			j.l.Error("恢复下发中断的任务失败", slog.Any("jobID", jobs[i].ID), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// syncJobStatus 根据本轮下发的执行记录推导任务状态，只在任务执行中或暂停时生效
+func (j *JobImpl) syncJobStatus(ctx context.Context, jobID uint, reason string) {
+	job, err := j.jobRepo.FetchPOByID(ctx, jobID)
+	if err != nil {
+		return
+	}
+	if job.Status != po.JobStatusExecuting && job.Status != po.JobStatusPaused {
+		return
+	}
+	executions, err := j.roundExecutions(ctx, jobID)
+	if err != nil {
+		j.l.Error("查询本轮执行记录失败", slog.Any("jobID", jobID), slog.Any("error", err))
+		return
+	}
+	if err := j.transitionJob(ctx, job, deriveJobStatus(executions), reason); err != nil {
+		j.l.Warn("同步任务状态失败", slog.Any("jobID", jobID), slog.Any("error", err))
+	}
+}
+
+// roundExecutions 获取最近一次下发产生的执行记录
+func (j *JobImpl) roundExecutions(ctx context.Context, jobID uint) ([]po.JobExecution, error) {
+	dispatched, err := j.jobRepo.SelectLastJobStatusHistory(ctx, jobID, po.JobStatusDispatching)
+	if err != nil {
+		return nil, err
+	}
+	executions, err := j.jobRepo.SelectExecutionsByJobID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if dispatched == nil {
+		return executions, nil
+	}
+	// 时间精度可能被数据库截断，执行记录不早于下发时间即属于本轮
+	since := dispatched.CreatedTime.Truncate(time.Second)
+	var round []po.JobExecution
+	for _, e := range executions {
+		if !e.CreatedTime.Before(since) {
+			round = append(round, e)
+		}
+	}
+	return round, nil
+}
+
+// deriveJobStatus 由执行记录推导任务状态
+// 有航线在执行时为执行中，全部暂停时为暂停；全部结束后至少一架成功为已完成，否则被终止为已取消、其余为失败
+func deriveJobStatus(executions []po.JobExecution) int {
+	active, paused, succeeded, cancelled := 0, 0, 0, 0
+	for _, e := range executions {
+		switch e.Status {
+		case po.JobExecutionStatusPreparing, po.JobExecutionStatusPrepared, po.JobExecutionStatusExecuting:
+			active++
+			if e.ProgressStatus == dto.FlighttaskStatusPaused {
+				paused++
+			}
+		case po.JobExecutionStatusFinished:
+			succeeded++
+		case po.JobExecutionStatusFailed:
+			if e.ProgressStatus == dto.FlighttaskStatusCanceled {
+				cancelled++
+			}
+		}
+	}
+	switch {
+	case active > 0 && paused == active:
+		return po.JobStatusPaused
+	case active > 0:
+		return po.JobStatusExecuting
+	case succeeded > 0:
+		return po.JobStatusCompleted
+	case cancelled > 0:
+		return po.JobStatusCancelled
+	}
+	return po.JobStatusFailed
+}
+
+func (j *JobImpl) CancelJob(ctx context.Context, id uint) error {
+	job, err := j.jobRepo.FetchPOByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// 下发中的任务可能已有无人机开始执行，同样需要终止；仍在下发的无人机会在执行前检查到任务已取消
+	if job.Status == po.JobStatusDispatching || job.Status == po.JobStatusExecuting || job.Status == po.JobStatusPaused {
+		executions, err := j.roundExecutions(ctx, id)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, e := range executions {
+			if e.Status != po.JobExecutionStatusPrepared && e.Status != po.JobExecutionStatusExecuting {
+				continue
+			}
+			if err := j.ControlFlighttask(ctx, e.DroneSN, dto.FlighttaskActionFinish); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.DroneSN, err))
+			}
+		}
+		// 仍有无人机在执行航线时不能标记为已取消
+		if len(errs) > 0 {
+			return fmt.Errorf("终止航线失败: %w", errors.Join(errs...))
+		}
+	}
+	return j.transitionJob(ctx, job, po.JobStatusCancelled, "人工取消")
+}
+
+func (j *JobImpl) FetchStatusHistories(ctx context.Context, id uint) ([]po.JobStatusHistory, error) {
+	return j.jobRepo.SelectJobStatusHistories(ctx, id)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobTransitions(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     bool
+	}{
+		{po.JobStatusDraft, po.JobStatusScheduled, true},
+		{po.JobStatusDraft, po.JobStatusDispatching, true},
+		{po.JobStatusDraft, po.JobStatusExecuting, false},
+		{po.JobStatusScheduled, po.JobStatusDispatching, true},
+		{po.JobStatusDispatching, po.JobStatusDispatching, false},
+		{po.JobStatusDispatching, po.JobStatusExecuting, true},
+		{po.JobStatusDispatching, po.JobStatusFailed, true},
+		{po.JobStatusDispatching, po.JobStatusCancelled, true},
+		{po.JobStatusDispatching, po.JobStatusDraft, false},
+		{po.JobStatusExecuting, po.JobStatusPaused, true},
+		{po.JobStatusExecuting, po.JobStatusDispatching, false},
+		{po.JobStatusExecuting, po.JobStatusDraft, false},
+		{po.JobStatusPaused, po.JobStatusExecuting, true},
+		{po.JobStatusPaused, po.JobStatusScheduled, false},
+		{po.JobStatusCompleted, po.JobStatusScheduled, true},
+		{po.JobStatusFailed, po.JobStatusDispatching, true},
+		{po.JobStatusFailed, po.JobStatusExecuting, false},
+		{po.JobStatusCancelled, po.JobStatusDraft, true},
+		{po.JobStatusCancelled, po.JobStatusScheduled, false},
+	}
+	for _, tt := range tests {
+		name := po.JobStatusNames[tt.from] + "->" + po.JobStatusNames[tt.to]
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, canTransition(tt.from, tt.to))
+		})
+	}
+
+	// 只有允许下发的状态可以进入下发中
+	for status := range po.JobStatusNames {
+		assert.Equal(t, slices.Contains(dispatchableStatuses, status), canTransition(status, po.JobStatusDispatching),
+			po.JobStatusNames[status])
+	}
+}
+
+func TestDeriveJobStatus(t *testing.T) {
+	execution := func(status int, progress string) po.JobExecution {
+		return po.JobExecution{Status: status, ProgressStatus: progress}
+	}
+	tests := []struct {
+		name       string
+		executions []po.JobExecution
+		want       int
+	}{
+		{"无执行记录", nil, po.JobStatusFailed},
+		{"执行中", []po.JobExecution{
+			execution(po.JobExecutionStatusExecuting, dto.FlighttaskStatusInProgress),
+			execution(po.JobExecutionStatusFinished, dto.FlighttaskStatusOK),
+		}, po.JobStatusExecuting},
+		{"准备中视为执行中", []po.JobExecution{
+			execution(po.JobExecutionStatusPreparing, ""),
+		}, po.JobStatusExecuting},
+		{"全部暂停", []po.JobExecution{
+			execution(po.JobExecutionStatusExecuting, dto.FlighttaskStatusPaused),
+			execution(po.JobExecutionStatusExecuting, dto.FlighttaskStatusPaused),
+			execution(po.JobExecutionStatusFinished, dto.FlighttaskStatusOK),
+		}, po.JobStatusPaused},
+		{"部分暂停", []po.JobExecution{
+			execution(po.JobExecutionStatusExecuting, dto.FlighttaskStatusPaused),
+			execution(po.JobExecutionStatusExecuting, dto.FlighttaskStatusInProgress),
+		}, po.JobStatusExecuting},
+		{"至少一架成功", []po.JobExecution{
+			execution(po.JobExecutionStatusFinished, dto.FlighttaskStatusOK),
+			execution(po.JobExecutionStatusFailed, dto.FlighttaskStatusFailed),
+		}, po.JobStatusCompleted},
+		{"被终止", []po.JobExecution{
+			execution(po.JobExecutionStatusFailed, dto.FlighttaskStatusCanceled),
+			execution(po.JobExecutionStatusFailed, dto.FlighttaskStatusFailed),
+		}, po.JobStatusCancelled},
+		{"全部失败", []po.JobExecution{
+			execution(po.JobExecutionStatusFailed, ""),
+			execution(po.JobExecutionStatusFailed, dto.FlighttaskStatusTimeout),
+		}, po.JobStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, deriveJobStatus(tt.executions))
+		})
+	}
+}
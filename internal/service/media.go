@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/pkg/servicecall"
+	"github.com/dronesphere/internal/repo"
+)
+
+type MediaSvc interface {
+	// FetchSTS 签发 Pilot 与机场上传媒体文件使用的临时凭证
+	FetchSTS(ctx context.Context, workspaceID string) (*dto.STSResult, error)
+	// HandleUploadCallback 登记上传完成的媒体文件，gatewaySN 为空表示由 Pilot 上报
+	HandleUploadCallback(ctx context.Context, gatewaySN string, file dto.MediaFile) (*po.Media, error)
+	// FilterExistingTinyFingerprints 返回已上传过的文件指纹，Pilot 据此跳过重复上传
+	FilterExistingTinyFingerprints(ctx context.Context, fingerprints []string) ([]string, error)
+	// HandleHighestPriority 处理机场上报的当前优先上传的航线任务
+	HandleHighestPriority(ctx context.Context, gatewaySN, flightID string) (*po.JobExecution, error)
+	// Prioritize 通过 upload_flighttask_media_prioritize 让机场优先上传指定航线任务的媒体文件
+	Prioritize(ctx context.Context, flightID string) error
+	List(ctx context.Context, query dto.MediaQuery) ([]MediaItem, int64, error)
+	FetchByID(ctx context.Context, id uint) (*MediaItem, error)
+}
+
+type MediaRepo interface {
+	Bucket() string
+	Save(ctx context.Context, media *po.Media) error
+	SelectExistingTinyFingerprints(ctx context.Context, fingerprints []string) ([]string, error)
+	SelectByID(ctx context.Context, id uint) (*po.Media, error)
+	SelectAll(ctx context.Context, query dto.MediaQuery) ([]po.Media, int64, error)
+	PresignDownloadURL(ctx context.Context, key string) (string, error)
+}
+
+// MediaItem 媒体文件及下载链接
+type MediaItem struct {
+	po.Media
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type MediaImpl struct {
+	repo        MediaRepo
+	storage     StorageRepo
+	jobRepo     JobRepo
+	gatewayRepo repo.GatewayRepo
+	caller      *servicecall.Client
+	l           *slog.Logger
+}
+
+func NewMediaImpl(repo MediaRepo, storage StorageRepo, jobRepo JobRepo, gatewayRepo repo.GatewayRepo, caller *servicecall.Client, l *slog.Logger) MediaSvc {
+	return &MediaImpl{
+		repo:        repo,
+		storage:     storage,
+		jobRepo:     jobRepo,
+		gatewayRepo: gatewayRepo,
+		caller:      caller,
+		l:           l,
+	}
+}
+
+func (s *MediaImpl) FetchSTS(ctx context.Context, workspaceID string) (*dto.STSResult, error) {
+	storage, err := s.storage.IssueCredentials(ctx, s.repo.Bucket())
+	if err != nil {
+		return nil, err
+	}
+	return &dto.STSResult{
+		StorageConfig:   storage,
+		ObjectKeyPrefix: workspaceID,
+	}, nil
+}
+
+// HandleUploadCallback 登记媒体文件
+// 通过 flight_id 关联到航线任务执行记录，从中得到任务、航线和无人机；
+// 非航线任务拍摄的文件由 Pilot 上报的 sn 或网关当前挂载的无人机确定归属
+func (s *MediaImpl) HandleUploadCallback(ctx context.Context, gatewaySN string, file dto.MediaFile) (*po.Media, error) {
+	if file.ObjectKey == "" {
+		return nil, errors.New("缺少对象键")
+	}
+	media := &po.Media{
+		ObjectKey:        file.ObjectKey,
+		Name:             file.Name,
+		Path:             file.Path,
+		Fingerprint:      file.Fingerprint,
+		TinyFingerprint:  file.TinyFingerprint,
+		SubFileType:      file.SubFileType,
+		IsOriginal:       file.Ext.IsOriginal,
+		DroneModelKey:    file.Ext.DroneModelKey,
+		PayloadModelKey:  file.Ext.PayloadModelKey,
+		DroneSN:          file.Ext.SN,
+		GatewaySN:        gatewaySN,
+		FlightID:         file.Ext.FlightID,
+		Latitude:         file.Metadata.ShootPosition.Lat,
+		Longitude:        file.Metadata.ShootPosition.Lng,
+		AbsoluteAltitude: file.Metadata.AbsoluteAltitude,
+		RelativeAltitude: file.Metadata.RelativeAltitude,
+		GimbalYawDegree:  file.Metadata.GimbalYawDegree,
+	}
+	if t, err := time.Parse(time.RFC3339, file.Metadata.CreateTime); err == nil {
+		media.ShootTime = &t
+	}
+
+	if media.FlightID != "" {
+		if execution, err := s.jobRepo.SelectExecutionByFlightID(ctx, media.FlightID); err == nil {
+			media.JobID = execution.JobID
+			media.WaylineID = execution.WaylineID
+			media.DroneSN = execution.DroneSN
+			if media.GatewaySN == "" {
+				media.GatewaySN = execution.GatewaySN
+			}
+		}
+	}
+	if media.DroneSN == "" && gatewaySN != "" {
+		if drones, err := s.gatewayRepo.GetConnectedDrones(ctx, gatewaySN); err == nil && len(drones) > 0 {
+			media.DroneSN = drones[0].SN
+		}
+	}
+
+	if err := s.repo.Save(ctx, media); err != nil {
+		return nil, err
+	}
+	s.l.Info("媒体文件已登记", slog.String("key", media.ObjectKey), slog.String("droneSN", media.DroneSN), slog.Uint64("jobID", uint64(media.JobID)))
+	return media, nil
+}
+
+func (s *MediaImpl) FilterExistingTinyFingerprints(ctx context.Context, fingerprints []string) ([]string, error) {
+	return s.repo.SelectExistingTinyFingerprints(ctx, fingerprints)
+}
+
+func (s *MediaImpl) HandleHighestPriority(ctx context.Context, gatewaySN, flightID string) (*po.JobExecution, error) {
+	execution, err := s.jobRepo.SelectExecutionByFlightID(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+	s.l.Info("机场优先上传航线任务媒体", slog.String("gatewaySN", gatewaySN), slog.String("flightID", flightID))
+	return execution, nil
+}
+
+func (s *MediaImpl) Prioritize(ctx context.Context, flightID string) error {
+	execution, err := s.jobRepo.SelectExecutionByFlightID(ctx, flightID)
+	if err != nil {
+		return errors.New("航线任务执行记录不存在")
+	}
+	_, err = s.caller.Call(ctx, execution.GatewaySN, dto.MethodUploadFlighttaskMediaPrioritize, dto.FlighttaskMediaData{FlightID: flightID})
+	return err
+}
+
+func (s *MediaImpl) List(ctx context.Context, query dto.MediaQuery) ([]MediaItem, int64, error) {
+	medias, total, err := s.repo.SelectAll(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]MediaItem, 0, len(medias))
+	for _, m := range medias {
+		item := MediaItem{Media: m}
+		item.DownloadURL, _ = s.repo.PresignDownloadURL(ctx, m.ObjectKey)
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+func (s *MediaImpl) FetchByID(ctx context.Context, id uint) (*MediaItem, error) {
+	media, err := s.repo.SelectByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	item := &MediaItem{Media: *media}
+	item.DownloadURL, err = s.repo.PresignDownloadURL(ctx, media.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
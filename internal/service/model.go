@@ -25,6 +25,7 @@ type (
 		SelectAllDroneModel(ctx context.Context, name string) ([]entity.DroneModel, error)
 		SelectDroneModels(ctx context.Context, query map[string]interface{}) ([]entity.DroneModel, error)
 		SelectDroneModelByID(ctx context.Context, id uint) (*entity.DroneModel, error)
+		FindDefaultDroneVariation(ctx context.Context, droneModelID uint) (*po.DroneVariation, error)
 
 		SelectAllGimbals(ctx context.Context) ([]po.GimbalModel, error)
 		SelectGimbalModels(ctx context.Context, query map[string]interface{}) ([]po.GimbalModel, error)
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dronesphere/internal/model/dto"
+	"github.com/dronesphere/internal/model/po"
+	"github.com/dronesphere/internal/pkg/mqttsub"
+)
+
+// MQTT 审计写入参数
+const (
+	mqttAuditBufferSize    = 4096             // 待写入队列长度，队列满时丢弃新消息
+	mqttAuditBatchSize     = 200              // 单次批量写入的条数
+	mqttAuditFlushInterval = time.Second      // 不足一批时的写入间隔
+	mqttAuditPurgeInterval = 10 * time.Minute // 过期清理间隔
+)
+
+// mqttAuditSampledSuffixes 高频遥测主题，按采样率记录；其余主题（services、events、requests、status 等）全部记录
+var mqttAuditSampledSuffixes = []string{"/osd", "/state", "/drc/up", "/drc/down"}
+
+type MQTTAuditSvc interface {
+	// Record 记录一条收发的 MQTT 消息，可直接作为 mqttsub.Tap 使用，不会阻塞
+	Record(direction mqttsub.Direction, topic string, payload []byte)
+	// Run 批量写入审计记录并定期清理过期记录，直到 ctx 结束
+	Run(ctx context.Context)
+	Query(ctx context.Context, query dto.MQTTAuditQuery) ([]po.MQTTAudit, int64, error)
+}
+
+type MQTTAuditRepo interface {
+	BatchCreate(ctx context.Context, audits []po.MQTTAudit) error
+	SelectAll(ctx context.Context, query dto.MQTTAuditQuery) ([]po.MQTTAudit, int64, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteOverflow(ctx context.Context, keep int64) (int64, error)
+}
+
+// MQTTAuditOptions 审计采样与保留策略
+type MQTTAuditOptions struct {
+	SampleRate float64       // 高频遥测的采样率，取值 (0, 1]
+	Retention  time.Duration // 记录保留时长，0 表示不按时间清理
+	MaxRows    int64         // 最多保留的记录数，0 表示不限制
+}
+
+type MQTTAuditImpl struct {
+	repo    MQTTAuditRepo
+	opts    MQTTAuditOptions
+	l       *slog.Logger
+	queue   chan po.MQTTAudit
+	dropped atomic.Int64 // 队列满被丢弃的消息数，写入时输出日志后清零
+}
+
+func NewMQTTAuditImpl(repo MQTTAuditRepo, opts MQTTAuditOptions, l *slog.Logger) MQTTAuditSvc {
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	return &MQTTAuditImpl{
+		repo:  repo,
+		opts:  opts,
+		l:     l,
+		queue: make(chan po.MQTTAudit, mqttAuditBufferSize),
+	}
+}
+
+func (s *MQTTAuditImpl) Record(direction mqttsub.Direction, topic string, payload []byte) {
+	if s.opts.SampleRate < 1 && isSampledTopic(topic) && rand.Float64() >= s.opts.SampleRate {
+		return
+	}
+	audit := po.MQTTAudit{
+		CreatedTime: time.Now(),
+		Direction:   string(direction),
+		Topic:       topic,
+		SN:          snFromTopic(topic),
+		Payload:     string(payload),
+	}
+	select {
+	case s.queue <- audit:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *MQTTAuditImpl) Run(ctx context.Context) {
+	flush := time.NewTicker(mqttAuditFlushInterval)
+	defer flush.Stop()
+	purge := time.NewTicker(mqttAuditPurgeInterval)
+	defer purge.Stop()
+
+	batch := make([]po.MQTTAudit, 0, mqttAuditBatchSize)
+	write := func() {
+		if n := s.dropped.Swap(0); n > 0 {
+			s.l.Warn("MQTT 审计队列已满，部分消息未记录", slog.Int64("dropped", n))
+		}
+		if len(batch) == 0 {
+			return
+		}
+		for i := range batch {
+			fillAuditEnvelope(&batch[i])
+		}
+		_ = s.repo.BatchCreate(context.Background(), batch)
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			write()
+			return
+		case audit := <-s.queue:
+			batch = append(batch, audit)
+			if len(batch) >= mqttAuditBatchSize {
+				write()
+			}
+		case <-flush.C:
+			write()
+		case <-purge.C:
+			s.purge(ctx)
+		}
+	}
+}
+
+// purge 按保留时长与最大条数清理审计记录
+func (s *MQTTAuditImpl) purge(ctx context.Context) {
+	if s.opts.Retention > 0 {
+		if n, err := s.repo.DeleteBefore(ctx, time.Now().Add(-s.opts.Retention)); err == nil && n > 0 {
+			s.l.Info("已清理过期 MQTT 审计记录", slog.Int64("count", n))
+		}
+	}
+	if s.opts.MaxRows > 0 {
+		if n, err := s.repo.DeleteOverflow(ctx, s.opts.MaxRows); err == nil && n > 0 {
+			s.l.Info("已清理超量 MQTT 审计记录", slog.Int64("count", n))
+		}
+	}
+}
+
+func (s *MQTTAuditImpl) Query(ctx context.Context, query dto.MQTTAuditQuery) ([]po.MQTTAudit, int64, error) {
+	return s.repo.SelectAll(ctx, query)
+}
+
+// fillAuditEnvelope 从消息体中解析 method、tid、bid，在写入协程中执行，避免阻塞 MQTT 回调
+func fillAuditEnvelope(audit *po.MQTTAudit) {
+	var envelope struct {
+		Method string `json:"method"`
+		TID    string `json:"tid"`
+		BID    string `json:"bid"`
+	}
+	if err := json.Unmarshal([]byte(audit.Payload), &envelope); err != nil {
+		return
+	}
+	audit.Method = envelope.Method
+	audit.TID = envelope.TID
+	audit.BID = envelope.BID
+}
+
+func isSampledTopic(topic string) bool {
+	for _, suffix := range mqttAuditSampledSuffixes {
+		if strings.HasSuffix(topic, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// snFromTopic 从 thing/product/{sn}/... 或 sys/product/{sn}/... 中取出设备序列号
+func snFromTopic(topic string) string {
+	parts := strings.SplitN(topic, "/", 4)
+	if len(parts) < 3 || parts[1] != "product" {
+		return ""
+	}
+	return parts[2]
+}
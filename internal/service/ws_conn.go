@@ -0,0 +1,25 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+// connWriteLocks 每个 WebSocket 连接的写锁，key 为 *websocket.Conn
+// 同一连接会被前端消息转发、DRC 遥测推送和错误回复同时写入，而连接不支持并发写
+var connWriteLocks sync.Map
+
+// WriteConn 串行写入 WebSocket 连接，所有对控制连接的写操作都应通过该函数
+func WriteConn(conn *websocket.Conn, messageType int, data []byte) error {
+	lock, _ := connWriteLocks.LoadOrStore(conn, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	return conn.WriteMessage(messageType, data)
+}
+
+// ReleaseConn 连接关闭后释放其写锁
+func ReleaseConn(conn *websocket.Conn) {
+	connWriteLocks.Delete(conn)
+}
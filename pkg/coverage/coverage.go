@@ -0,0 +1,204 @@
+// Package coverage 在多边形区域内规划往返式（割草机式）覆盖航线，并根据相机参数计算航线间距与拍照间距
+package coverage
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	earthRadius       = 6371000 // 地球半径，单位米，与 coordinate.HaversineDistance 保持一致
+	fullFrameDiagonal = 43.27   // 35mm 全画幅传感器对角线长度，单位 mm
+)
+
+// Point 经纬度坐标点
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Segment 一条扫描线
+type Segment struct {
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+}
+
+// Camera 相机的焦距与传感器尺寸，单位 mm
+type Camera struct {
+	FocalLength  float64
+	SensorWidth  float64 // 传感器长边，垂直于飞行方向
+	SensorHeight float64 // 传感器短边，沿飞行方向
+}
+
+// CameraFromEquivalent 由实际焦距与 35mm 等效焦距推算传感器尺寸，假定传感器画幅为 4:3
+func CameraFromEquivalent(focalLength, equivalentFocalLength float64) (Camera, error) {
+	if focalLength <= 0 || equivalentFocalLength <= 0 {
+		return Camera{}, errors.New("焦距与等效焦距必须大于 0")
+	}
+	diagonal := fullFrameDiagonal * focalLength / equivalentFocalLength
+	return Camera{
+		FocalLength:  focalLength,
+		SensorWidth:  diagonal * 0.8,
+		SensorHeight: diagonal * 0.6,
+	}, nil
+}
+
+// Footprint 相机垂直向下拍摄时单张照片覆盖的地面范围，height 为相对地面的高度，单位米
+// 返回垂直于飞行方向的宽度与沿飞行方向的长度
+func (c Camera) Footprint(height float64) (width, length float64) {
+	return height * c.SensorWidth / c.FocalLength, height * c.SensorHeight / c.FocalLength
+}
+
+// Spacing 根据飞行高度与重叠率计算相邻航线的间距与沿航线的拍照间距，overlap 取值 [0, 1)
+// 旁向重叠与航向重叠使用同一重叠率
+func (c Camera) Spacing(height, overlap float64) (lineSpacing, photoDistance float64, err error) {
+	if c.FocalLength <= 0 || c.SensorWidth <= 0 || c.SensorHeight <= 0 {
+		return 0, 0, errors.New("相机参数无效")
+	}
+	if height <= 0 {
+		return 0, 0, errors.New("飞行高度必须大于 0")
+	}
+	if overlap < 0 || overlap >= 1 {
+		return 0, 0, errors.New("重叠率应在 [0, 1) 之间")
+	}
+	width, length := c.Footprint(height)
+	return width * (1 - overlap), length * (1 - overlap), nil
+}
+
+// vec 以多边形中心为原点的平面坐标，单位米
+type vec struct {
+	x, y float64
+}
+
+// projection 在多边形中心附近将经纬度近似投影为平面坐标，适用于数公里范围内的区域
+type projection struct {
+	origin Point
+	cosLat float64
+}
+
+func newProjection(polygon []Point) projection {
+	var origin Point
+	for _, p := range polygon {
+		origin.Lat += p.Lat
+		origin.Lng += p.Lng
+	}
+	origin.Lat /= float64(len(polygon))
+	origin.Lng /= float64(len(polygon))
+	return projection{origin: origin, cosLat: math.Cos(origin.Lat * math.Pi / 180)}
+}
+
+func (p projection) forward(pt Point) vec {
+	k := earthRadius * math.Pi / 180
+	return vec{x: (pt.Lng - p.origin.Lng) * k * p.cosLat, y: (pt.Lat - p.origin.Lat) * k}
+}
+
+func (p projection) inverse(v vec) Point {
+	k := earthRadius * math.Pi / 180
+	return Point{Lat: p.origin.Lat + v.y/k, Lng: p.origin.Lng + v.x/(k*p.cosLat)}
+}
+
+func rotate(v vec, angle float64) vec {
+	sin, cos := math.Sincos(angle)
+	return vec{x: v.x*cos - v.y*sin, y: v.x*sin + v.y*cos}
+}
+
+// Lines 以 spacing 米为间距生成覆盖多边形的扫描线，扫描线平行于多边形的最长边以减少转弯次数
+// 扫描线按垂直方向依次排列且方向一致；凹多边形的扫描线取最外侧的两个交点，可能经过区域外
+func Lines(polygon []Point, spacing float64) ([]Segment, error) {
+	if len(polygon) < 3 {
+		return nil, errors.New("区域至少需要 3 个顶点")
+	}
+	if spacing <= 0 {
+		return nil, errors.New("航线间距必须大于 0")
+	}
+
+	proj := newProjection(polygon)
+	points := make([]vec, len(polygon))
+	for i, p := range polygon {
+		points[i] = proj.forward(p)
+	}
+
+	// 将最长边旋转到水平方向
+	angle, longest := 0.0, 0.0
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		if d := math.Hypot(b.x-a.x, b.y-a.y); d > longest {
+			longest = d
+			angle = math.Atan2(b.y-a.y, b.x-a.x)
+		}
+	}
+	if longest == 0 {
+		return nil, errors.New("区域顶点重合")
+	}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i := range points {
+		points[i] = rotate(points[i], -angle)
+		minY = math.Min(minY, points[i].y)
+		maxY = math.Max(maxY, points[i].y)
+	}
+
+	// 扫描线在区域内居中分布，两侧到边界的距离相等且不超过半个间距
+	count := int(math.Ceil((maxY - minY) / spacing))
+	if count < 1 {
+		count = 1
+	}
+	start := minY + ((maxY-minY)-float64(count-1)*spacing)/2
+
+	var lines []Segment
+	for i := 0; i < count; i++ {
+		y := start + float64(i)*spacing
+		var xs []float64
+		for j := range points {
+			a, b := points[j], points[(j+1)%len(points)]
+			if (a.y <= y && y < b.y) || (b.y <= y && y < a.y) {
+				xs = append(xs, a.x+(y-a.y)*(b.x-a.x)/(b.y-a.y))
+			}
+		}
+		if len(xs) < 2 {
+			continue
+		}
+		sort.Float64s(xs)
+		lines = append(lines, Segment{
+			Start: proj.inverse(rotate(vec{x: xs[0], y: y}, angle)),
+			End:   proj.inverse(rotate(vec{x: xs[len(xs)-1], y: y}, angle)),
+		})
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("区域面积过小，无法生成航线")
+	}
+	return lines, nil
+}
+
+// Split 将扫描线按顺序均分为 n 组，每组相邻，用于多架无人机分区作业
+// 扫描线少于 n 条时，多出的组为空
+func Split(lines []Segment, n int) [][]Segment {
+	if n <= 0 {
+		return nil
+	}
+	groups := make([][]Segment, n)
+	size, remainder := len(lines)/n, len(lines)%n
+	start := 0
+	for i := range groups {
+		end := start + size
+		if i < remainder {
+			end++
+		}
+		groups[i] = lines[start:end]
+		start = end
+	}
+	return groups
+}
+
+// Route 将扫描线首尾相连成往返式航线，奇数条扫描线反向飞行
+func Route(lines []Segment) []Point {
+	route := make([]Point, 0, len(lines)*2)
+	for i, line := range lines {
+		if i%2 == 1 {
+			route = append(route, line.End, line.Start)
+		} else {
+			route = append(route, line.Start, line.End)
+		}
+	}
+	return route
+}
@@ -0,0 +1,133 @@
+package coverage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dronesphere/pkg/coordinate"
+)
+
+// rectangle 以 (22.58, 113.94) 为西南角，东西宽 width 米、南北长 height 米的矩形
+func rectangle(width, height float64) []Point {
+	origin := Point{Lat: 22.58, Lng: 113.94}
+	k := earthRadius * math.Pi / 180
+	dLat := height / k
+	dLng := width / (k * math.Cos(origin.Lat*math.Pi/180))
+	return []Point{
+		origin,
+		{Lat: origin.Lat, Lng: origin.Lng + dLng},
+		{Lat: origin.Lat + dLat, Lng: origin.Lng + dLng},
+		{Lat: origin.Lat + dLat, Lng: origin.Lng},
+	}
+}
+
+func distance(a, b Point) float64 {
+	return coordinate.HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+}
+
+func TestCameraSpacing(t *testing.T) {
+	c := Camera{FocalLength: 10, SensorWidth: 13.2, SensorHeight: 8.8}
+	lineSpacing, photoDistance, err := c.Spacing(100, 0.75)
+	if err != nil {
+		t.Fatalf("Spacing() error = %v", err)
+	}
+	if math.Abs(lineSpacing-33) > 1e-9 || math.Abs(photoDistance-22) > 1e-9 {
+		t.Errorf("Spacing() = (%v, %v), want (33, 22)", lineSpacing, photoDistance)
+	}
+
+	for _, overlap := range []float64{-0.1, 1, 75} {
+		if _, _, err := c.Spacing(100, overlap); err == nil {
+			t.Errorf("Spacing(100, %v) expected error", overlap)
+		}
+	}
+	if _, _, err := (Camera{}).Spacing(100, 0.5); err == nil {
+		t.Error("Spacing() with empty camera expected error")
+	}
+}
+
+func TestCameraFromEquivalent(t *testing.T) {
+	// 1 英寸传感器，8.8mm 焦距约等效 24mm
+	c, err := CameraFromEquivalent(8.8, 24)
+	if err != nil {
+		t.Fatalf("CameraFromEquivalent() error = %v", err)
+	}
+	if math.Abs(c.SensorWidth-12.69) > 0.01 || math.Abs(c.SensorHeight-9.52) > 0.01 {
+		t.Errorf("CameraFromEquivalent() sensor = %.2f x %.2f, want 12.69 x 9.52", c.SensorWidth, c.SensorHeight)
+	}
+	if _, err := CameraFromEquivalent(0, 24); err == nil {
+		t.Error("CameraFromEquivalent(0, 24) expected error")
+	}
+}
+
+func TestLinesFollowLongestEdge(t *testing.T) {
+	// 南北向的长条区域，扫描线应为南北方向
+	lines, err := Lines(rectangle(60, 200), 20)
+	if err != nil {
+		t.Fatalf("Lines() error = %v", err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("len(Lines()) = %d, want 3", len(lines))
+	}
+	for i, line := range lines {
+		if d := distance(line.Start, line.End); math.Abs(d-200) > 1 {
+			t.Errorf("line %d length = %.2f, want 200", i, d)
+		}
+		if math.Abs(line.Start.Lng-line.End.Lng) > 1e-7 {
+			t.Errorf("line %d is not north-south: %+v", i, line)
+		}
+	}
+	// 扫描线居中分布，两侧距边界 10 米，从哪一侧开始取决于最长边的方向
+	first := distance(Point{Lat: lines[0].Start.Lat, Lng: 113.94}, lines[0].Start)
+	last := distance(Point{Lat: lines[2].Start.Lat, Lng: 113.94}, lines[2].Start)
+	if math.Abs(math.Min(first, last)-10) > 0.1 || math.Abs(math.Max(first, last)-50) > 0.1 {
+		t.Errorf("line offsets = %.2f, %.2f, want 10 and 50", first, last)
+	}
+	if d := distance(lines[0].Start, lines[1].Start); math.Abs(d-20) > 0.1 {
+		t.Errorf("line spacing = %.2f, want 20", d)
+	}
+}
+
+func TestLinesInvalid(t *testing.T) {
+	if _, err := Lines(rectangle(100, 100)[:2], 10); err == nil {
+		t.Error("Lines() with 2 points expected error")
+	}
+	if _, err := Lines(rectangle(100, 100), 0); err == nil {
+		t.Error("Lines() with zero spacing expected error")
+	}
+	p := Point{Lat: 22.58, Lng: 113.94}
+	if _, err := Lines([]Point{p, p, p}, 10); err == nil {
+		t.Error("Lines() with coincident points expected error")
+	}
+}
+
+func TestRouteAlternates(t *testing.T) {
+	lines, err := Lines(rectangle(100, 50), 10)
+	if err != nil {
+		t.Fatalf("Lines() error = %v", err)
+	}
+	route := Route(lines)
+	if len(route) != len(lines)*2 {
+		t.Fatalf("len(Route()) = %d, want %d", len(route), len(lines)*2)
+	}
+	// 每条扫描线的终点与下一条的起点在同一侧，间距等于航线间距
+	for i := 1; i+1 < len(route); i += 2 {
+		if d := distance(route[i], route[i+1]); math.Abs(d-10) > 0.1 {
+			t.Errorf("turn %d length = %.2f, want 10", i/2, d)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	lines := make([]Segment, 5)
+	groups := Split(lines, 2)
+	if len(groups) != 2 || len(groups[0]) != 3 || len(groups[1]) != 2 {
+		t.Errorf("Split(5, 2) sizes = %d, %d, want 3, 2", len(groups[0]), len(groups[1]))
+	}
+	groups = Split(lines[:1], 3)
+	if len(groups) != 3 || len(groups[0]) != 1 || len(groups[1]) != 0 || len(groups[2]) != 0 {
+		t.Errorf("Split(1, 3) = %v", groups)
+	}
+	if Split(lines, 0) != nil {
+		t.Error("Split(5, 0) expected nil")
+	}
+}
@@ -259,7 +259,7 @@ type Placemark struct {
 	IsRisky                    *BoolAsInt                  `xml:"wpml:isRisky,omitempty"`                    // 是否为高风险航点
 	WaypointWorkType           *WaypointWorkType           `xml:"wpml:waypointWorkType,omitempty"`           // 航点工作类型
 	WaypointGimbalHeadingParam *WaypointGimbalHeadingParam `xml:"wpml:waypointGimbalHeadingParam,omitempty"` // 航点云台角度
-	ActionGroup                *ActionGroup                `xml:"wpml:actionGroup,omitempty"`                // 动作组
+	ActionGroups               []ActionGroup               `xml:"wpml:actionGroup,omitempty"`                // 动作组，一个航点可包含多个动作组
 }
 
 func DefaultPlacemark(lng, lat float64) Placemark {
@@ -280,7 +280,6 @@ func DefaultPlacemark(lng, lat float64) Placemark {
 		WaypointTurnParam:     nil,
 		UseStraightLine:       &falseBool,
 		GimbalPitchAngle:      0,
-		ActionGroup:           nil,
 	}
 }
 